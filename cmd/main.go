@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
@@ -9,15 +10,34 @@ import (
 	"time"
 
 	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/logger"
 	"client-message-transformer/internal/service"
 )
 
 func main() {
+	checkOnly := flag.Bool("check", false, "verify broker connectivity and topics, then exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	logger.SetFormat(cfg.LogFormat)
+
+	// Smoke-test mode: verify connectivity and exit 0/1
+	if *checkOnly || cfg.CheckOnly {
+		if err := service.Check(cfg); err != nil {
+			log.Fatalf("Connectivity check failed: %v", err)
+		}
+		log.Println("✅ Connectivity check passed")
+		return
+	}
+
+	// Keep stdout clean for NDJSON records
+	if cfg.OutputSink == config.OutputSinkStdout {
+		logger.SetOutput(os.Stderr)
+	}
 
 	// Create service
 	svc, err := service.New(cfg)
@@ -38,16 +58,29 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	<-sigChan
-	log.Println("Received shutdown signal...")
+	exitCode := 0
+	var reason string
+	select {
+	case sig := <-sigChan:
+		log.Println("Received shutdown signal...")
+		reason = "signal: " + sig.String()
+	case fatalErr := <-svc.Fatal():
+		log.Printf("Fatal service error, shutting down: %v", fatalErr)
+		reason = "fatal: " + fatalErr.Error()
+		exitCode = 1
+	}
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
 
-	err = svc.Stop(shutdownCtx)
+	err = svc.Stop(shutdownCtx, reason)
 	if err != nil {
 		log.Fatalf("Error during shutdown: %v", err)
 	}
 
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+
 	log.Println("🎉 Kafka Transformer Service stopped successfully")
 }
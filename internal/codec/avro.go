@@ -0,0 +1,50 @@
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// wireHeaderSize is the Confluent wire format prefix: a zero magic byte
+// followed by the big-endian schema ID
+const wireHeaderSize = 5
+
+// ErrNotWireFormat is returned for values without the schema registry prefix
+var ErrNotWireFormat = errors.New("value is not in schema registry wire format")
+
+// AvroDecoder decodes Avro values written with a Confluent schema registry
+// serializer
+type AvroDecoder struct {
+	registry *SchemaRegistry
+}
+
+// NewAvroDecoder creates a decoder resolving writer schemas from registry
+func NewAvroDecoder(registry *SchemaRegistry) *AvroDecoder {
+	return &AvroDecoder{registry: registry}
+}
+
+// Decode strips the wire format header, decodes the value with the writer
+// schema it names and renders it as plain JSON, with unions unwrapped
+func (d *AvroDecoder) Decode(ctx context.Context, value []byte) ([]byte, error) {
+	if len(value) < wireHeaderSize || value[0] != 0 {
+		return nil, ErrNotWireFormat
+	}
+	schemaID := binary.BigEndian.Uint32(value[1:wireHeaderSize])
+
+	codec, err := d.registry.Codec(ctx, schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	native, _, err := codec.NativeFromBinary(value[wireHeaderSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode avro value with schema %d: %w", schemaID, err)
+	}
+	data, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render avro value with schema %d: %w", schemaID, err)
+	}
+	return data, nil
+}
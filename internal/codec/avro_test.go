@@ -0,0 +1,136 @@
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// captureSchema is a cut-down capture record with an optional union field
+const captureSchema = `{"type":"record","name":"Capture","fields":[
+	{"name":"request","type":{"type":"record","name":"Request","fields":[
+		{"name":"url","type":"string"},
+		{"name":"method","type":"string"}]}},
+	{"name":"note","type":["null","string"],"default":null}]}`
+
+// stubRegistry serves schemas by ID and counts the requests it answers
+func stubRegistry(t *testing.T, schemas map[uint32]schemaResponse) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		var id uint32
+		if _, err := fmt.Sscanf(r.URL.Path, "/schemas/ids/%d", &id); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		schema, ok := schemas[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(schema)
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+// wireValue encodes native with captureSchema behind the wire format header
+func wireValue(t *testing.T, schemaID uint32, native map[string]interface{}) []byte {
+	t.Helper()
+	codec, err := goavro.NewCodec(captureSchema)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	value := make([]byte, wireHeaderSize)
+	binary.BigEndian.PutUint32(value[1:], schemaID)
+	value, err = codec.BinaryFromNative(value, native)
+	if err != nil {
+		t.Fatalf("BinaryFromNative: %v", err)
+	}
+	return value
+}
+
+func TestAvroDecoder(t *testing.T) {
+	request := map[string]interface{}{"url": "https://api.example.com/users", "method": "GET"}
+	tests := []struct {
+		name    string
+		value   func(t *testing.T) []byte
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{"record", func(t *testing.T) []byte {
+			return wireValue(t, 1, map[string]interface{}{"request": request, "note": nil})
+		}, map[string]interface{}{"request": map[string]interface{}{"url": "https://api.example.com/users", "method": "GET"}, "note": nil}, ""},
+		{"union unwrapped", func(t *testing.T) []byte {
+			return wireValue(t, 1, map[string]interface{}{"request": request, "note": goavro.Union("string", "mirrored")})
+		}, map[string]interface{}{"request": map[string]interface{}{"url": "https://api.example.com/users", "method": "GET"}, "note": "mirrored"}, ""},
+		{"plain JSON", func(t *testing.T) []byte { return []byte(`{"request":{}}`) }, nil, ErrNotWireFormat.Error()},
+		{"short value", func(t *testing.T) []byte { return []byte{0, 0, 1} }, nil, ErrNotWireFormat.Error()},
+		{"unknown schema", func(t *testing.T) []byte {
+			return wireValue(t, 9, map[string]interface{}{"request": request, "note": nil})
+		}, nil, "status 404 for schema 9"},
+		{"not an avro schema", func(t *testing.T) []byte {
+			return wireValue(t, 2, map[string]interface{}{"request": request, "note": nil})
+		}, nil, "schema 2 is PROTOBUF, not AVRO"},
+		{"truncated body", func(t *testing.T) []byte {
+			value := wireValue(t, 1, map[string]interface{}{"request": request, "note": nil})
+			return value[:len(value)-4]
+		}, nil, "failed to decode avro value with schema 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := stubRegistry(t, map[uint32]schemaResponse{
+				1: {Schema: captureSchema},
+				2: {Schema: `syntax = "proto3";`, SchemaType: "PROTOBUF"},
+			})
+			decoder := NewAvroDecoder(NewSchemaRegistry(server.URL + "/"))
+
+			data, err := decoder.Decode(context.Background(), tt.value(t))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == ErrNotWireFormat.Error() && !errors.Is(err, ErrNotWireFormat) {
+					t.Errorf("Decode error = %v, want ErrNotWireFormat", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("decoded value %s is not JSON: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %s, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaRegistryCachesCodecs(t *testing.T) {
+	server, fetches := stubRegistry(t, map[uint32]schemaResponse{1: {Schema: captureSchema}})
+	decoder := NewAvroDecoder(NewSchemaRegistry(server.URL))
+	value := wireValue(t, 1, map[string]interface{}{"request": map[string]interface{}{"url": "/", "method": "GET"}, "note": nil})
+
+	for i := 0; i < 3; i++ {
+		if _, err := decoder.Decode(context.Background(), value); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("registry fetched %d times, want 1", got)
+	}
+}
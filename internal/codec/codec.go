@@ -0,0 +1,31 @@
+package codec
+
+import (
+	"context"
+	"fmt"
+)
+
+// Source message formats
+const (
+	FormatJSON = "json"
+	FormatAvro = "avro"
+)
+
+// Decoder turns a source message value into the client's nested JSON format,
+// which the transformer consumes
+type Decoder interface {
+	Decode(ctx context.Context, value []byte) ([]byte, error)
+}
+
+// New returns the decoder for a SOURCE_FORMAT. JSON sources need no decoding,
+// so it returns nil for them.
+func New(format string, registryURL string) (Decoder, error) {
+	switch format {
+	case FormatJSON:
+		return nil, nil
+	case FormatAvro:
+		return NewAvroDecoder(NewSchemaRegistry(registryURL)), nil
+	default:
+		return nil, fmt.Errorf("unsupported source format %q", format)
+	}
+}
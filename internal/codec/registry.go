@@ -0,0 +1,93 @@
+package codec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// SchemaRegistry fetches Avro schemas by ID from a Confluent schema registry,
+// caching them since a schema ID never changes meaning
+type SchemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu     sync.RWMutex
+	codecs map[uint32]*goavro.Codec
+}
+
+// schemaResponse is the body of GET /schemas/ids/{id}
+type schemaResponse struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+// NewSchemaRegistry creates a client for the registry at url. Credentials may
+// be given in the URL's user info.
+func NewSchemaRegistry(url string) *SchemaRegistry {
+	return &SchemaRegistry{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+		codecs: make(map[uint32]*goavro.Codec),
+	}
+}
+
+// Codec returns the codec for a schema ID, fetching the schema on first use
+func (r *SchemaRegistry) Codec(ctx context.Context, id uint32) (*goavro.Codec, error) {
+	r.mu.RLock()
+	codec, ok := r.codecs[id]
+	r.mu.RUnlock()
+	if ok {
+		return codec, nil
+	}
+
+	schema, err := r.fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Standard JSON codecs render unions as bare values rather than {"type": value}
+	codec, err = goavro.NewCodecForStandardJSONFull(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema %d: %w", id, err)
+	}
+
+	r.mu.Lock()
+	r.codecs[id] = codec
+	r.mu.Unlock()
+	return codec, nil
+}
+
+// fetch reads a schema definition from the registry
+func (r *SchemaRegistry) fetch(ctx context.Context, id uint32) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", r.url, id), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build schema request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch schema %d: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("schema registry returned status %d for schema %d", resp.StatusCode, id)
+	}
+
+	var body schemaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse schema %d: %w", id, err)
+	}
+	// Avro schemas omit schemaType
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return "", fmt.Errorf("schema %d is %s, not AVRO", id, body.SchemaType)
+	}
+	return body.Schema, nil
+}
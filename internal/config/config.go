@@ -2,12 +2,41 @@ package config
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Sinks for transformed records
+const (
+	OutputSinkKafka  = "kafka"  // DestinationTopic
+	OutputSinkStdout = "stdout" // NDJSON on stdout
+)
+
+// Transform modes
+const (
+	TransformModeFlat        = "flat"        // Flat JSON record
+	TransformModeProto       = "proto"       // Marshaled HttpResponseParam
+	TransformModePassthrough = "passthrough" // Source bytes unchanged
+)
+
+// Transformer output versions selectable per destination
+const (
+	OutputVersionV1 = "v1" // Flat JSON
+	OutputVersionV2 = "v2" // HttpResponseParam protobuf
+)
+
+// Output is an extra destination topic and the output version it receives
+type Output struct {
+	Topic   string
+	Version string
+}
+
 // ConfigError represents a configuration error
 type ConfigError struct {
 	Message string
@@ -21,7 +50,7 @@ func (e *ConfigError) Error() string {
 // Config holds all configuration from environment variables
 type Config struct {
 	SourceBrokers         string
-	SourceTopic           string
+	SourceTopics          []string
 	DestinationBrokers    string
 	DestinationTopic      string
 	ConsumerGroup         string
@@ -30,12 +59,40 @@ type Config struct {
 	MaxConcurrentMessages int
 	CommitInterval        time.Duration
 	ProcessingTimeout     time.Duration
+	BrokerReadyTimeout    time.Duration
+	MessageDeadline       time.Duration
+	HealthPort            int // Port for the operational HTTP endpoints, 0 disables them
+	MetricsPort           int // Port serving Prometheus /metrics, 0 disables it
+	SubscribeDelay        time.Duration
+
+	// MaxPollIntervalMs is the consumer's max.poll.interval.ms; messages in
+	// flight for StuckWarnFraction of it are logged as stuck (0 disables)
+	MaxPollIntervalMs int
+	StuckWarnFraction float64
+
+	// MaxHeapMB is a soft heap limit; above it messages are processed one at a time (0 disables)
+	MaxHeapMB int
+
+	// ConsumerErrorWindow is how long consumer errors may persist before /readyz fails
+	ConsumerErrorWindow time.Duration
+
+	// CompactMessageLog replaces per-message progress logs with one summary line
+	CompactMessageLog bool
+
+	// LogFormat renders log lines as text or single-line json objects
+	LogFormat string
+
+	// Per-component log levels, each defaulting to LogLevel
+	LogLevelService     string
+	LogLevelTransformer string
+	LogLevelKafka       string
 
 	// Source SASL Configuration
 	SourceSASLEnabled      bool
 	SourceSASLMechanism    string
 	SourceSASLUsername     string
 	SourceSASLPassword     string
+	SourceSASLPasswordFile string // Re-read on authentication retries, overrides SourceSASLPassword
 	SourceSecurityProtocol string
 
 	// Destination SASL Configuration
@@ -43,9 +100,264 @@ type Config struct {
 	DestinationSASLMechanism    string
 	DestinationSASLUsername     string
 	DestinationSASLPassword     string
+	DestinationSASLPasswordFile string // Re-read on authentication retries, overrides DestinationSASLPassword
 	DestinationSecurityProtocol string
+
+	// DestinationCompression is the producer compression codec:
+	// none, gzip, snappy, lz4 or zstd
+	DestinationCompression string
+
+	// TLS certificate files for each side's brokers (PEM paths)
+	SourceSSLCALocation               string
+	SourceSSLCertificateLocation      string
+	SourceSSLKeyLocation              string
+	DestinationSSLCALocation          string
+	DestinationSSLCertificateLocation string
+	DestinationSSLKeyLocation         string
+
+	// Producer delivery timeouts in milliseconds
+	RequestTimeoutMs  int
+	DeliveryTimeoutMs int
+
+	// Startup retries of connections failing with the AuthRetryErrors
+	// authentication errors, reloading password files between attempts
+	AuthRetryErrors  []string
+	AuthRetries      int
+	AuthRetryBackoff time.Duration
+
+	// Header sanitization
+	DropHeaders              []string
+	CoalesceDuplicateHeaders bool
+
+	// API version extraction
+	ExtractAPIVersion bool
+	APIVersionHeader  string
+
+	// TimeOutputFormat renders the flat "time" field as "epoch" seconds or "rfc3339"
+	TimeOutputFormat string
+
+	// DateTimeUnit is the unit of the source info.dateTime: auto, s, ms, us or ns
+	DateTimeUnit string
+
+	// PayloadEncoding is the encoding of inbound bodies: none, gzip, snappy or lz4
+	PayloadEncoding string
+
+	// DecodeBase64Bodies decodes base64 bodies to text when PayloadEncoding is none
+	DecodeBase64Bodies bool
+
+	// DecodeFailurePolicy handles bodies that fail to decode: fail or passthrough-raw
+	DecodeFailurePolicy string
+
+	// MaxProduceRate caps published messages per second (0 disables the limit)
+	MaxProduceRate float64
+
+	// StatsD metrics export (disabled when StatsDAddr is empty)
+	StatsDAddr     string
+	StatsDPrefix   string
+	StatsDInterval time.Duration
+
+	// DropPrivateIPs skips traffic whose client IP is private or loopback
+	DropPrivateIPs bool
+
+	// Skip rules drop transformed traffic matching any path glob (* within a
+	// segment, ** across segments), status code or class (404, 3xx) or method
+	SkipPaths       []string
+	SkipStatusCodes []string
+	SkipMethods     []string
+
+	// PerClientRate caps messages per second for each client ID (0 disables the limit)
+	PerClientRate float64
+
+	// OpenTelemetry tracing (spans are exported only when OTelEndpoint is set)
+	OTelEndpoint    string
+	OTelServiceName string
+
+	// Passthrough forwards source messages verbatim without transforming them
+	Passthrough bool
+
+	// TransformMode is flat (JSON transform), proto or passthrough. Set, it
+	// overrides Passthrough and OutputFormat; unset, it is derived from them.
+	TransformMode string
+
+	// DownstreamHealthURL is polled and consumption paused while it is unhealthy
+	DownstreamHealthURL      string
+	DownstreamHealthInterval time.Duration
+
+	// CheckOnly verifies broker connectivity and topics, then exits
+	CheckOnly bool
+
+	// ShutdownReportFile receives a JSON summary when the service stops
+	ShutdownReportFile string
+
+	// SampleRate is the fraction of messages transformed (1 keeps all);
+	// sampled-out messages go raw to SampledOutTopic when set
+	SampleRate      float64
+	SampledOutTopic string
+
+	// TombstonePolicy handles nil-value source records: skip or forward-tombstone
+	TombstonePolicy string
+
+	// OutputSink is where transformed records go: kafka (DestinationTopic) or
+	// stdout (one JSON record per line, logs move to stderr)
+	OutputSink string
+
+	// DLQTopic receives the original bytes of messages that fail to transform
+	// or publish, with dlq_error, dlq_stage and dlq_timestamp headers
+	DLQTopic string
+
+	// ReservedClientPolicy handles client IDs listed in ReservedClientIDs:
+	// allow, reject (a reserved CLIENT_ID fails startup) or quarantine
+	// (messages resolving to one go raw to QuarantineTopic)
+	ReservedClientPolicy string
+	ReservedClientIDs    []string
+	QuarantineTopic      string
+
+	// BinaryHeaders lists source message headers whose values are base64-encoded
+	BinaryHeaders []string
+
+	// CaptureJoinWindow buffers partial captures (request or response only)
+	// this long to join them with their other half, matched on the
+	// dot-separated CaptureIDField path (0 disables, and response-only
+	// captures are then rejected as malformed)
+	CaptureJoinWindow time.Duration
+	CaptureIDField    string
+
+	// PreserveSourceHeaders copies source message headers onto published
+	// messages; PreserveSourceKey publishes with the source key when it has one
+	PreserveSourceHeaders bool
+	PreserveSourceKey     bool
+
+	// TenantHeader routes each message to traffic.<tenant> using this source
+	// header's value; messages without it use the default routing
+	TenantHeader string
+
+	// ClientIDSource picks where each message's client ID comes from:
+	// config, header, payload or auto (header, then payload)
+	ClientIDSource string
+
+	// FieldMissMetrics counts transformed messages missing method, path, status or ip
+	FieldMissMetrics bool
+
+	// DetectDuplicates counts messages identical to their partition predecessor
+	DetectDuplicates bool
+
+	// AlertWebhookURL receives JSON alert notifications when set
+	AlertWebhookURL string
+
+	// Consumer lag is sampled every LagCheckInterval (0 disables) and alerted
+	// on when it stays above LagAlertThreshold (0 disables alerting)
+	LagAlertThreshold int64
+	LagAlertDuration  time.Duration
+	LagCheckInterval  time.Duration
+
+	// KeyTemplate builds message keys from output fields, e.g. "{akto_account_id}:{path}"
+	KeyTemplate string
+
+	// SpreadKey keys messages by a hash of client, method and path template,
+	// taking precedence over KeyTemplate
+	SpreadKey bool
+
+	// ParseFormBody emits url-encoded form request bodies as a formParams map
+	ParseFormBody bool
+
+	// MaxJSONDepth rejects messages nested deeper than this (0, the default, disables)
+	MaxJSONDepth int
+
+	// MaxHeaderValueSize truncates longer header values (0 disables)
+	MaxHeaderValueSize int
+
+	// MaxBodyBytes truncates longer request/response bodies, flagging the
+	// output with body_truncated (0 disables)
+	MaxBodyBytes int
+
+	// HeaderCase sets header-name casing in output: lower, canonical or preserve
+	HeaderCase string
+
+	// KeepHeaders is an allowlist of forwarded headers (empty keeps all)
+	KeepHeaders []string
+
+	// SniffContentType emits body MIME types detected from content
+	SniffContentType bool
+
+	// ProtoRawHeaders carries the redacted, filtered header strings in protobuf output
+	ProtoRawHeaders bool
+
+	// OutputStructuredBody emits JSON bodies as nested objects alongside the strings
+	OutputStructuredBody bool
+
+	// EmitKafkaTimestamp adds the source record's broker timestamp as kafkaTimestamp
+	EmitKafkaTimestamp bool
+
+	// DechunkBodies strips chunk framing from chunked transfer-encoded bodies
+	DechunkBodies bool
+
+	// DropResponseBody never forwards response bodies
+	DropResponseBody bool
+
+	// ExtractAuthScheme emits the Authorization scheme and redacts the credential
+	ExtractAuthScheme bool
+
+	// AuthHeaders lists headers whose presence marks a request as authenticated
+	AuthHeaders []string
+
+	// InternalHostSuffixes lists host suffixes tagged isInternal=true
+	InternalHostSuffixes []string
+
+	// HostToCollection overrides the API collection ID derived for a host
+	HostToCollection map[string]int32
+
+	// Delivery retries for leader-unavailable errors, with backoff doubling up
+	// to 30s. Retries still waiting at shutdown are counted as failed.
+	ProduceLeaderRetries int
+	ProduceRetryBackoff  time.Duration
+
+	// MappingFile is a YAML or JSON file mapping output fields to source
+	// paths, replacing the built-in mapping when set
+	MappingFile string
+
+	// SourceFormat is the source message encoding: json, or avro in the
+	// Confluent wire format with schemas fetched from SchemaRegistryURL
+	SourceFormat      string
+	SchemaRegistryURL string
+
+	// MaxRetries retries a failed publish this many times, waiting
+	// RetryBackoff and then 1.5x longer each time, before the message fails
+	MaxRetries   int
+	RetryBackoff time.Duration
+
+	// OutputFormat selects the destination encoding: json (flat record) or
+	// proto (marshaled HttpResponseParam)
+	OutputFormat string
+
+	// Envelope wraps JSON output as {schema, payload, meta}
+	Envelope       bool
+	EnvelopeSchema string
+
+	// AuditTopic receives policy-dropped messages unchanged, with a drop_reason header
+	AuditTopic string
+
+	// SourceTopicWeights splits the worker pool across source topics by weight
+	SourceTopicWeights map[string]int
+
+	// Outputs are extra destinations, each receiving its own output version
+	Outputs []Output
+
+	// ComputePartition pins each message to hash(key) % PartitionCount instead
+	// of leaving partition choice to the producer
+	ComputePartition bool
+	PartitionCount   int
+
+	// StatusRouting maps status classes ("5xx") or codes ("404") to topics
+	StatusRouting map[string]string
+
+	// AllowSelfLoop permits consuming and producing on the same broker topic
+	AllowSelfLoop bool
 }
 
+// defaultDropHeaders lists HTTP/2 pseudo-headers and hop-by-hop headers
+const defaultDropHeaders = ":authority,:method,:path,:scheme,:status," +
+	"connection,keep-alive,proxy-connection,te,trailer,transfer-encoding,upgrade"
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	// Load .env file if it exists
@@ -71,21 +383,33 @@ func LoadConfig() (*Config, error) {
 	// Optional configuration with defaults
 	config := &Config{
 		SourceBrokers:         requiredVars["SOURCE_BROKERS"],
-		SourceTopic:           requiredVars["SOURCE_TOPIC"],
+		SourceTopics:          getList(requiredVars["SOURCE_TOPIC"]),
 		DestinationBrokers:    requiredVars["DESTINATION_BROKERS"],
 		DestinationTopic:      requiredVars["DESTINATION_TOPIC"],
 		ConsumerGroup:         requiredVars["CONSUMER_GROUP"],
 		ClientID:              requiredVars["CLIENT_ID"],
+		CompactMessageLog:     getEnvBool("COMPACT_MESSAGE_LOG", false),
 		LogLevel:              getEnv("LOG_LEVEL", "INFO"),
-		MaxConcurrentMessages: 10,
-		CommitInterval:        5 * time.Second,
-		ProcessingTimeout:     10 * time.Second,
+		LogFormat:             strings.ToLower(getEnv("LOG_FORMAT", "text")),
+		MaxConcurrentMessages: getEnvInt("MAX_CONCURRENT_MESSAGES", 10),
+		CommitInterval:        getEnvDuration("COMMIT_INTERVAL", 5*time.Second),
+		ProcessingTimeout:     getEnvDuration("PROCESSING_TIMEOUT", 10*time.Second),
+		BrokerReadyTimeout:    getEnvDuration("BROKER_READY_TIMEOUT", 30*time.Second),
+		MessageDeadline:       getEnvDuration(renamedEnv("MESSAGE_PROCESSING_DEADLINE", "MESSAGE_DEADLINE"), 0),
+		HealthPort:            getEnvInt("HEALTH_PORT", 8080),
+		MetricsPort:           getEnvInt("METRICS_PORT", 9090),
+		MaxHeapMB:             getEnvInt("MAX_HEAP_MB", 0),
+		MaxPollIntervalMs:     getEnvInt("MAX_POLL_INTERVAL_MS", 300000),
+		StuckWarnFraction:     getEnvFloat("STUCK_WARN_FRACTION", 0.5),
+		ConsumerErrorWindow:   getEnvDuration("CONSUMER_ERROR_WINDOW", time.Minute),
+		SubscribeDelay:        getEnvDuration("SUBSCRIBE_DELAY", 0),
 
 		// Source SASL Configuration (optional)
 		SourceSASLEnabled:      getEnvBool("SOURCE_SASL_ENABLED", false),
 		SourceSASLMechanism:    getEnv("SOURCE_SASL_MECHANISM", "PLAIN"),
 		SourceSASLUsername:     getEnv("SOURCE_SASL_USERNAME", ""),
 		SourceSASLPassword:     getEnv("SOURCE_SASL_PASSWORD", ""),
+		SourceSASLPasswordFile: getEnv("SOURCE_SASL_PASSWORD_FILE", ""),
 		SourceSecurityProtocol: getEnv("SOURCE_SECURITY_PROTOCOL", "SASL_PLAINTEXT"),
 
 		// Destination SASL Configuration (optional)
@@ -93,12 +417,528 @@ func LoadConfig() (*Config, error) {
 		DestinationSASLMechanism:    getEnv("DESTINATION_SASL_MECHANISM", "PLAIN"),
 		DestinationSASLUsername:     getEnv("DESTINATION_SASL_USERNAME", ""),
 		DestinationSASLPassword:     getEnv("DESTINATION_SASL_PASSWORD", ""),
+		DestinationSASLPasswordFile: getEnv("DESTINATION_SASL_PASSWORD_FILE", ""),
 		DestinationSecurityProtocol: getEnv("DESTINATION_SECURITY_PROTOCOL", "SASL_PLAINTEXT"),
+
+		DestinationCompression: strings.ToLower(getEnv("DESTINATION_COMPRESSION", "none")),
+
+		// TLS Configuration (optional)
+		SourceSSLCALocation:               getEnv("SOURCE_SSL_CA_LOCATION", ""),
+		SourceSSLCertificateLocation:      getEnv("SOURCE_SSL_CERTIFICATE_LOCATION", ""),
+		SourceSSLKeyLocation:              getEnv("SOURCE_SSL_KEY_LOCATION", ""),
+		DestinationSSLCALocation:          getEnv("DESTINATION_SSL_CA_LOCATION", ""),
+		DestinationSSLCertificateLocation: getEnv("DESTINATION_SSL_CERTIFICATE_LOCATION", ""),
+		DestinationSSLKeyLocation:         getEnv("DESTINATION_SSL_KEY_LOCATION", ""),
+
+		// Producer delivery timeouts (optional)
+		RequestTimeoutMs:  getEnvInt("REQUEST_TIMEOUT_MS", 30000),
+		DeliveryTimeoutMs: getEnvInt("DELIVERY_TIMEOUT_MS", 300000),
+
+		AuthRetryErrors:  getEnvList("AUTH_RETRY_ERRORS", "authentication,sasl_authentication_failed"),
+		AuthRetries:      getEnvInt("AUTH_RETRIES", 3),
+		AuthRetryBackoff: getEnvDuration("AUTH_RETRY_BACKOFF", 2*time.Second),
+
+		// Header sanitization (optional)
+		DropHeaders:              getEnvList("DROP_HEADERS", defaultDropHeaders),
+		CoalesceDuplicateHeaders: getEnvBool("COALESCE_DUPLICATE_HEADERS", false),
+
+		// API version extraction (optional)
+		ExtractAPIVersion: getEnvBool("EXTRACT_API_VERSION", false),
+		APIVersionHeader:  getEnv("API_VERSION_HEADER", "X-API-Version"),
+
+		TimeOutputFormat: strings.ToLower(getEnv("TIME_OUTPUT_FORMAT", "epoch")),
+		DateTimeUnit:     strings.ToLower(getEnv("DATETIME_UNIT", "auto")),
+
+		PayloadEncoding:     strings.ToLower(getEnv("PAYLOAD_ENCODING", "none")),
+		DecodeFailurePolicy: strings.ToLower(getEnv("DECODE_FAILURE_POLICY", "fail")),
+		DecodeBase64Bodies:  getEnvBool("DECODE_BASE64_BODIES", false),
+
+		MaxProduceRate: getEnvFloat("MAX_PRODUCE_RATE", 0),
+
+		StatsDAddr:     getEnv("STATSD_ADDR", ""),
+		StatsDPrefix:   getEnv("STATSD_PREFIX", "transformer."),
+		StatsDInterval: getEnvDuration("STATSD_INTERVAL", 10*time.Second),
+
+		DropPrivateIPs: getEnvBool("DROP_PRIVATE_IPS", false),
+
+		SkipPaths:       getEnvList("SKIP_PATHS", ""),
+		SkipStatusCodes: getEnvList("SKIP_STATUS_CODES", ""),
+		SkipMethods:     getEnvList("SKIP_METHODS", ""),
+
+		PerClientRate: getEnvFloat("PER_CLIENT_RATE", 0),
+
+		OTelEndpoint:    getEnv("OTEL_ENDPOINT", ""),
+		OTelServiceName: getEnv("OTEL_SERVICE_NAME", "client-message-transformer"),
+
+		Passthrough: getEnvBool("PASSTHROUGH", false),
+
+		DownstreamHealthURL:      getEnv("DOWNSTREAM_HEALTH_URL", ""),
+		DownstreamHealthInterval: getEnvDuration("DOWNSTREAM_HEALTH_INTERVAL", 10*time.Second),
+
+		CheckOnly: getEnvBool("CHECK_ONLY", false),
+
+		ShutdownReportFile: getEnv("SHUTDOWN_REPORT_FILE", ""),
+
+		ProduceLeaderRetries: getEnvInt("PRODUCE_LEADER_RETRIES", 3),
+		ProduceRetryBackoff:  getEnvDuration("PRODUCE_RETRY_BACKOFF", 500*time.Millisecond),
+
+		OutputFormat: strings.ToLower(getEnv("OUTPUT_FORMAT", "json")),
+
+		MaxRetries:   getEnvSignedInt("MAX_RETRIES", 3),
+		RetryBackoff: getEnvDuration("RETRY_BACKOFF", 100*time.Millisecond),
+
+		MappingFile: getEnv("MAPPING_FILE", ""),
+
+		SourceFormat:      strings.ToLower(getEnv("SOURCE_FORMAT", "json")),
+		SchemaRegistryURL: getEnv("SCHEMA_REGISTRY_URL", ""),
+
+		Envelope:       getEnvBool("ENVELOPE", false),
+		EnvelopeSchema: getEnv("ENVELOPE_SCHEMA", "akto.http-traffic.v1"),
+
+		AuditTopic: getEnv("AUDIT_TOPIC", ""),
+
+		SampleRate:      getEnvFloat("SAMPLE_RATE", 1),
+		SampledOutTopic: getEnv("SAMPLED_OUT_TOPIC", ""),
+
+		TombstonePolicy: strings.ToLower(getEnv("TOMBSTONE_POLICY", "skip")),
+
+		ClientIDSource: strings.ToLower(getEnv("CLIENT_ID_SOURCE", "auto")),
+
+		TenantHeader: getEnv("TENANT_HEADER", ""),
+
+		BinaryHeaders: getEnvList("BINARY_HEADERS", ""),
+
+		CaptureJoinWindow: getEnvDuration("CAPTURE_JOIN_WINDOW", 0),
+		CaptureIDField:    getEnv("CAPTURE_ID_FIELD", "info.requestId"),
+
+		PreserveSourceHeaders: getEnvBool("PRESERVE_SOURCE_HEADERS", false),
+		PreserveSourceKey:     getEnvBool("PRESERVE_SOURCE_KEY", false),
+
+		OutputSink: strings.ToLower(getEnv("OUTPUT_SINK", OutputSinkKafka)),
+
+		DLQTopic: getEnv("DLQ_TOPIC", ""),
+
+		ReservedClientPolicy: strings.ToLower(getEnv("RESERVED_CLIENT_POLICY", "allow")),
+		ReservedClientIDs:    getEnvList("RESERVED_CLIENT_IDS", "default-client"),
+		QuarantineTopic:      getEnv("QUARANTINE_TOPIC", ""),
+
+		FieldMissMetrics: getEnvBool("FIELD_MISS_METRICS", false),
+
+		DetectDuplicates: getEnvBool("DETECT_DUPLICATES", false),
+
+		AlertWebhookURL: getEnv("ALERT_WEBHOOK_URL", ""),
+
+		LagAlertThreshold: int64(getEnvInt("LAG_ALERT_THRESHOLD", 0)),
+		LagAlertDuration:  getEnvDuration("LAG_ALERT_DURATION", 5*time.Minute),
+		LagCheckInterval:  getEnvDuration("LAG_CHECK_INTERVAL", 30*time.Second),
+
+		ComputePartition: getEnvBool("COMPUTE_PARTITION", false),
+		PartitionCount:   getEnvInt("PARTITION_COUNT", 0),
+
+		KeyTemplate: getEnv("KEY_TEMPLATE", ""),
+		SpreadKey:   getEnvBool("SPREAD_KEY", false),
+
+		ParseFormBody: getEnvBool("PARSE_FORM_BODY", false),
+
+		DechunkBodies: getEnvBool("DECHUNK_BODIES", false),
+
+		MaxJSONDepth: getEnvInt("MAX_JSON_DEPTH", 0),
+
+		KeepHeaders:        getEnvList("KEEP_HEADERS", ""),
+		MaxHeaderValueSize: getEnvInt("MAX_HEADER_VALUE_SIZE", 0),
+		HeaderCase:         strings.ToLower(getEnv("HEADER_CASE", "")),
+
+		MaxBodyBytes: getEnvInt("MAX_BODY_BYTES", 0),
+
+		InternalHostSuffixes: getEnvList("INTERNAL_HOST_SUFFIXES", ""),
+
+		SniffContentType: getEnvBool("SNIFF_CONTENT_TYPE", false),
+
+		ProtoRawHeaders: getEnvBool("PROTO_RAW_HEADERS", false),
+
+		OutputStructuredBody: getEnvBool("OUTPUT_STRUCTURED_BODY", false),
+
+		EmitKafkaTimestamp: getEnvBool("EMIT_KAFKA_TIMESTAMP", false),
+
+		DropResponseBody: getEnvBool("DROP_RESPONSE_BODY", false),
+
+		ExtractAuthScheme: getEnvBool("EXTRACT_AUTH_SCHEME", false),
+		AuthHeaders:       getEnvList("AUTH_HEADERS", "authorization,cookie,x-api-key"),
+
+		AllowSelfLoop: getEnvBool("ALLOW_SELF_LOOP", false),
+	}
+
+	config.TransformMode = strings.ToLower(getEnv("TRANSFORM_MODE", ""))
+	switch config.TransformMode {
+	case "":
+		config.TransformMode = TransformModeFlat
+		if config.Passthrough {
+			config.TransformMode = TransformModePassthrough
+		} else if config.OutputFormat == "proto" {
+			config.TransformMode = TransformModeProto
+		}
+	case TransformModeFlat:
+		config.Passthrough = false
+		config.OutputFormat = "json"
+	case TransformModeProto:
+		config.Passthrough = false
+		config.OutputFormat = "proto"
+	case TransformModePassthrough:
+		config.Passthrough = true
+	default:
+		return nil, &ConfigError{Message: fmt.Sprintf("TRANSFORM_MODE must be flat, proto or passthrough, got %q", config.TransformMode)}
+	}
+
+	config.LogLevelService = getEnv("LOG_LEVEL_SERVICE", config.LogLevel)
+	config.LogLevelTransformer = getEnv("LOG_LEVEL_TRANSFORMER", config.LogLevel)
+	config.LogLevelKafka = getEnv("LOG_LEVEL_KAFKA", config.LogLevel)
+
+	statusRouting, err := parseStatusRouting(getEnv("STATUS_ROUTING", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.StatusRouting = statusRouting
+
+	topicWeights, err := parseTopicWeights(getEnv("SOURCE_TOPIC_WEIGHTS", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.SourceTopicWeights = topicWeights
+
+	outputs, err := parseOutputs(getEnv("OUTPUTS", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.Outputs = outputs
+
+	hostToCollection, err := parseHostToCollection(getEnv("HOST_TO_COLLECTION", ""))
+	if err != nil {
+		return nil, err
+	}
+	config.HostToCollection = hostToCollection
+
+	if err := config.validate(); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// validate checks cross-field consistency of the loaded configuration
+func (c *Config) validate() error {
+	if c.MaxRetries < 0 {
+		return &ConfigError{Message: fmt.Sprintf("MAX_RETRIES must not be negative, got %d", c.MaxRetries)}
+	}
+	if c.MaxRetries > 0 && c.RetryBackoff <= 0 {
+		return &ConfigError{Message: "RETRY_BACKOFF must be positive when MAX_RETRIES is set"}
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return &ConfigError{Message: fmt.Sprintf("LOG_FORMAT must be text or json, got %q", c.LogFormat)}
+	}
+	switch c.SourceFormat {
+	case "json":
+	case "avro":
+		if c.SchemaRegistryURL == "" {
+			return &ConfigError{Message: "SCHEMA_REGISTRY_URL is required when SOURCE_FORMAT=avro"}
+		}
+	default:
+		return &ConfigError{Message: fmt.Sprintf("SOURCE_FORMAT must be json or avro, got %q", c.SourceFormat)}
+	}
+	if c.TimeOutputFormat != "epoch" && c.TimeOutputFormat != "rfc3339" {
+		return &ConfigError{Message: fmt.Sprintf("TIME_OUTPUT_FORMAT must be epoch or rfc3339, got %q", c.TimeOutputFormat)}
+	}
+	switch c.DateTimeUnit {
+	case "auto", "s", "ms", "us", "ns":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("DATETIME_UNIT must be one of auto, s, ms, us, ns, got %q", c.DateTimeUnit)}
+	}
+	for _, code := range c.SkipStatusCodes {
+		if !isStatusPattern(strings.ToLower(code)) {
+			return &ConfigError{Message: fmt.Sprintf("SKIP_STATUS_CODES entries must be status codes (404) or classes (3xx), got %q", code)}
+		}
+	}
+	switch c.DestinationCompression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("DESTINATION_COMPRESSION must be one of none, gzip, snappy, lz4, zstd, got %q", c.DestinationCompression)}
+	}
+	switch c.PayloadEncoding {
+	case "none", "gzip", "snappy", "lz4":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("PAYLOAD_ENCODING must be one of none, gzip, snappy, lz4, got %q", c.PayloadEncoding)}
+	}
+	if c.DecodeFailurePolicy != "fail" && c.DecodeFailurePolicy != "passthrough-raw" {
+		return &ConfigError{Message: fmt.Sprintf("DECODE_FAILURE_POLICY must be fail or passthrough-raw, got %q", c.DecodeFailurePolicy)}
+	}
+	if _, err := c.SourcePassword(); err != nil {
+		return &ConfigError{Message: fmt.Sprintf("SOURCE_SASL_PASSWORD_FILE: %v", err)}
+	}
+	if _, err := c.DestinationPassword(); err != nil {
+		return &ConfigError{Message: fmt.Sprintf("DESTINATION_SASL_PASSWORD_FILE: %v", err)}
+	}
+	if (c.SourceSSLCertificateLocation == "") != (c.SourceSSLKeyLocation == "") {
+		return &ConfigError{Message: "SOURCE_SSL_CERTIFICATE_LOCATION and SOURCE_SSL_KEY_LOCATION must be set together"}
+	}
+	if (c.DestinationSSLCertificateLocation == "") != (c.DestinationSSLKeyLocation == "") {
+		return &ConfigError{Message: "DESTINATION_SSL_CERTIFICATE_LOCATION and DESTINATION_SSL_KEY_LOCATION must be set together"}
+	}
+	if err := validateSASL("SOURCE", c.SourceSASLEnabled, c.SourceSASLMechanism, c.SourceSASLUsername,
+		c.SourceSASLPassword, c.SourceSASLPasswordFile, c.SourceSecurityProtocol); err != nil {
+		return err
+	}
+	if err := validateSASL("DESTINATION", c.DestinationSASLEnabled, c.DestinationSASLMechanism, c.DestinationSASLUsername,
+		c.DestinationSASLPassword, c.DestinationSASLPasswordFile, c.DestinationSecurityProtocol); err != nil {
+		return err
+	}
+	if len(c.SourceTopics) == 0 {
+		return &ConfigError{Message: "SOURCE_TOPIC must name at least one topic"}
+	}
+	if c.MaxConcurrentMessages <= 0 {
+		return &ConfigError{Message: fmt.Sprintf("MAX_CONCURRENT_MESSAGES must be positive, got %d", c.MaxConcurrentMessages)}
+	}
+	if c.CommitInterval <= 0 {
+		return &ConfigError{Message: fmt.Sprintf("COMMIT_INTERVAL must be positive, got %v", c.CommitInterval)}
+	}
+	if c.ProcessingTimeout <= 0 {
+		return &ConfigError{Message: fmt.Sprintf("PROCESSING_TIMEOUT must be positive, got %v", c.ProcessingTimeout)}
+	}
+	switch c.HeaderCase {
+	case "", "lower", "canonical", "preserve":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("HEADER_CASE must be one of lower, canonical, preserve, got %q", c.HeaderCase)}
+	}
+	if c.TombstonePolicy != "skip" && c.TombstonePolicy != "forward-tombstone" {
+		return &ConfigError{Message: fmt.Sprintf("TOMBSTONE_POLICY must be skip or forward-tombstone, got %q", c.TombstonePolicy)}
+	}
+	switch c.ClientIDSource {
+	case "config", "header", "payload", "auto":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("CLIENT_ID_SOURCE must be one of config, header, payload, auto, got %q", c.ClientIDSource)}
+	}
+	switch c.OutputSink {
+	case OutputSinkKafka:
+	case OutputSinkStdout:
+		if c.OutputFormat != "json" {
+			return &ConfigError{Message: "OUTPUT_SINK=stdout requires OUTPUT_FORMAT=json"}
+		}
+	default:
+		return &ConfigError{Message: fmt.Sprintf("OUTPUT_SINK must be kafka or stdout, got %q", c.OutputSink)}
+	}
+	switch c.ReservedClientPolicy {
+	case "allow":
+	case "reject":
+		for _, reserved := range c.ReservedClientIDs {
+			if c.ClientID == reserved {
+				return &ConfigError{Message: fmt.Sprintf("CLIENT_ID %q is reserved (RESERVED_CLIENT_POLICY=reject)", c.ClientID)}
+			}
+		}
+	case "quarantine":
+		if c.QuarantineTopic == "" {
+			return &ConfigError{Message: "QUARANTINE_TOPIC is required when RESERVED_CLIENT_POLICY=quarantine"}
+		}
+	default:
+		return &ConfigError{Message: fmt.Sprintf("RESERVED_CLIENT_POLICY must be one of allow, reject, quarantine, got %q", c.ReservedClientPolicy)}
+	}
+	if c.CaptureJoinWindow > 0 && c.CaptureIDField == "" {
+		return &ConfigError{Message: "CAPTURE_ID_FIELD is required when CAPTURE_JOIN_WINDOW is set"}
+	}
+	if c.StuckWarnFraction > 1 {
+		return &ConfigError{Message: fmt.Sprintf("STUCK_WARN_FRACTION must be between 0 and 1, got %v", c.StuckWarnFraction)}
+	}
+	if c.StuckWarnFraction > 0 && c.MaxPollIntervalMs <= 0 {
+		return &ConfigError{Message: "MAX_POLL_INTERVAL_MS must be positive when STUCK_WARN_FRACTION is set"}
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return &ConfigError{Message: fmt.Sprintf("SAMPLE_RATE must be between 0 and 1, got %v", c.SampleRate)}
+	}
+	if c.OutputFormat != "json" && c.OutputFormat != "proto" {
+		return &ConfigError{Message: fmt.Sprintf("OUTPUT_FORMAT must be json or proto, got %q", c.OutputFormat)}
+	}
+	// The protobuf output has no room for JSON-only decorations
+	if c.TransformMode == TransformModeProto {
+		switch {
+		case c.Envelope:
+			return &ConfigError{Message: "ENVELOPE wraps JSON output and cannot be combined with OUTPUT_FORMAT=proto"}
+		case c.EmitKafkaTimestamp:
+			return &ConfigError{Message: "EMIT_KAFKA_TIMESTAMP adds a JSON field and cannot be combined with OUTPUT_FORMAT=proto"}
+		case len(c.Outputs) > 0:
+			return &ConfigError{Message: "OUTPUTS render from the flat JSON record and cannot be combined with OUTPUT_FORMAT=proto"}
+		}
+	}
+	if c.RequestTimeoutMs <= 0 {
+		return &ConfigError{Message: "REQUEST_TIMEOUT_MS must be greater than zero"}
+	}
+	if c.DeliveryTimeoutMs < c.RequestTimeoutMs {
+		return &ConfigError{Message: fmt.Sprintf("DELIVERY_TIMEOUT_MS (%d) must be >= REQUEST_TIMEOUT_MS (%d)", c.DeliveryTimeoutMs, c.RequestTimeoutMs)}
+	}
+	if c.StatsDAddr != "" && c.StatsDInterval <= 0 {
+		return &ConfigError{Message: "STATSD_INTERVAL must be greater than zero"}
+	}
+	if c.ComputePartition && c.PartitionCount <= 0 {
+		return &ConfigError{Message: "PARTITION_COUNT must be greater than zero when COMPUTE_PARTITION is enabled"}
+	}
+	if c.DownstreamHealthURL != "" && c.DownstreamHealthInterval <= 0 {
+		return &ConfigError{Message: "DOWNSTREAM_HEALTH_INTERVAL must be greater than zero"}
+	}
+	if c.LagAlertThreshold > 0 && c.LagCheckInterval <= 0 {
+		return &ConfigError{Message: "LAG_CHECK_INTERVAL must be greater than zero when LAG_ALERT_THRESHOLD is set"}
+	}
+	if !c.AllowSelfLoop && c.isSelfLoop() {
+		return &ConfigError{Message: fmt.Sprintf(
+			"source and destination both point to topic %q on the same brokers, which would loop messages forever (set ALLOW_SELF_LOOP=true to override)",
+			c.DestinationTopic)}
+	}
+	return nil
+}
+
+// isSelfLoop reports whether the service would consume its own output
+func (c *Config) isSelfLoop() bool {
+	if normalizeBrokers(c.SourceBrokers) != normalizeBrokers(c.DestinationBrokers) {
+		return false
+	}
+	for _, topic := range c.SourceTopics {
+		if topic == c.DestinationTopic {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusRouting parses "5xx=topic,404=topic" into a status-to-topic map
+func parseStatusRouting(value string) (map[string]string, error) {
+	routes := make(map[string]string)
+	for _, entry := range getList(value) {
+		status, topic, ok := strings.Cut(entry, "=")
+		status = strings.ToLower(strings.TrimSpace(status))
+		topic = strings.TrimSpace(topic)
+		if !ok || topic == "" || !isStatusPattern(status) {
+			return nil, &ConfigError{Message: fmt.Sprintf("invalid STATUS_ROUTING entry %q, expected <status>=<topic> such as 5xx=errors", entry)}
+		}
+		routes[status] = topic
+	}
+	return routes, nil
+}
+
+// parseTopicWeights parses "topic=3,topic=1" into per-topic worker weights
+func parseTopicWeights(value string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, entry := range getList(value) {
+		topic, weight, ok := strings.Cut(entry, "=")
+		topic = strings.TrimSpace(topic)
+		parsed, err := strconv.Atoi(strings.TrimSpace(weight))
+		if !ok || topic == "" || err != nil || parsed <= 0 {
+			return nil, &ConfigError{Message: fmt.Sprintf("invalid SOURCE_TOPIC_WEIGHTS entry %q, expected <topic>=<positive weight>", entry)}
+		}
+		weights[topic] = parsed
+	}
+	return weights, nil
+}
+
+// parseOutputs parses "topic=v1,topic=v2" into extra output destinations
+func parseOutputs(value string) ([]Output, error) {
+	var outputs []Output
+	for _, entry := range getList(value) {
+		topic, version, ok := strings.Cut(entry, "=")
+		topic = strings.TrimSpace(topic)
+		version = strings.ToLower(strings.TrimSpace(version))
+		if !ok || topic == "" || (version != OutputVersionV1 && version != OutputVersionV2) {
+			return nil, &ConfigError{Message: fmt.Sprintf("invalid OUTPUTS entry %q, expected <topic>=v1 or <topic>=v2", entry)}
+		}
+		outputs = append(outputs, Output{Topic: topic, Version: version})
+	}
+	return outputs, nil
+}
+
+// parseHostToCollection parses "host=id,host=id" into a host-to-collection map
+func parseHostToCollection(value string) (map[string]int32, error) {
+	collections := make(map[string]int32)
+	for _, entry := range getList(value) {
+		host, id, ok := strings.Cut(entry, "=")
+		host = strings.ToLower(strings.TrimSpace(host))
+		collectionID, err := strconv.ParseInt(strings.TrimSpace(id), 10, 32)
+		if !ok || host == "" || err != nil {
+			return nil, &ConfigError{Message: fmt.Sprintf("invalid HOST_TO_COLLECTION entry %q, expected <host>=<collection id>", entry)}
+		}
+		collections[host] = int32(collectionID)
+	}
+	return collections, nil
+}
+
+// isStatusPattern reports whether s is a status code ("404") or class ("4xx")
+func isStatusPattern(s string) bool {
+	if len(s) != 3 || s[0] < '1' || s[0] > '5' {
+		return false
+	}
+	if s[1:] == "xx" {
+		return true
+	}
+	return s[1] >= '0' && s[1] <= '9' && s[2] >= '0' && s[2] <= '9'
+}
+
+// normalizeBrokers returns a canonical form of a broker list for comparison
+func normalizeBrokers(brokers string) string {
+	list := getList(brokers)
+	for i, broker := range list {
+		list[i] = strings.ToLower(broker)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// validateSASL checks one side's security settings. The protocol must be a
+// known value; with SASL enabled it must be a SASL protocol, the mechanism
+// supported and the credentials present.
+func validateSASL(prefix string, enabled bool, mechanism, username, password, passwordFile, protocol string) error {
+	switch protocol {
+	case "PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("%s_SECURITY_PROTOCOL must be one of PLAINTEXT, SSL, SASL_PLAINTEXT, SASL_SSL, got %q", prefix, protocol)}
+	}
+	if !enabled {
+		return nil
+	}
+
+	if protocol != "SASL_PLAINTEXT" && protocol != "SASL_SSL" {
+		return &ConfigError{Message: fmt.Sprintf("%s_SASL_ENABLED requires %s_SECURITY_PROTOCOL to be SASL_PLAINTEXT or SASL_SSL, got %q", prefix, prefix, protocol)}
+	}
+	switch mechanism {
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+	default:
+		return &ConfigError{Message: fmt.Sprintf("%s_SASL_MECHANISM must be one of PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, got %q", prefix, mechanism)}
+	}
+	if username == "" {
+		return &ConfigError{Message: fmt.Sprintf("%s_SASL_USERNAME is required when %s_SASL_ENABLED is true", prefix, prefix)}
+	}
+	if password == "" && passwordFile == "" {
+		return &ConfigError{Message: fmt.Sprintf("%s_SASL_PASSWORD or %s_SASL_PASSWORD_FILE is required when %s_SASL_ENABLED is true", prefix, prefix, prefix)}
+	}
+	return nil
+}
+
+// SourcePassword returns the source SASL password, read fresh from
+// SOURCE_SASL_PASSWORD_FILE when one is configured
+func (c *Config) SourcePassword() (string, error) {
+	return readPassword(c.SourceSASLPassword, c.SourceSASLPasswordFile)
+}
+
+// DestinationPassword returns the destination SASL password, read fresh from
+// DESTINATION_SASL_PASSWORD_FILE when one is configured
+func (c *Config) DestinationPassword() (string, error) {
+	return readPassword(c.DestinationSASLPassword, c.DestinationSASLPasswordFile)
+}
+
+// readPassword returns the trimmed contents of file, or password when no file is set
+func readPassword(password string, file string) (string, error) {
+	if file == "" {
+		return password, nil
+	}
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
 // getEnv gets environment variable with default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -107,6 +947,20 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// renamedEnv returns the variable to read for a renamed setting: key, or the
+// deprecated oldKey with a warning when only that is set
+func renamedEnv(key, oldKey string) string {
+	if os.Getenv(oldKey) == "" {
+		return key
+	}
+	if os.Getenv(key) != "" {
+		log.Printf("⚠️  %s is deprecated and ignored since %s is set", oldKey, key)
+		return key
+	}
+	log.Printf("⚠️  %s is deprecated, use %s", oldKey, key)
+	return oldKey
+}
+
 // getEnvBool gets boolean environment variable with default value
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -114,3 +968,76 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvInt gets non-negative integer environment variable with default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		log.Printf("⚠️  Invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// getEnvSignedInt gets integer environment variable with default value,
+// keeping negative values so validate can reject them
+func getEnvSignedInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️  Invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// getEnvFloat gets non-negative float environment variable with default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		log.Printf("⚠️  Invalid number %q for %s, using default %v", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// getEnvDuration gets duration environment variable (e.g. "30s") with default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Printf("⚠️  Invalid duration %q for %s, using default %v", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvList gets a comma-separated environment variable as a trimmed list
+func getEnvList(key, defaultValue string) []string {
+	return getList(getEnv(key, defaultValue))
+}
+
+// getList splits a comma-separated value into a trimmed list
+func getList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
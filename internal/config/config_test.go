@@ -0,0 +1,817 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequiredEnv sets the variables LoadConfig requires, then the overrides
+func setRequiredEnv(t *testing.T, overrides map[string]string) {
+	t.Helper()
+	env := map[string]string{
+		"CLIENT_ID":           "1000",
+		"SOURCE_BROKERS":      "source:9092",
+		"SOURCE_TOPIC":        "client.traffic",
+		"DESTINATION_BROKERS": "destination:9092",
+		"DESTINATION_TOPIC":   "akto.api.logs",
+		"CONSUMER_GROUP":      "transformer",
+	}
+	for key, value := range overrides {
+		env[key] = value
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+// wantConfigError fails unless err is a ConfigError mentioning substr
+func wantConfigError(t *testing.T, err error, substr string) {
+	t.Helper()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("error = %v, want a ConfigError", err)
+	}
+	if !strings.Contains(configErr.Message, substr) {
+		t.Fatalf("error = %q, want it to mention %q", configErr.Message, substr)
+	}
+}
+
+func TestSelfLoopDetection(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name: "distinct brokers",
+			env: map[string]string{
+				"SOURCE_TOPIC":      "akto.api.logs",
+				"DESTINATION_TOPIC": "akto.api.logs",
+			},
+		},
+		{
+			name: "distinct topics",
+			env: map[string]string{
+				"DESTINATION_BROKERS": "source:9092",
+			},
+		},
+		{
+			name: "same brokers and topic",
+			env: map[string]string{
+				"DESTINATION_BROKERS": "source:9092",
+				"SOURCE_TOPIC":        "akto.api.logs",
+			},
+			wantErr: true,
+		},
+		{
+			name: "broker lists differing only in order and case",
+			env: map[string]string{
+				"SOURCE_BROKERS":      "a:9092,B:9092",
+				"DESTINATION_BROKERS": "b:9092, a:9092",
+				"SOURCE_TOPIC":        "client.traffic,akto.api.logs",
+			},
+			wantErr: true,
+		},
+		{
+			name: "override",
+			env: map[string]string{
+				"DESTINATION_BROKERS": "source:9092",
+				"SOURCE_TOPIC":        "akto.api.logs",
+				"ALLOW_SELF_LOOP":     "true",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			_, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "ALLOW_SELF_LOOP")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseStatusRouting(t *testing.T) {
+	routes, err := parseStatusRouting("5XX=errors, 404 = not-found")
+	if err != nil {
+		t.Fatalf("parseStatusRouting: %v", err)
+	}
+	if routes["5xx"] != "errors" || routes["404"] != "not-found" {
+		t.Errorf("routes = %v", routes)
+	}
+
+	for _, value := range []string{"5xx", "600=errors", "5xx=", "ok=errors"} {
+		if _, err := parseStatusRouting(value); err == nil {
+			t.Errorf("parseStatusRouting(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestDeliveryTimeouts(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantRequest  int
+		wantDelivery int
+		wantErr      string
+	}{
+		{name: "defaults", wantRequest: 30000, wantDelivery: 300000},
+		{
+			name:        "custom",
+			env:         map[string]string{"REQUEST_TIMEOUT_MS": "5000", "DELIVERY_TIMEOUT_MS": "60000"},
+			wantRequest: 5000, wantDelivery: 60000,
+		},
+		{
+			name:    "zero request timeout",
+			env:     map[string]string{"REQUEST_TIMEOUT_MS": "0"},
+			wantErr: "REQUEST_TIMEOUT_MS must be greater than zero",
+		},
+		{
+			name:    "delivery shorter than request",
+			env:     map[string]string{"REQUEST_TIMEOUT_MS": "60000", "DELIVERY_TIMEOUT_MS": "30000"},
+			wantErr: "DELIVERY_TIMEOUT_MS (30000) must be >= REQUEST_TIMEOUT_MS (60000)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.RequestTimeoutMs != tt.wantRequest || cfg.DeliveryTimeoutMs != tt.wantDelivery {
+				t.Errorf("timeouts = %d/%d, want %d/%d", cfg.RequestTimeoutMs, cfg.DeliveryTimeoutMs, tt.wantRequest, tt.wantDelivery)
+			}
+		})
+	}
+}
+
+func TestComponentLogLevels(t *testing.T) {
+	tests := []struct {
+		name                        string
+		env                         map[string]string
+		service, transformer, kafka string
+	}{
+		{"defaults", nil, "INFO", "INFO", "INFO"},
+		{"inherit LOG_LEVEL", map[string]string{"LOG_LEVEL": "WARN"}, "WARN", "WARN", "WARN"},
+		{
+			"override one component",
+			map[string]string{"LOG_LEVEL": "WARN", "LOG_LEVEL_TRANSFORMER": "DEBUG"},
+			"WARN", "DEBUG", "WARN",
+		},
+		{
+			"override every component",
+			map[string]string{"LOG_LEVEL_SERVICE": "ERROR", "LOG_LEVEL_TRANSFORMER": "DEBUG", "LOG_LEVEL_KAFKA": "WARN"},
+			"ERROR", "DEBUG", "WARN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.LogLevelService != tt.service || cfg.LogLevelTransformer != tt.transformer || cfg.LogLevelKafka != tt.kafka {
+				t.Errorf("levels = %s/%s/%s, want %s/%s/%s", cfg.LogLevelService, cfg.LogLevelTransformer, cfg.LogLevelKafka,
+					tt.service, tt.transformer, tt.kafka)
+			}
+		})
+	}
+}
+
+func TestSourceTopics(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"client.traffic", []string{"client.traffic"}},
+		{"client.traffic,client.bulk,client.replay", []string{"client.traffic", "client.bulk", "client.replay"}},
+		{" client.traffic , client.bulk ,", []string{"client.traffic", "client.bulk"}},
+		{" , ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"SOURCE_TOPIC": tt.value})
+			cfg, err := LoadConfig()
+			if tt.want == nil {
+				wantConfigError(t, err, "SOURCE_TOPIC must name at least one topic")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if strings.Join(cfg.SourceTopics, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("SourceTopics = %q, want %q", cfg.SourceTopics, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "text", false},
+		{"text", "text", false},
+		{"JSON", "json", false},
+		{"logfmt", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"LOG_FORMAT": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "LOG_FORMAT must be text or json")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.LogFormat != tt.want {
+				t.Errorf("LogFormat = %q, want %q", cfg.LogFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateTimeUnit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "auto", false},
+		{"auto", "auto", false},
+		{"s", "s", false},
+		{"MS", "ms", false},
+		{"us", "us", false},
+		{"ns", "ns", false},
+		{"seconds", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"DATETIME_UNIT": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "DATETIME_UNIT must be one of auto, s, ms, us, ns")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.DateTimeUnit != tt.want {
+				t.Errorf("DateTimeUnit = %q, want %q", cfg.DateTimeUnit, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrySettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    int
+		wantErr string
+	}{
+		{"default", nil, 3, ""},
+		{"disabled", map[string]string{"MAX_RETRIES": "0", "RETRY_BACKOFF": "0s"}, 0, ""},
+		{"negative", map[string]string{"MAX_RETRIES": "-1"}, 0, "MAX_RETRIES must not be negative, got -1"},
+		{"not a number falls back to the default", map[string]string{"MAX_RETRIES": "lots"}, 3, ""},
+		{"no backoff", map[string]string{"MAX_RETRIES": "2", "RETRY_BACKOFF": "0s"}, 0, "RETRY_BACKOFF must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.MaxRetries != tt.want {
+				t.Errorf("MaxRetries = %d, want %d", cfg.MaxRetries, tt.want)
+			}
+		})
+	}
+}
+
+func TestSASLSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"disabled", map[string]string{"SOURCE_SASL_ENABLED": "false"}, ""},
+		{"plaintext without SASL", map[string]string{"SOURCE_SECURITY_PROTOCOL": "PLAINTEXT"}, ""},
+		{"plain", map[string]string{"SOURCE_SASL_ENABLED": "true", "SOURCE_SASL_USERNAME": "svc", "SOURCE_SASL_PASSWORD": "pw"}, ""},
+		{"scram over TLS", map[string]string{"DESTINATION_SASL_ENABLED": "true", "DESTINATION_SASL_MECHANISM": "SCRAM-SHA-512",
+			"DESTINATION_SECURITY_PROTOCOL": "SASL_SSL", "DESTINATION_SASL_USERNAME": "svc", "DESTINATION_SASL_PASSWORD": "pw"}, ""},
+		{"unknown protocol", map[string]string{"SOURCE_SECURITY_PROTOCOL": "TLS"},
+			`SOURCE_SECURITY_PROTOCOL must be one of PLAINTEXT, SSL, SASL_PLAINTEXT, SASL_SSL, got "TLS"`},
+		{"SASL over a non-SASL protocol", map[string]string{"DESTINATION_SASL_ENABLED": "true", "DESTINATION_SECURITY_PROTOCOL": "SSL",
+			"DESTINATION_SASL_USERNAME": "svc", "DESTINATION_SASL_PASSWORD": "pw"},
+			"DESTINATION_SASL_ENABLED requires DESTINATION_SECURITY_PROTOCOL to be SASL_PLAINTEXT or SASL_SSL"},
+		{"unknown mechanism", map[string]string{"SOURCE_SASL_ENABLED": "true", "SOURCE_SASL_MECHANISM": "GSSAPI",
+			"SOURCE_SASL_USERNAME": "svc", "SOURCE_SASL_PASSWORD": "pw"},
+			`SOURCE_SASL_MECHANISM must be one of PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, got "GSSAPI"`},
+		{"missing username", map[string]string{"SOURCE_SASL_ENABLED": "true", "SOURCE_SASL_PASSWORD": "pw"},
+			"SOURCE_SASL_USERNAME is required when SOURCE_SASL_ENABLED is true"},
+		{"missing password", map[string]string{"DESTINATION_SASL_ENABLED": "true", "DESTINATION_SASL_USERNAME": "svc"},
+			"DESTINATION_SASL_PASSWORD or DESTINATION_SASL_PASSWORD_FILE is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			_, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestTLSSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"CA only", map[string]string{"SOURCE_SSL_CA_LOCATION": "/certs/ca.pem"}, ""},
+		{"client pair", map[string]string{"DESTINATION_SSL_CERTIFICATE_LOCATION": "/certs/client.pem", "DESTINATION_SSL_KEY_LOCATION": "/certs/client.key"}, ""},
+		{"certificate without key", map[string]string{"SOURCE_SSL_CERTIFICATE_LOCATION": "/certs/client.pem"},
+			"SOURCE_SSL_CERTIFICATE_LOCATION and SOURCE_SSL_KEY_LOCATION must be set together"},
+		{"key without certificate", map[string]string{"DESTINATION_SSL_KEY_LOCATION": "/certs/client.key"},
+			"DESTINATION_SSL_CERTIFICATE_LOCATION and DESTINATION_SSL_KEY_LOCATION must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			_, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestDestinationCompression(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "none", false},
+		{"none", "none", false},
+		{"gzip", "gzip", false},
+		{"Snappy", "snappy", false},
+		{"lz4", "lz4", false},
+		{"ZSTD", "zstd", false},
+		{"brotli", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"DESTINATION_COMPRESSION": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "DESTINATION_COMPRESSION must be one of none, gzip, snappy, lz4, zstd")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.DestinationCompression != tt.want {
+				t.Errorf("DestinationCompression = %q, want %q", cfg.DestinationCompression, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     time.Duration
+		wantWarn string
+	}{
+		{"unset", nil, 0, ""},
+		{"current name", map[string]string{"MESSAGE_PROCESSING_DEADLINE": "5s"}, 5 * time.Second, ""},
+		{"deprecated alias", map[string]string{"MESSAGE_DEADLINE": "3s"}, 3 * time.Second,
+			"MESSAGE_DEADLINE is deprecated, use MESSAGE_PROCESSING_DEADLINE"},
+		{"both set", map[string]string{"MESSAGE_PROCESSING_DEADLINE": "5s", "MESSAGE_DEADLINE": "3s"}, 5 * time.Second,
+			"MESSAGE_DEADLINE is deprecated and ignored since MESSAGE_PROCESSING_DEADLINE is set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.MessageDeadline != tt.want {
+				t.Errorf("MessageDeadline = %v, want %v", cfg.MessageDeadline, tt.want)
+			}
+			if tt.wantWarn == "" && strings.Contains(logs.String(), "MESSAGE_DEADLINE") {
+				t.Errorf("logs = %q, want no deprecation warning", logs.String())
+			}
+			if !strings.Contains(logs.String(), tt.wantWarn) {
+				t.Errorf("logs = %q, want %q", logs.String(), tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestTransformMode(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		want            string
+		wantPassthrough bool
+		wantFormat      string
+		wantErr         bool
+	}{
+		{"default", nil, TransformModeFlat, false, "json", false},
+		{"derived from PASSTHROUGH", map[string]string{"PASSTHROUGH": "true"}, TransformModePassthrough, true, "json", false},
+		{"derived from OUTPUT_FORMAT", map[string]string{"OUTPUT_FORMAT": "proto"}, TransformModeProto, false, "proto", false},
+		{"flat overrides", map[string]string{"TRANSFORM_MODE": "flat", "PASSTHROUGH": "true", "OUTPUT_FORMAT": "proto"}, TransformModeFlat, false, "json", false},
+		{"proto overrides", map[string]string{"TRANSFORM_MODE": "Proto", "PASSTHROUGH": "true"}, TransformModeProto, false, "proto", false},
+		{"passthrough", map[string]string{"TRANSFORM_MODE": "passthrough"}, TransformModePassthrough, true, "json", false},
+		{"unknown", map[string]string{"TRANSFORM_MODE": "raw"}, "", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "TRANSFORM_MODE must be flat, proto or passthrough")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.TransformMode != tt.want || cfg.Passthrough != tt.wantPassthrough || cfg.OutputFormat != tt.wantFormat {
+				t.Errorf("TransformMode, Passthrough, OutputFormat = %q, %v, %q, want %q, %v, %q",
+					cfg.TransformMode, cfg.Passthrough, cfg.OutputFormat, tt.want, tt.wantPassthrough, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "json", false},
+		{"json", "json", false},
+		{"PROTO", "proto", false},
+		{"avro", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"OUTPUT_FORMAT": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "OUTPUT_FORMAT must be json or proto")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.OutputFormat != tt.want {
+				t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantPaths   []string
+		wantCodes   []string
+		wantMethods []string
+		wantErr     string
+	}{
+		{"unset", nil, nil, nil, nil, ""},
+		{"lists trimmed", map[string]string{
+			"SKIP_PATHS":        "/healthz, /static/** ,",
+			"SKIP_STATUS_CODES": "304, 5XX",
+			"SKIP_METHODS":      "options,HEAD",
+		}, []string{"/healthz", "/static/**"}, []string{"304", "5XX"}, []string{"options", "HEAD"}, ""},
+		{"invalid status code", map[string]string{"SKIP_STATUS_CODES": "404,teapot"}, nil, nil, nil, "SKIP_STATUS_CODES entries must be status codes"},
+		{"status class too long", map[string]string{"SKIP_STATUS_CODES": "40xx"}, nil, nil, nil, "SKIP_STATUS_CODES entries must be status codes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.SkipPaths, tt.wantPaths) || !reflect.DeepEqual(cfg.SkipStatusCodes, tt.wantCodes) || !reflect.DeepEqual(cfg.SkipMethods, tt.wantMethods) {
+				t.Errorf("skip settings = %v, %v, %v, want %v, %v, %v",
+					cfg.SkipPaths, cfg.SkipStatusCodes, cfg.SkipMethods, tt.wantPaths, tt.wantCodes, tt.wantMethods)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 0},
+		{"1048576", 1048576},
+		{"0", 0},
+		{"-1", 0},
+		{"lots", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"MAX_BODY_BYTES": tt.value})
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.MaxBodyBytes != tt.want {
+				t.Errorf("MaxBodyBytes = %d, want %d", cfg.MaxBodyBytes, tt.want)
+			}
+		})
+	}
+}
+
+func TestTombstonePolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "skip", false},
+		{"skip", "skip", false},
+		{"Forward-Tombstone", "forward-tombstone", false},
+		{"delete", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"TOMBSTONE_POLICY": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "TOMBSTONE_POLICY must be skip or forward-tombstone")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.TombstonePolicy != tt.want {
+				t.Errorf("TombstonePolicy = %q, want %q", cfg.TombstonePolicy, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtoOutputCombinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"proto alone", map[string]string{"OUTPUT_FORMAT": "proto"}, ""},
+		{"json with envelope", map[string]string{"ENVELOPE": "true"}, ""},
+		{"proto with envelope", map[string]string{"OUTPUT_FORMAT": "proto", "ENVELOPE": "true"}, "ENVELOPE"},
+		{"proto mode with envelope", map[string]string{"TRANSFORM_MODE": "proto", "ENVELOPE": "true"}, "ENVELOPE"},
+		{"proto with kafka timestamp", map[string]string{"OUTPUT_FORMAT": "proto", "EMIT_KAFKA_TIMESTAMP": "true"}, "EMIT_KAFKA_TIMESTAMP"},
+		{"proto with outputs", map[string]string{"OUTPUT_FORMAT": "proto", "OUTPUTS": "akto.api.logs.v2=v2"}, "OUTPUTS"},
+		{"flat mode overrides proto format", map[string]string{"TRANSFORM_MODE": "flat", "OUTPUT_FORMAT": "proto", "ENVELOPE": "true"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			_, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientIDSource(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "auto", false},
+		{"config", "config", false},
+		{"HEADER", "header", false},
+		{"payload", "payload", false},
+		{"kafka-key", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t, map[string]string{"CLIENT_ID_SOURCE": tt.value})
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				wantConfigError(t, err, "CLIENT_ID_SOURCE must be one of")
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.ClientIDSource != tt.want {
+				t.Errorf("ClientIDSource = %q, want %q", cfg.ClientIDSource, tt.want)
+			}
+		})
+	}
+}
+
+func TestReservedClientPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"default allows a reserved CLIENT_ID", map[string]string{"CLIENT_ID": "default-client"}, ""},
+		{"reject with a regular CLIENT_ID", map[string]string{"RESERVED_CLIENT_POLICY": "reject"}, ""},
+		{"reject with a reserved CLIENT_ID", map[string]string{"RESERVED_CLIENT_POLICY": "reject", "CLIENT_ID": "default-client"},
+			`CLIENT_ID "default-client" is reserved`},
+		{"reject with a custom reserved list", map[string]string{"RESERVED_CLIENT_POLICY": "reject", "RESERVED_CLIENT_IDS": "0,1000"},
+			`CLIENT_ID "1000" is reserved`},
+		{"quarantine with a topic", map[string]string{"RESERVED_CLIENT_POLICY": "quarantine", "QUARANTINE_TOPIC": "transformer-quarantine"}, ""},
+		{"quarantine without a topic", map[string]string{"RESERVED_CLIENT_POLICY": "quarantine"}, "QUARANTINE_TOPIC is required"},
+		{"unknown policy", map[string]string{"RESERVED_CLIENT_POLICY": "drop"}, "RESERVED_CLIENT_POLICY must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			_, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetEnvInt(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  int
+	}{
+		{"valid", "25", 25},
+		{"zero", "0", 0},
+		{"missing", "", 10},
+		{"not a number", "ten", 10},
+		{"negative", "-3", 10},
+		{"fractional", "2.5", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_INT", tt.value)
+			if got := getEnvInt("TEST_INT", 10); got != tt.want {
+				t.Errorf("getEnvInt(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"valid", "250ms", 250 * time.Millisecond},
+		{"compound", "1m30s", 90 * time.Second},
+		{"zero", "0s", 0},
+		{"missing", "", 5 * time.Second},
+		{"missing unit", "30", 5 * time.Second},
+		{"negative", "-1s", 5 * time.Second},
+		{"garbage", "soon", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_DURATION", tt.value)
+			if got := getEnvDuration("TEST_DURATION", 5*time.Second); got != tt.want {
+				t.Errorf("getEnvDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessingSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantConcurrent int
+		wantCommit     time.Duration
+		wantPoll       time.Duration
+		wantErr        string
+	}{
+		{name: "defaults", wantConcurrent: 10, wantCommit: 5 * time.Second, wantPoll: 10 * time.Second},
+		{
+			name:           "custom",
+			env:            map[string]string{"MAX_CONCURRENT_MESSAGES": "64", "COMMIT_INTERVAL": "1s", "PROCESSING_TIMEOUT": "100ms"},
+			wantConcurrent: 64, wantCommit: time.Second, wantPoll: 100 * time.Millisecond,
+		},
+		{name: "zero concurrency", env: map[string]string{"MAX_CONCURRENT_MESSAGES": "0"}, wantErr: "MAX_CONCURRENT_MESSAGES must be positive"},
+		{name: "zero commit interval", env: map[string]string{"COMMIT_INTERVAL": "0s"}, wantErr: "COMMIT_INTERVAL must be positive"},
+		{name: "zero poll timeout", env: map[string]string{"PROCESSING_TIMEOUT": "0s"}, wantErr: "PROCESSING_TIMEOUT must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t, tt.env)
+			cfg, err := LoadConfig()
+			if tt.wantErr != "" {
+				wantConfigError(t, err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.MaxConcurrentMessages != tt.wantConcurrent || cfg.CommitInterval != tt.wantCommit || cfg.ProcessingTimeout != tt.wantPoll {
+				t.Errorf("settings = %d/%v/%v, want %d/%v/%v", cfg.MaxConcurrentMessages, cfg.CommitInterval, cfg.ProcessingTimeout,
+					tt.wantConcurrent, tt.wantCommit, tt.wantPoll)
+			}
+		})
+	}
+}
+
+func TestPasswordFileReload(t *testing.T) {
+	file := t.TempDir() + "/password"
+	tests := []struct {
+		name     string
+		password string
+		file     string
+		contents string // Written to file before reading; "" leaves it absent
+		want     string
+		wantErr  bool
+	}{
+		{"inline password", "inline", "", "", "inline", false},
+		{"file wins over inline", "inline", file, "first\n", "first", false},
+		{"rotated file reread", "inline", file, "second", "second", false},
+		{"missing file", "inline", t.TempDir() + "/missing", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.contents != "" {
+				if err := os.WriteFile(tt.file, []byte(tt.contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := &Config{SourceSASLPassword: tt.password, SourceSASLPasswordFile: tt.file}
+			got, err := cfg.SourcePassword()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("SourcePassword = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
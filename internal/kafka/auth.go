@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"client-message-transformer/internal/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// authErrorCodes maps AUTH_RETRY_ERRORS names to Kafka error codes
+var authErrorCodes = map[string]kafka.ErrorCode{
+	"authentication":               kafka.ErrAuthentication,
+	"sasl_authentication_failed":   kafka.ErrSaslAuthenticationFailed,
+	"cluster_authorization_failed": kafka.ErrClusterAuthorizationFailed,
+	"topic_authorization_failed":   kafka.ErrTopicAuthorizationFailed,
+	"group_authorization_failed":   kafka.ErrGroupAuthorizationFailed,
+}
+
+// AuthRetry retries connections failing with selected authentication errors
+type AuthRetry struct {
+	Codes    []kafka.ErrorCode
+	Attempts int
+	Backoff  time.Duration
+}
+
+// NewAuthRetry builds an AuthRetry from AUTH_RETRY_ERRORS names
+func NewAuthRetry(names []string, attempts int, backoff time.Duration) (*AuthRetry, error) {
+	retry := &AuthRetry{Attempts: attempts, Backoff: backoff}
+	for _, name := range names {
+		code, ok := authErrorCodes[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown authentication error %q", name)
+		}
+		retry.Codes = append(retry.Codes, code)
+	}
+	return retry, nil
+}
+
+// IsAuthError reports whether err carries a Kafka authentication or
+// authorization error, which retrying with the same credentials cannot fix
+func IsAuthError(err error) bool {
+	var kafkaErr kafka.Error
+	if !errors.As(err, &kafkaErr) {
+		return false
+	}
+	for _, code := range authErrorCodes {
+		if kafkaErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether err is one of the configured authentication errors
+func (r *AuthRetry) retryable(err error) bool {
+	var kafkaErr kafka.Error
+	if !errors.As(err, &kafkaErr) {
+		return false
+	}
+	for _, code := range r.Codes {
+		if kafkaErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect runs connect, calling it again with exponential backoff while it
+// fails with a retryable authentication error. connect should build a fresh
+// client on every call so reloaded credentials take effect.
+func (r *AuthRetry) Connect(name string, log *logger.Logger, connect func() error) error {
+	backoff := r.Backoff
+	for attempt := 0; ; attempt++ {
+		err := connect()
+		if err == nil || !r.retryable(err) || attempt >= r.Attempts {
+			return err
+		}
+		log.Warnf("🔐 %s authentication failed (%v), retrying with reloaded credentials in %v (attempt %d/%d)",
+			name, err, backoff, attempt+1, r.Attempts)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestAuthRetryConnect(t *testing.T) {
+	authFailed := kafka.NewError(kafka.ErrSaslAuthenticationFailed, "bad credentials", false)
+	tests := []struct {
+		name      string
+		attempts  int
+		failures  []error // Returned by successive connects; success once exhausted
+		wantCalls int
+		wantErr   bool
+	}{
+		{"connects first time", 3, nil, 1, false},
+		{"auth error then success", 3, []error{authFailed}, 2, false},
+		{"wrapped auth error then success", 3, []error{errors.Join(errors.New("source brokers not ready"), authFailed)}, 2, false},
+		{"succeeds on the last attempt", 2, []error{authFailed, authFailed}, 3, false},
+		{"attempts exhausted", 1, []error{authFailed, authFailed}, 2, true},
+		{"retries disabled", 0, []error{authFailed}, 1, true},
+		{"unlisted auth error", 3, []error{kafka.NewError(kafka.ErrTopicAuthorizationFailed, "denied", false)}, 1, true},
+		{"not an auth error", 3, []error{errors.New("connection refused")}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, err := NewAuthRetry([]string{"sasl_authentication_failed"}, tt.attempts, time.Millisecond)
+			if err != nil {
+				t.Fatalf("NewAuthRetry: %v", err)
+			}
+			calls := 0
+			err = retry.Connect("Source", quietLogger, func() error {
+				calls++
+				if calls <= len(tt.failures) {
+					return tt.failures[calls-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Connect error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("connect called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNewAuthRetry(t *testing.T) {
+	tests := []struct {
+		names   []string
+		want    int
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]string{"authentication", "SASL_AUTHENTICATION_FAILED"}, 2, false},
+		{[]string{"authentication", "timeout"}, 0, true},
+	}
+	for _, tt := range tests {
+		retry, err := NewAuthRetry(tt.names, 3, time.Second)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewAuthRetry(%v) error = %v, wantErr %v", tt.names, err, tt.wantErr)
+			continue
+		}
+		if err == nil && len(retry.Codes) != tt.want {
+			t.Errorf("NewAuthRetry(%v) has %d codes, want %d", tt.names, len(retry.Codes), tt.want)
+		}
+	}
+}
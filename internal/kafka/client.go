@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"client-message-transformer/internal/logger"
+
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
@@ -17,10 +19,70 @@ type ClientConfig struct {
 	SASLUsername     string
 	SASLPassword     string
 	SecurityProtocol string
+
+	// TLS files: the CA bundle verifying brokers and, for mutual TLS, the
+	// client certificate and key. Without SASL, setting any selects the SSL
+	// security protocol.
+	SSLCALocation          string
+	SSLCertificateLocation string
+	SSLKeyLocation         string
+
+	// Producer delivery timeouts in milliseconds
+	RequestTimeoutMs  int
+	DeliveryTimeoutMs int
+
+	// Compression is the producer's compression.type (empty keeps none)
+	Compression string
+
+	// MaxPollIntervalMs is the consumer's max.poll.interval.ms (0 keeps the default)
+	MaxPollIntervalMs int
+
+	// Logger receives client logs and sets librdkafka's log level; nil logs at INFO
+	Logger *logger.Logger
+}
+
+// log returns the configured logger or an INFO-level default
+func (c *ClientConfig) log() *logger.Logger {
+	if c.Logger == nil {
+		return logger.NewLogger("INFO", nil)
+	}
+	return c.Logger
+}
+
+// applySSL sets the TLS file locations that are configured, reporting
+// whether any were
+func (c *ClientConfig) applySSL(configMap *kafka.ConfigMap) bool {
+	set := false
+	for key, location := range map[string]string{
+		"ssl.ca.location":          c.SSLCALocation,
+		"ssl.certificate.location": c.SSLCertificateLocation,
+		"ssl.key.location":         c.SSLKeyLocation,
+	} {
+		if location != "" {
+			configMap.SetKey(key, location)
+			set = true
+		}
+	}
+	return set
+}
+
+// syslogLevel maps a logger level to the syslog level librdkafka expects
+func syslogLevel(level logger.LogLevel) int {
+	switch level {
+	case logger.DEBUG:
+		return 7
+	case logger.WARN:
+		return 4
+	case logger.ERROR:
+		return 3
+	default:
+		return 6
+	}
 }
 
 // NewConsumer creates a new Kafka consumer
 func NewConsumer(config *ClientConfig) (*kafka.Consumer, error) {
+	log := config.log()
 	configMap := &kafka.ConfigMap{
 		"bootstrap.servers":               config.Brokers,
 		"group.id":                        config.ConsumerGroup,
@@ -33,6 +95,11 @@ func NewConsumer(config *ClientConfig) (*kafka.Consumer, error) {
 		"reconnect.backoff.ms":            100,
 		"reconnect.backoff.max.ms":        10000,
 		"metadata.max.age.ms":             300000,
+		"log_level":                       syslogLevel(log.Level()),
+	}
+
+	if config.MaxPollIntervalMs > 0 {
+		configMap.SetKey("max.poll.interval.ms", config.MaxPollIntervalMs)
 	}
 
 	// Add SASL configuration if enabled
@@ -41,10 +108,17 @@ func NewConsumer(config *ClientConfig) (*kafka.Consumer, error) {
 		configMap.SetKey("sasl.mechanism", config.SASLMechanism)
 		configMap.SetKey("sasl.username", config.SASLUsername)
 		configMap.SetKey("sasl.password", config.SASLPassword)
-		fmt.Printf("🔐 Consumer SASL Config: protocol=%s, mechanism=%s, username=%s\n",
+		log.Infof("🔐 Consumer SASL Config: protocol=%s, mechanism=%s, username=%s",
 			config.SecurityProtocol, config.SASLMechanism, config.SASLUsername)
 	} else {
-		fmt.Printf("⚠️  Consumer SASL DISABLED\n")
+		log.Warn("⚠️  Consumer SASL DISABLED")
+	}
+
+	if config.applySSL(configMap) {
+		if !config.SASLEnabled {
+			configMap.SetKey("security.protocol", "SSL")
+		}
+		log.Info("🔒 Consumer TLS certificates configured")
 	}
 
 	consumer, err := kafka.NewConsumer(configMap)
@@ -55,46 +129,66 @@ func NewConsumer(config *ClientConfig) (*kafka.Consumer, error) {
 	return consumer, nil
 }
 
+// producerConfigMap builds the librdkafka settings for a producer
+func (c *ClientConfig) producerConfigMap(log *logger.Logger) *kafka.ConfigMap {
+	configMap := &kafka.ConfigMap{
+		"bootstrap.servers":                     c.Brokers,
+		"acks":                                  "all",
+		"retries":                               10,
+		"max.in.flight.requests.per.connection": 5,
+		"socket.keepalive.enable":               true,
+		"socket.timeout.ms":                     60000,
+		"api.version.request.timeout.ms":        30000,
+		"reconnect.backoff.ms":                  100,
+		"reconnect.backoff.max.ms":              10000,
+		"metadata.max.age.ms":                   300000,
+		"request.timeout.ms":                    c.RequestTimeoutMs,
+		"delivery.timeout.ms":                   c.DeliveryTimeoutMs,
+		"log_level":                             syslogLevel(log.Level()),
+	}
+
+	if c.Compression != "" {
+		configMap.SetKey("compression.type", c.Compression)
+	}
+
+	// Add SASL configuration if enabled
+	if c.SASLEnabled {
+		configMap.SetKey("security.protocol", c.SecurityProtocol)
+		configMap.SetKey("sasl.mechanism", c.SASLMechanism)
+		configMap.SetKey("sasl.username", c.SASLUsername)
+		configMap.SetKey("sasl.password", c.SASLPassword)
+		log.Infof("🔐 Producer SASL Config: protocol=%s, mechanism=%s, username=%s",
+			c.SecurityProtocol, c.SASLMechanism, c.SASLUsername)
+	} else {
+		log.Warn("⚠️  Producer SASL DISABLED")
+	}
+
+	if c.applySSL(configMap) {
+		if !c.SASLEnabled {
+			configMap.SetKey("security.protocol", "SSL")
+		}
+		log.Info("🔒 Producer TLS certificates configured")
+	}
+
+	return configMap
+}
+
 // NewProducer creates a new Kafka producer with retry logic
 func NewProducer(config *ClientConfig) (*kafka.Producer, error) {
 	maxRetries := 5
 	retryDelay := time.Second * 3
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		configMap := &kafka.ConfigMap{
-			"bootstrap.servers":                     config.Brokers,
-			"acks":                                  "all",
-			"retries":                               10,
-			"max.in.flight.requests.per.connection": 5,
-			"socket.keepalive.enable":               true,
-			"socket.timeout.ms":                     60000,
-			"api.version.request.timeout.ms":        30000,
-			"reconnect.backoff.ms":                  100,
-			"reconnect.backoff.max.ms":              10000,
-			"metadata.max.age.ms":                   300000,
-			"delivery.timeout.ms":                   300000,
-		}
-
-		// Add SASL configuration if enabled
-		if config.SASLEnabled {
-			configMap.SetKey("security.protocol", config.SecurityProtocol)
-			configMap.SetKey("sasl.mechanism", config.SASLMechanism)
-			configMap.SetKey("sasl.username", config.SASLUsername)
-			configMap.SetKey("sasl.password", config.SASLPassword)
-			fmt.Printf("🔐 Producer SASL Config: protocol=%s, mechanism=%s, username=%s\n",
-				config.SecurityProtocol, config.SASLMechanism, config.SASLUsername)
-		} else {
-			fmt.Printf("⚠️  Producer SASL DISABLED\n")
-		}
+	log := config.log()
 
-		producer, err := kafka.NewProducer(configMap)
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		producer, err := kafka.NewProducer(config.producerConfigMap(log))
 		if err == nil {
-			fmt.Printf("✅ Producer connected to %s\n", config.Brokers)
+			log.Infof("✅ Producer connected to %s", config.Brokers)
 			return producer, nil
 		}
 
 		if attempt < maxRetries {
-			fmt.Printf("⏳ Producer connection attempt %d/%d failed, retrying in %v...\n", attempt, maxRetries, retryDelay)
+			log.Warnf("⏳ Producer connection attempt %d/%d failed, retrying in %v...", attempt, maxRetries, retryDelay)
 			time.Sleep(retryDelay)
 			retryDelay = time.Duration(float64(retryDelay) * 1.5) // Exponential backoff with 1.5x multiplier
 		} else {
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// configValue reads a key from a ConfigMap, failing the test when it is unset
+func configValue(t *testing.T, configMap *kafka.ConfigMap, key string) kafka.ConfigValue {
+	t.Helper()
+	value, err := configMap.Get(key, nil)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if value == nil {
+		t.Fatalf("%s is not set", key)
+	}
+	return value
+}
+
+func TestProducerTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  int
+		delivery int
+	}{
+		{"defaults", 30000, 300000},
+		{"custom", 5000, 60000},
+		{"equal", 10000, 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ClientConfig{Brokers: "localhost:9092", RequestTimeoutMs: tt.request, DeliveryTimeoutMs: tt.delivery, Logger: quietLogger}
+			configMap := config.producerConfigMap(quietLogger)
+			if got := configValue(t, configMap, "request.timeout.ms"); got != tt.request {
+				t.Errorf("request.timeout.ms = %v, want %d", got, tt.request)
+			}
+			if got := configValue(t, configMap, "delivery.timeout.ms"); got != tt.delivery {
+				t.Errorf("delivery.timeout.ms = %v, want %d", got, tt.delivery)
+			}
+		})
+	}
+}
+
+func TestProducerSASL(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    ClientConfig
+		wantKeys  map[string]string
+		wantUnset []string
+	}{
+		{"disabled", ClientConfig{SecurityProtocol: "SASL_PLAINTEXT", SASLMechanism: "PLAIN"},
+			nil, []string{"security.protocol", "sasl.mechanism", "sasl.username", "sasl.password"}},
+		{"plain", ClientConfig{SASLEnabled: true, SecurityProtocol: "SASL_PLAINTEXT", SASLMechanism: "PLAIN", SASLUsername: "svc", SASLPassword: "pw"},
+			map[string]string{"security.protocol": "SASL_PLAINTEXT", "sasl.mechanism": "PLAIN", "sasl.username": "svc", "sasl.password": "pw"}, nil},
+		{"scram over TLS", ClientConfig{SASLEnabled: true, SecurityProtocol: "SASL_SSL", SASLMechanism: "SCRAM-SHA-512", SASLUsername: "svc", SASLPassword: "pw"},
+			map[string]string{"security.protocol": "SASL_SSL", "sasl.mechanism": "SCRAM-SHA-512"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Brokers = "localhost:9092"
+			configMap := tt.config.producerConfigMap(quietLogger)
+			for key, want := range tt.wantKeys {
+				if got := configValue(t, configMap, key); got != want {
+					t.Errorf("%s = %v, want %q", key, got, want)
+				}
+			}
+			for _, key := range tt.wantUnset {
+				if value, _ := configMap.Get(key, nil); value != nil {
+					t.Errorf("%s = %v, want it unset", key, value)
+				}
+			}
+		})
+	}
+}
+
+func TestProducerTLS(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       ClientConfig
+		wantKeys     map[string]string
+		wantUnset    []string
+		wantProtocol string // Empty when security.protocol stays unset
+	}{
+		{"no certificates", ClientConfig{}, nil,
+			[]string{"ssl.ca.location", "ssl.certificate.location", "ssl.key.location"}, ""},
+		{"CA only", ClientConfig{SSLCALocation: "/certs/ca.pem"},
+			map[string]string{"ssl.ca.location": "/certs/ca.pem"}, []string{"ssl.certificate.location", "ssl.key.location"}, "SSL"},
+		{"mutual TLS", ClientConfig{SSLCALocation: "/certs/ca.pem", SSLCertificateLocation: "/certs/client.pem", SSLKeyLocation: "/certs/client.key"},
+			map[string]string{"ssl.ca.location": "/certs/ca.pem", "ssl.certificate.location": "/certs/client.pem", "ssl.key.location": "/certs/client.key"}, nil, "SSL"},
+		{"with SASL", ClientConfig{SSLCALocation: "/certs/ca.pem", SASLEnabled: true, SecurityProtocol: "SASL_SSL", SASLMechanism: "PLAIN"},
+			map[string]string{"ssl.ca.location": "/certs/ca.pem"}, nil, "SASL_SSL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Brokers = "localhost:9092"
+			configMap := tt.config.producerConfigMap(quietLogger)
+			for key, want := range tt.wantKeys {
+				if got := configValue(t, configMap, key); got != want {
+					t.Errorf("%s = %v, want %q", key, got, want)
+				}
+			}
+			for _, key := range tt.wantUnset {
+				if value, _ := configMap.Get(key, nil); value != nil {
+					t.Errorf("%s = %v, want it unset", key, value)
+				}
+			}
+			protocol, _ := configMap.Get("security.protocol", nil)
+			if (tt.wantProtocol == "" && protocol != nil) || (tt.wantProtocol != "" && protocol != tt.wantProtocol) {
+				t.Errorf("security.protocol = %v, want %q", protocol, tt.wantProtocol)
+			}
+		})
+	}
+}
+
+func TestProducerCompression(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "snappy", "lz4", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			config := &ClientConfig{Brokers: "localhost:9092", Compression: compression}
+			if got := configValue(t, config.producerConfigMap(quietLogger), "compression.type"); got != compression {
+				t.Errorf("compression.type = %v, want %q", got, compression)
+			}
+		})
+	}
+
+	config := &ClientConfig{Brokers: "localhost:9092"}
+	if value, _ := config.producerConfigMap(quietLogger).Get("compression.type", nil); value != nil {
+		t.Errorf("compression.type = %v without a codec, want it unset", value)
+	}
+}
@@ -0,0 +1,75 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"client-message-transformer/internal/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// MetadataSource is implemented by Kafka clients that can fetch cluster metadata
+type MetadataSource interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+}
+
+// maxMetadataAttemptTimeout caps a single metadata request while waiting
+const maxMetadataAttemptTimeout = 5 * time.Second
+
+// WaitForBrokers polls cluster metadata until the brokers answer or the timeout elapses
+func WaitForBrokers(client MetadataSource, timeout time.Duration, log *logger.Logger) error {
+	deadline := time.Now().Add(timeout)
+	retryDelay := 500 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		attemptTimeout := time.Until(deadline)
+		if attemptTimeout > maxMetadataAttemptTimeout {
+			attemptTimeout = maxMetadataAttemptTimeout
+		}
+		if attemptTimeout < time.Millisecond {
+			attemptTimeout = time.Millisecond
+		}
+
+		metadata, err := client.GetMetadata(nil, false, int(attemptTimeout.Milliseconds()))
+		if err == nil && len(metadata.Brokers) > 0 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no brokers in metadata response")
+		}
+		// The same credentials will keep failing, so let the caller decide
+		if IsAuthError(err) {
+			return fmt.Errorf("brokers rejected credentials: %w", err)
+		}
+
+		if time.Until(deadline) <= retryDelay {
+			return fmt.Errorf("brokers not ready after %v (%d attempts): %w", timeout, attempt, err)
+		}
+
+		log.Warnf("⏳ Broker metadata attempt %d failed, retrying in %v: %v", attempt, retryDelay, err)
+		time.Sleep(retryDelay)
+		retryDelay = time.Duration(float64(retryDelay) * 1.5) // Exponential backoff with 1.5x multiplier
+	}
+}
+
+// CheckTopics verifies that every topic exists in the cluster metadata. It
+// lists all topics rather than requesting each one so the check never
+// triggers broker-side topic auto-creation.
+func CheckTopics(client MetadataSource, topics []string, timeout time.Duration) error {
+	metadata, err := client.GetMetadata(nil, true, int(timeout.Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+
+	for _, topic := range topics {
+		topicMetadata, ok := metadata.Topics[topic]
+		if !ok {
+			return fmt.Errorf("topic %q does not exist", topic)
+		}
+		if topicMetadata.Error.Code() != kafka.ErrNoError {
+			return fmt.Errorf("topic %q is unavailable: %w", topic, topicMetadata.Error)
+		}
+	}
+	return nil
+}
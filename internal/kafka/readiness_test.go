@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fakeMetadata answers with brokers once readyAt has passed
+type fakeMetadata struct {
+	readyAt time.Time
+	err     error // Returned instead of metadata when set
+	calls   int
+	topics  map[string]kafka.TopicMetadata
+}
+
+func (f *fakeMetadata) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if time.Now().Before(f.readyAt) {
+		return nil, kafka.NewError(kafka.ErrTransport, "brokers down", false)
+	}
+	return &kafka.Metadata{
+		Brokers: []kafka.BrokerMetadata{{ID: 1, Host: "localhost", Port: 9092}},
+		Topics:  f.topics,
+	}, nil
+}
+
+var quietLogger = logger.NewLogger("ERROR", io.Discard)
+
+func TestWaitForBrokers(t *testing.T) {
+	tests := []struct {
+		name    string
+		delay   time.Duration
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"ready immediately", 0, time.Second, false},
+		{"ready after a delay", 700 * time.Millisecond, 5 * time.Second, false},
+		{"not ready before the deadline", time.Hour, time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeMetadata{readyAt: time.Now().Add(tt.delay)}
+			start := time.Now()
+			err := WaitForBrokers(source, tt.timeout, quietLogger)
+			elapsed := time.Since(start)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WaitForBrokers error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && elapsed < tt.delay {
+				t.Errorf("returned after %v, before the brokers were ready at %v", elapsed, tt.delay)
+			}
+			if tt.wantErr && elapsed > tt.timeout+time.Second {
+				t.Errorf("gave up after %v, want about %v", elapsed, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestWaitForBrokersAuthError(t *testing.T) {
+	source := &fakeMetadata{err: kafka.NewError(kafka.ErrSaslAuthenticationFailed, "bad credentials", false)}
+	err := WaitForBrokers(source, time.Minute, quietLogger)
+	if err == nil || !strings.Contains(err.Error(), "credentials") {
+		t.Fatalf("WaitForBrokers error = %v, want a credentials error", err)
+	}
+	if !IsAuthError(err) {
+		t.Error("IsAuthError = false for the wrapped authentication error")
+	}
+	if source.calls != 1 {
+		t.Errorf("GetMetadata called %d times, want 1 (auth errors are not retried)", source.calls)
+	}
+}
+
+func TestCheckTopics(t *testing.T) {
+	topics := map[string]kafka.TopicMetadata{
+		"present":     {Topic: "present"},
+		"unavailable": {Topic: "unavailable", Error: kafka.NewError(kafka.ErrLeaderNotAvailable, "no leader", false)},
+	}
+	tests := []struct {
+		name    string
+		topics  []string
+		err     error
+		wantErr string
+	}{
+		{"present", []string{"present"}, nil, ""},
+		{"no topics", nil, nil, ""},
+		{"missing", []string{"present", "missing"}, nil, `topic "missing" does not exist`},
+		{"unavailable", []string{"unavailable"}, nil, `topic "unavailable" is unavailable`},
+		{"metadata failure", []string{"present"}, errors.New("metadata request failed"), "failed to fetch metadata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeMetadata{topics: topics, err: tt.err}
+			err := CheckTopics(source, tt.topics, time.Second)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckTopics(%v) = %v", tt.topics, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckTopics(%v) = %v, want %q", tt.topics, err, tt.wantErr)
+			}
+			if source.calls != 1 {
+				t.Errorf("GetMetadata called %d times, want 1", source.calls)
+			}
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -18,14 +19,39 @@ const (
 	ERROR
 )
 
+// Log output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 // Logger provides structured logging with levels
 type Logger struct {
-	level  LogLevel
-	logger *log.Logger
+	level LogLevel
+	out   io.Writer // nil writes to the package output
+}
+
+// output is where loggers created without a writer write
+var output io.Writer = os.Stdout
+
+// format is the line format shared by all loggers
+var format = FormatText
+
+// SetOutput redirects loggers created without a writer, e.g. to stderr when
+// stdout carries data
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// SetFormat selects text or json lines for all loggers. Call it at startup,
+// before any logging.
+func SetFormat(f string) {
+	format = f
 }
 
-// NewLogger creates a new logger with specified level
-func NewLogger(levelStr string) *Logger {
+// NewLogger creates a new logger with specified level, writing to w or, when
+// w is nil, to the package output
+func NewLogger(levelStr string, w io.Writer) *Logger {
 	level := INFO
 	switch strings.ToUpper(levelStr) {
 	case "DEBUG":
@@ -39,41 +65,72 @@ func NewLogger(levelStr string) *Logger {
 	}
 
 	return &Logger{
-		level:  level,
-		logger: log.New(os.Stdout, "", 0),
+		level: level,
+		out:   w,
 	}
 }
 
+// Level returns the minimum level the logger emits
+func (l *Logger) Level() LogLevel {
+	return l.level
+}
+
+// jsonLine is a log line in json format
+type jsonLine struct {
+	TS    string `json:"ts"`
+	Level string `json:"level"`
+	Msg   string `json:"msg"`
+}
+
 // formatMessage creates a formatted log message
 func (l *Logger) formatMessage(levelStr string, msg string) string {
+	if format == FormatJSON {
+		line, err := json.Marshal(jsonLine{
+			TS:    time.Now().UTC().Format(time.RFC3339Nano),
+			Level: strings.TrimSpace(levelStr),
+			Msg:   msg,
+		})
+		if err == nil {
+			return string(line)
+		}
+	}
 	return fmt.Sprintf("[%s] %s | %s", time.Now().Format("2006-01-02 15:04:05"), levelStr, msg)
 }
 
+// write emits one line with a single write so concurrent lines don't interleave
+func (l *Logger) write(levelStr string, msg string) {
+	w := l.out
+	if w == nil {
+		w = output
+	}
+	io.WriteString(w, l.formatMessage(levelStr, msg)+"\n")
+}
+
 // Debug logs a debug message
 func (l *Logger) Debug(msg string) {
 	if l.level <= DEBUG {
-		l.logger.Println(l.formatMessage("DEBUG", msg))
+		l.write("DEBUG", msg)
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(msg string) {
 	if l.level <= INFO {
-		l.logger.Println(l.formatMessage("INFO ", msg))
+		l.write("INFO ", msg)
 	}
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(msg string) {
 	if l.level <= WARN {
-		l.logger.Println(l.formatMessage("WARN ", msg))
+		l.write("WARN ", msg)
 	}
 }
 
 // Error logs an error message
 func (l *Logger) Error(msg string) {
 	if l.level <= ERROR {
-		l.logger.Println(l.formatMessage("ERROR", msg))
+		l.write("ERROR", msg)
 	}
 }
 
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFormat selects a line format until the test ends
+func useFormat(t *testing.T, f string) {
+	t.Helper()
+	previous := format
+	SetFormat(f)
+	t.Cleanup(func() { SetFormat(previous) })
+}
+
+func TestJSONFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		log       func(l *Logger)
+		wantLevel string // Empty when the line is filtered out
+		wantMsg   string
+	}{
+		{"info", "INFO", func(l *Logger) { l.Info("started") }, "INFO", "started"},
+		{"warn trimmed", "INFO", func(l *Logger) { l.Warn("slow") }, "WARN", "slow"},
+		{"error", "INFO", func(l *Logger) { l.Errorf("failed: %d", 3) }, "ERROR", "failed: 3"},
+		{"debug filtered", "INFO", func(l *Logger) { l.Debug("noise") }, "", ""},
+		{"quotes and newlines escaped", "DEBUG", func(l *Logger) { l.Debug("say \"hi\"\nbye") }, "DEBUG", "say \"hi\"\nbye"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFormat(t, FormatJSON)
+			var out bytes.Buffer
+			tt.log(NewLogger(tt.level, &out))
+
+			if tt.wantLevel == "" {
+				if out.Len() != 0 {
+					t.Errorf("logged %q, want nothing", out.String())
+				}
+				return
+			}
+			if strings.Count(out.String(), "\n") != 1 || !strings.HasSuffix(out.String(), "\n") {
+				t.Fatalf("output %q, want a single line", out.String())
+			}
+			var line jsonLine
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatalf("line %q is not JSON: %v", out.String(), err)
+			}
+			if line.Level != tt.wantLevel || line.Msg != tt.wantMsg {
+				t.Errorf("level, msg = %q, %q, want %q, %q", line.Level, line.Msg, tt.wantLevel, tt.wantMsg)
+			}
+			if _, err := time.Parse(time.RFC3339Nano, line.TS); err != nil {
+				t.Errorf("ts = %q, want RFC 3339: %v", line.TS, err)
+			}
+		})
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *Logger)
+		want string
+	}{
+		{"info", func(l *Logger) { l.Info("started") }, "] INFO  | started\n"},
+		{"error", func(l *Logger) { l.Error("failed") }, "] ERROR | failed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFormat(t, FormatText)
+			var out bytes.Buffer
+			tt.log(NewLogger("INFO", &out))
+			if !strings.HasPrefix(out.String(), "[") || !strings.HasSuffix(out.String(), tt.want) {
+				t.Errorf("line = %q, want it to end with %q", out.String(), tt.want)
+			}
+		})
+	}
+}
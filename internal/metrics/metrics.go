@@ -1,23 +1,72 @@
 package metrics
 
 import (
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Metrics tracks transformation statistics
 type Metrics struct {
-	mu                  sync.RWMutex
-	MessagesReceived    int64
-	MessagesTransformed int64
-	MessagesFailed      int64
-	MessagesPublished   int64
-	TotalProcessingTime time.Duration
+	mu                       sync.RWMutex
+	MessagesReceived         int64
+	MessagesTransformed      int64
+	MessagesFailed           int64
+	MessagesPublished        int64
+	MessagesOutputFailed     int64
+	MessagesDeadlineExceeded int64
+	MessagesSkippedPrivateIP int64
+	MessagesSkipped          int64
+	MessagesRateLimited      int64
+	MessagesLikelyDuplicate  int64
+	MessagesRejectedDeepJSON int64
+	MessagesTombstones       int64
+	MessagesSampledOut       int64
+	MessagesQuarantined      int64
+	CapturesJoined           int64
+	RateLimitedByClient      map[string]int64
+	OutputFailedByTopic      map[string]int64
+	ByClient                 map[string]*ClientCounts
+	FieldMisses              map[string]int64
+	ConsumerLag              []PartitionLag // Latest lag sample, empty until the first check
+	TotalProcessingTime      time.Duration
+	processingDuration       prometheus.Histogram
+}
+
+// maxTrackedClients caps the per-client breakdown. Clients seen after the cap
+// is reached are counted together under OtherClients, keeping memory bounded
+// when client IDs come from message headers or payloads.
+const maxTrackedClients = 1000
+
+// OtherClients collects the counts of clients beyond maxTrackedClients
+const OtherClients = "(other)"
+
+// ClientCounts are one client's message counters
+type ClientCounts struct {
+	Received    int64 `json:"received"`
+	Transformed int64 `json:"transformed"`
+	Published   int64 `json:"published"`
+	Failed      int64 `json:"failed"`
+}
+
+// PartitionLag is the consumer lag of one assigned partition
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Lag       int64  `json:"lag"`
 }
 
 // New creates a new metrics instance
 func New() *Metrics {
-	return &Metrics{}
+	return &Metrics{
+		RateLimitedByClient: make(map[string]int64),
+		OutputFailedByTopic: make(map[string]int64),
+		ByClient:            make(map[string]*ClientCounts),
+		FieldMisses:         make(map[string]int64),
+		processingDuration:  newProcessingDuration(),
+	}
 }
 
 // IncrementReceived increments the received message counter
@@ -48,11 +97,164 @@ func (m *Metrics) IncrementPublished() {
 	m.MessagesPublished++
 }
 
+// client returns a client's counters, creating them if needed. The caller
+// must hold the lock.
+func (m *Metrics) client(clientID string) *ClientCounts {
+	counts, ok := m.ByClient[clientID]
+	if ok {
+		return counts
+	}
+	if len(m.ByClient) >= maxTrackedClients {
+		clientID = OtherClients
+		if counts, ok := m.ByClient[clientID]; ok {
+			return counts
+		}
+	}
+	counts = &ClientCounts{}
+	m.ByClient[clientID] = counts
+	return counts
+}
+
+// trackedClient returns the key to count a client under in a per-client map,
+// OtherClients once the map holds maxTrackedClients other clients
+func trackedClient(counts map[string]int64, clientID string) string {
+	if _, ok := counts[clientID]; ok || len(counts) < maxTrackedClients {
+		return clientID
+	}
+	return OtherClients
+}
+
+// IncrementReceivedFor increments the received counters, overall and for a client
+func (m *Metrics) IncrementReceivedFor(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesReceived++
+	m.client(clientID).Received++
+}
+
+// IncrementTransformedFor increments the transformed counters, overall and for a client
+func (m *Metrics) IncrementTransformedFor(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesTransformed++
+	m.client(clientID).Transformed++
+}
+
+// IncrementPublishedFor increments the published counters, overall and for a client
+func (m *Metrics) IncrementPublishedFor(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesPublished++
+	m.client(clientID).Published++
+}
+
+// IncrementFailedFor increments the failed counters, overall and for a client
+func (m *Metrics) IncrementFailedFor(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesFailed++
+	m.client(clientID).Failed++
+}
+
+// IncrementOutputFailed counts a message that could not reach an extra output
+func (m *Metrics) IncrementOutputFailed(topic string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesOutputFailed++
+	m.OutputFailedByTopic[topic]++
+}
+
+// IncrementDeadlineExceeded increments the counter of messages abandoned past their deadline
+func (m *Metrics) IncrementDeadlineExceeded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesDeadlineExceeded++
+}
+
+// IncrementSkippedPrivateIP increments the counter of messages dropped for a private source IP
+func (m *Metrics) IncrementSkippedPrivateIP() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesSkippedPrivateIP++
+}
+
+// IncrementSkipped increments the counter of messages dropped by the skip rules
+func (m *Metrics) IncrementSkipped() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesSkipped++
+}
+
+// IncrementRateLimited increments the rate-limited counters for a client
+func (m *Metrics) IncrementRateLimited(clientID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesRateLimited++
+	m.RateLimitedByClient[trackedClient(m.RateLimitedByClient, clientID)]++
+}
+
+// IncrementLikelyDuplicate increments the counter of messages identical to their predecessor
+func (m *Metrics) IncrementLikelyDuplicate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesLikelyDuplicate++
+}
+
+// IncrementRejectedDeepJSON increments the counter of messages rejected for excessive nesting
+func (m *Metrics) IncrementRejectedDeepJSON() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesRejectedDeepJSON++
+}
+
+// IncrementTombstones increments the counter of nil-value source records
+func (m *Metrics) IncrementTombstones() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesTombstones++
+}
+
+// IncrementSampledOut increments the counter of messages dropped by sampling
+func (m *Metrics) IncrementSampledOut() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesSampledOut++
+}
+
+// IncrementQuarantined increments the counter of messages diverted for a reserved client ID
+func (m *Metrics) IncrementQuarantined() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.MessagesQuarantined++
+}
+
+// IncrementCapturesJoined increments the counter of partial captures joined with their other half
+func (m *Metrics) IncrementCapturesJoined() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CapturesJoined++
+}
+
+// IncrementFieldMiss counts a transformed message missing an expected field
+func (m *Metrics) IncrementFieldMiss(field string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.FieldMisses[field]++
+}
+
+// SetConsumerLag replaces the consumer lag sample
+func (m *Metrics) SetConsumerLag(lags []PartitionLag) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ConsumerLag = lags
+}
+
 // AddProcessingTime adds to the total processing time
 func (m *Metrics) AddProcessingTime(duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.TotalProcessingTime += duration
+	m.processingDuration.Observe(duration.Seconds())
 }
 
 // GetSnapshot returns a thread-safe snapshot of metrics
@@ -65,12 +267,76 @@ func (m *Metrics) GetSnapshot() map[string]interface{} {
 		avgTime = m.TotalProcessingTime / time.Duration(m.MessagesTransformed)
 	}
 
+	rateLimitedByClient := make(map[string]int64, len(m.RateLimitedByClient))
+	for clientID, count := range m.RateLimitedByClient {
+		rateLimitedByClient[clientID] = count
+	}
+
+	outputFailedByTopic := make(map[string]int64, len(m.OutputFailedByTopic))
+	for topic, count := range m.OutputFailedByTopic {
+		outputFailedByTopic[topic] = count
+	}
+
+	byClient := make(map[string]ClientCounts, len(m.ByClient))
+	for clientID, counts := range m.ByClient {
+		byClient[clientID] = *counts
+	}
+
+	var consumerLag int64
+	lagByPartition := make([]PartitionLag, len(m.ConsumerLag))
+	for i, lag := range m.ConsumerLag {
+		consumerLag += lag.Lag
+		lagByPartition[i] = lag
+	}
+
+	fieldMisses := make(map[string]int64, len(m.FieldMisses))
+	for field, count := range m.FieldMisses {
+		fieldMisses[field] = count
+	}
+
 	return map[string]interface{}{
-		"received":     m.MessagesReceived,
-		"transformed":  m.MessagesTransformed,
-		"published":    m.MessagesPublished,
-		"failed":       m.MessagesFailed,
-		"avg_time":     avgTime,
-		"total_time":   m.TotalProcessingTime,
+		"received":               m.MessagesReceived,
+		"transformed":            m.MessagesTransformed,
+		"published":              m.MessagesPublished,
+		"failed":                 m.MessagesFailed,
+		"output_failed":          m.MessagesOutputFailed,
+		"output_failed_by_topic": outputFailedByTopic,
+		"deadline_exceeded":      m.MessagesDeadlineExceeded,
+		"skipped_private_ip":     m.MessagesSkippedPrivateIP,
+		"skipped":                m.MessagesSkipped,
+		"rate_limited":           m.MessagesRateLimited,
+		"rate_limited_by_client": rateLimitedByClient,
+		"by_client":              byClient,
+		"likely_duplicate":       m.MessagesLikelyDuplicate,
+		"rejected_deep_json":     m.MessagesRejectedDeepJSON,
+		"tombstones":             m.MessagesTombstones,
+		"sampled_out":            m.MessagesSampledOut,
+		"quarantined":            m.MessagesQuarantined,
+		"captures_joined":        m.CapturesJoined,
+		"field_misses":           fieldMisses,
+		"consumer_lag":           consumerLag,
+		"lag_by_partition":       lagByPartition,
+		"avg_time":               avgTime,
+		"total_time":             m.TotalProcessingTime,
+	}
+}
+
+// TopClients returns up to n client IDs from a by_client snapshot, busiest
+// (most received) first
+func TopClients(byClient map[string]ClientCounts, n int) []string {
+	clientIDs := make([]string, 0, len(byClient))
+	for clientID := range byClient {
+		clientIDs = append(clientIDs, clientID)
+	}
+	sort.Slice(clientIDs, func(i, j int) bool {
+		a, b := byClient[clientIDs[i]], byClient[clientIDs[j]]
+		if a.Received != b.Received {
+			return a.Received > b.Received
+		}
+		return clientIDs[i] < clientIDs[j]
+	})
+	if len(clientIDs) > n {
+		clientIDs = clientIDs[:n]
 	}
+	return clientIDs
 }
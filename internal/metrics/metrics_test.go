@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClientCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients int // Clients incrementing concurrently
+		each    int // Messages per client
+		want    ClientCounts
+	}{
+		{"one client", 1, 100, ClientCounts{Received: 100, Transformed: 100, Published: 100, Failed: 50}},
+		{"many clients", 20, 50, ClientCounts{Received: 50, Transformed: 50, Published: 50, Failed: 25}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			var wg sync.WaitGroup
+			for c := 0; c < tt.clients; c++ {
+				clientID := fmt.Sprintf("client-%d", c)
+				for i := 0; i < tt.each; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						m.IncrementReceivedFor(clientID)
+						m.IncrementTransformedFor(clientID)
+						m.IncrementPublishedFor(clientID)
+						if i%2 == 0 {
+							m.IncrementFailedFor(clientID)
+						}
+					}(i)
+				}
+			}
+			wg.Wait()
+
+			snapshot := m.GetSnapshot()
+			byClient := snapshot["by_client"].(map[string]ClientCounts)
+			if len(byClient) != tt.clients {
+				t.Fatalf("by_client has %d clients, want %d", len(byClient), tt.clients)
+			}
+			for clientID, counts := range byClient {
+				if !reflect.DeepEqual(counts, tt.want) {
+					t.Errorf("%s counts = %+v, want %+v", clientID, counts, tt.want)
+				}
+			}
+			if got, want := snapshot["received"].(int64), int64(tt.clients*tt.each); got != want {
+				t.Errorf("received = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestClientCountsCapped(t *testing.T) {
+	tests := []struct {
+		name      string
+		increment func(m *Metrics, clientID string)
+		counts    func(snapshot map[string]interface{}) map[string]int64
+	}{
+		{"by_client", (*Metrics).IncrementReceivedFor, func(snapshot map[string]interface{}) map[string]int64 {
+			counts := make(map[string]int64)
+			for clientID, c := range snapshot["by_client"].(map[string]ClientCounts) {
+				counts[clientID] = c.Received
+			}
+			return counts
+		}},
+		{"rate_limited_by_client", (*Metrics).IncrementRateLimited, func(snapshot map[string]interface{}) map[string]int64 {
+			return snapshot["rate_limited_by_client"].(map[string]int64)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			for c := 0; c < maxTrackedClients+5; c++ {
+				tt.increment(m, fmt.Sprintf("client-%d", c))
+			}
+			tt.increment(m, "client-0") // Tracked before the cap, still counted apart
+
+			counts := tt.counts(m.GetSnapshot())
+			if len(counts) != maxTrackedClients+1 {
+				t.Errorf("%s has %d entries, want %d plus %s", tt.name, len(counts), maxTrackedClients, OtherClients)
+			}
+			if got := counts[OtherClients]; got != 5 {
+				t.Errorf("%s %s = %d, want 5", tt.name, OtherClients, got)
+			}
+			if got := counts["client-0"]; got != 2 {
+				t.Errorf("%s client-0 = %d, want 2", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestTopClients(t *testing.T) {
+	byClient := map[string]ClientCounts{"a": {Received: 1}, "b": {Received: 9}, "c": {Received: 5}, "d": {Received: 5}}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"b"}},
+		{3, []string{"b", "c", "d"}},
+		{10, []string{"b", "c", "d", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			if got := TopClients(byClient, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopClients(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
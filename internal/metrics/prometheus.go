@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processingDurationBuckets covers sub-millisecond transforms through slow produces
+var processingDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// newProcessingDuration creates the histogram AddProcessingTime observes into
+func newProcessingDuration() prometheus.Histogram {
+	return prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "message_processing_duration_seconds",
+		Help:    "Time to transform and publish a message.",
+		Buckets: processingDurationBuckets,
+	})
+}
+
+// Registry returns a Prometheus registry exposing the message counters and
+// the processing-duration histogram. The counters read the in-memory totals
+// at scrape time, so nothing is counted twice.
+func (m *Metrics) Registry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		m.counterFunc("messages_received_total", "Messages consumed from the source topic.", func() int64 { return m.MessagesReceived }),
+		m.counterFunc("messages_transformed_total", "Messages transformed successfully.", func() int64 { return m.MessagesTransformed }),
+		m.counterFunc("messages_published_total", "Messages published to the destination.", func() int64 { return m.MessagesPublished }),
+		m.counterFunc("messages_failed_total", "Messages that failed to transform or publish.", func() int64 { return m.MessagesFailed }),
+		m.processingDuration,
+		&lagCollector{metrics: m},
+	)
+	return registry
+}
+
+// consumerLagDesc describes the per-partition consumer lag gauge
+var consumerLagDesc = prometheus.NewDesc("consumer_lag", "Messages between the committed offset and the high watermark.",
+	[]string{"topic", "partition"}, nil)
+
+// lagCollector exposes the latest consumer lag sample as labeled gauges
+type lagCollector struct {
+	metrics *Metrics
+}
+
+// Describe implements prometheus.Collector
+func (c *lagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- consumerLagDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *lagCollector) Collect(ch chan<- prometheus.Metric) {
+	c.metrics.mu.RLock()
+	defer c.metrics.mu.RUnlock()
+	for _, lag := range c.metrics.ConsumerLag {
+		ch <- prometheus.MustNewConstMetric(consumerLagDesc, prometheus.GaugeValue, float64(lag.Lag),
+			lag.Topic, strconv.Itoa(int(lag.Partition)))
+	}
+}
+
+// counterFunc exposes an in-memory counter read under the metrics lock
+func (m *Metrics) counterFunc(name string, help string, value func() int64) prometheus.CounterFunc {
+	return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return float64(value())
+	})
+}
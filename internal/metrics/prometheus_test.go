@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	m := New()
+	for i := 0; i < 3; i++ {
+		m.IncrementReceived()
+	}
+	m.IncrementTransformed()
+	m.IncrementTransformed()
+	m.IncrementPublished()
+	m.IncrementFailed()
+	m.AddProcessingTime(3 * time.Millisecond)
+	m.AddProcessingTime(2 * time.Second)
+
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	gathered := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				gathered[family.GetName()] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				gathered[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"messages_received_total", 3},
+		{"messages_transformed_total", 2},
+		{"messages_published_total", 1},
+		{"messages_failed_total", 1},
+		{"message_processing_duration_seconds", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := gathered[tt.name]
+			if !ok {
+				t.Fatalf("%s not exposed", tt.name)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumerLagGauge(t *testing.T) {
+	tests := []struct {
+		name string
+		lags []PartitionLag
+		want map[string]float64 // Gauge value by partition label
+	}{
+		{"no sample yet", nil, map[string]float64{}},
+		{"per partition", []PartitionLag{{Topic: "client.traffic", Partition: 0, Lag: 150}, {Topic: "client.traffic", Partition: 1, Lag: 0}},
+			map[string]float64{"0": 150, "1": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.SetConsumerLag(tt.lags)
+			families, err := m.Registry().Gather()
+			if err != nil {
+				t.Fatalf("Gather: %v", err)
+			}
+			got := make(map[string]float64)
+			for _, family := range families {
+				if family.GetName() != "consumer_lag" {
+					continue
+				}
+				for _, metric := range family.GetMetric() {
+					labels := make(map[string]string)
+					for _, label := range metric.GetLabel() {
+						labels[label.GetName()] = label.GetValue()
+					}
+					if labels["topic"] != "client.traffic" {
+						t.Errorf("topic label = %q, want client.traffic", labels["topic"])
+					}
+					got[labels["partition"]] = metric.GetGauge().GetValue()
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("consumer_lag = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
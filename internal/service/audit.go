@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"client-message-transformer/internal/tracing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Reasons recorded on audit-topic messages for policy-dropped traffic
+const (
+	dropReasonRateLimited = "rate_limited"
+	dropReasonPrivateIP   = "private_ip"
+	dropReasonSkipped     = "skipped"
+)
+
+// auditDropped copies a message dropped by policy, unchanged, to AUDIT_TOPIC
+// with the drop reason and its source coordinates as headers. Failures are
+// logged and never affect the message's own outcome.
+func (s *TransformerService) auditDropped(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, reason string) {
+	if s.config.AuditTopic == "" {
+		return
+	}
+
+	topic := s.config.AuditTopic
+	err := s.forwardRaw(ctx, topic, clientID, kafkaMsg,
+		kafkalib.Header{Key: "drop_reason", Value: []byte(reason)},
+		kafkalib.Header{Key: "source_topic", Value: []byte(*kafkaMsg.TopicPartition.Topic)},
+		kafkalib.Header{Key: "source_partition", Value: []byte(strconv.Itoa(int(kafkaMsg.TopicPartition.Partition)))},
+		kafkalib.Header{Key: "source_offset", Value: []byte(kafkaMsg.TopicPartition.Offset.String())},
+		kafkalib.Header{Key: "dropped_at", Value: []byte(time.Now().Format(time.RFC3339))},
+	)
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to audit dropped message: %v", err))
+		return
+	}
+	s.logger.Debug(fmt.Sprintf("📝 Audited dropped message to %s (reason: %s)", topic, reason))
+}
+
+// forwardRaw produces a source message's key and value unchanged to a topic,
+// tagged with the client ID and any extra headers
+func (s *TransformerService) forwardRaw(ctx context.Context, topic string, clientID string, kafkaMsg *kafkalib.Message, extra ...kafkalib.Header) error {
+	headers := append([]kafkalib.Header{{Key: "client_id", Value: []byte(clientID)}}, extra...)
+	tracing.Inject(ctx, &headers)
+
+	err := s.produce(ctx, s.producer,
+		&kafkalib.Message{
+			TopicPartition: kafkalib.TopicPartition{
+				Topic:     &topic,
+				Partition: kafkalib.PartitionAny,
+			},
+			Key:     kafkaMsg.Key,
+			Value:   kafkaMsg.Value,
+			Headers: headers,
+		},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce message to %s: %w", topic, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestAuditDropped(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		value      string
+		wantReason string // Empty when the message is published
+	}{
+		{"skip rule", map[string]string{"SKIP_METHODS": "GET"}, sampleCapture, dropReasonSkipped},
+		{"private IP", map[string]string{"DROP_PRIVATE_IPS": "true"}, strings.Replace(sampleCapture, "203.0.113.7", "10.0.0.8", 1), dropReasonPrivateIP},
+		{"rate limit", map[string]string{"PER_CLIENT_RATE": "0.001"}, sampleCapture, dropReasonRateLimited},
+		{"published", nil, sampleCapture, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"AUDIT_TOPIC": "transformer-audit"}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			s := newTestService(t, testConfig(t, env))
+
+			// The rate limit's single token goes to a first message
+			if tt.wantReason == dropReasonRateLimited {
+				s.handleMessage(context.Background(), sourceMessage(sampleCapture, 6))
+			}
+			msg := sourceMessage(tt.value, 7)
+			msg.Key = []byte("source-key")
+			s.handleMessage(context.Background(), msg)
+
+			audited := s.sink.messages("transformer-audit")
+			if tt.wantReason == "" {
+				if len(audited) != 0 {
+					t.Errorf("audited %d published messages", len(audited))
+				}
+				return
+			}
+			if len(audited) != 1 {
+				t.Fatalf("audited %d messages, want 1", len(audited))
+			}
+			got := audited[0]
+			if string(got.Value) != tt.value || string(got.Key) != "source-key" {
+				t.Errorf("audited %q (key %q), want the source message unchanged", got.Value, got.Key)
+			}
+			wantHeaders := map[string]string{
+				"drop_reason":      tt.wantReason,
+				"source_topic":     "client.traffic",
+				"source_partition": "0",
+				"source_offset":    kafkalib.Offset(7).String(),
+			}
+			for key, want := range wantHeaders {
+				if value := headerValue(got, key); value != want {
+					t.Errorf("%s = %q, want %q", key, value, want)
+				}
+			}
+			if headerValue(got, "dropped_at") == "" {
+				t.Error("dropped_at header is missing")
+			}
+		})
+	}
+}
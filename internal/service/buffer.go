@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize keeps buffers grown by unusually large messages out of
+// the pool so they do not pin memory
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool recycles JSON marshal buffers between messages
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// marshalPooled encodes value as JSON into a pooled buffer. The returned
+// bytes alias the buffer and are only valid until releaseBuffer is called.
+func marshalPooled(value interface{}) (*bytes.Buffer, []byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(value); err != nil {
+		releaseBuffer(buf)
+		return nil, nil, err
+	}
+	// Encode terminates the document with a newline that Marshal does not
+	return buf, bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// releaseBuffer returns a marshal buffer to the pool. produce() copies message
+// values, so a buffer may be released once its publish has returned.
+func releaseBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPooledBuffersDoNotLeakAcrossMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"flat", nil},
+		{"envelope", map[string]string{"ENVELOPE": "true"}},
+		{"extra outputs", map[string]string{"OUTPUTS": "akto.api.logs.v1=v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, tt.env))
+			first := strings.Replace(sampleCapture, "/users?id=1", "/first/with/a/much/longer/path?id=1", 1)
+			second := strings.Replace(sampleCapture, "/users?id=1", "/second", 1)
+
+			s.handleMessage(context.Background(), sourceMessage(first, 0))
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			firstValue := append([]byte(nil), published[0].Value...)
+
+			// The second message reuses the pooled map and buffer
+			s.handleMessage(context.Background(), sourceMessage(second, 1))
+
+			published = s.sink.messages("akto.api.logs")
+			if !bytes.Equal(published[0].Value, firstValue) {
+				t.Errorf("first message changed after the second was processed:\n got  %s\n want %s", published[0].Value, firstValue)
+			}
+			if !json.Valid(published[1].Value) || !strings.Contains(string(published[1].Value), `"/second"`) {
+				t.Errorf("second message = %s, want valid JSON for /second", published[1].Value)
+			}
+			if strings.Contains(string(published[1].Value), "/first") {
+				t.Errorf("second message carries fields of the first: %s", published[1].Value)
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	record := map[string]interface{}{
+		"path":            "/users?id=1",
+		"method":          "GET",
+		"requestHeaders":  `{"Content-Type":"application/json"}`,
+		"responsePayload": strings.Repeat(`{"id":1}`, 64),
+		"statusCode":      "200",
+	}
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, _, err := marshalPooled(record)
+			if err != nil {
+				b.Fatal(err)
+			}
+			releaseBuffer(buf)
+		}
+	})
+	b.Run("json.Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(record); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
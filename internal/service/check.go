@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+
+	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/kafka"
+	"client-message-transformer/internal/logger"
+)
+
+// Check verifies connectivity to the source and destination brokers and that
+// the source and destination topics exist, without consuming or producing
+func Check(cfg *config.Config) error {
+	log := logger.NewLogger(cfg.LogLevelService, nil)
+	kafkaLog := logger.NewLogger(cfg.LogLevelKafka, nil)
+
+	log.Info("🔍 Checking source broker connectivity...")
+	// A check reports the first failure rather than retrying it
+	noRetry := &kafka.AuthRetry{}
+
+	consumer, err := connectConsumer(cfg, noRetry, log, kafkaLog)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	if err := checkSource(cfg, consumer, log); err != nil {
+		return err
+	}
+
+	log.Info("🔍 Checking destination broker connectivity...")
+	producer, err := connectProducer(cfg, noRetry, log, kafkaLog)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+
+	return checkDestination(cfg, producer, log)
+}
+
+// checkSource verifies that every source topic exists on the source brokers
+func checkSource(cfg *config.Config, client kafka.MetadataSource, log *logger.Logger) error {
+	if err := kafka.CheckTopics(client, cfg.SourceTopics, cfg.BrokerReadyTimeout); err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	log.Info(fmt.Sprintf("✅ Source topics %v reachable on %s", cfg.SourceTopics, cfg.SourceBrokers))
+	return nil
+}
+
+// checkDestination verifies that the destination topic and every extra
+// output topic exist on the destination brokers
+func checkDestination(cfg *config.Config, client kafka.MetadataSource, log *logger.Logger) error {
+	destinationTopics := []string{cfg.DestinationTopic}
+	for _, output := range cfg.Outputs {
+		destinationTopics = append(destinationTopics, output.Topic)
+	}
+	if err := kafka.CheckTopics(client, destinationTopics, cfg.BrokerReadyTimeout); err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+	log.Info(fmt.Sprintf("✅ Destination topics %v reachable on %s", destinationTopics, cfg.DestinationBrokers))
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"client-message-transformer/internal/logger"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fakeMetadata lists a fixed set of topics
+type fakeMetadata struct {
+	topics []string
+}
+
+func (f *fakeMetadata) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafkalib.Metadata, error) {
+	metadata := &kafkalib.Metadata{Topics: make(map[string]kafkalib.TopicMetadata)}
+	for _, name := range f.topics {
+		metadata.Topics[name] = kafkalib.TopicMetadata{Topic: name}
+	}
+	return metadata, nil
+}
+
+func TestCheckTopicsOnBothSides(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      []string
+		destination []string
+		wantErr     string
+	}{
+		{"all topics present", []string{"client.traffic"}, []string{"akto.api.logs", "akto.api.logs.v2"}, ""},
+		{"source topic missing", nil, []string{"akto.api.logs", "akto.api.logs.v2"}, `source: topic "client.traffic" does not exist`},
+		{"destination topic missing", []string{"client.traffic"}, []string{"akto.api.logs.v2"}, `destination: topic "akto.api.logs" does not exist`},
+		{"output topic missing", []string{"client.traffic"}, []string{"akto.api.logs"}, `destination: topic "akto.api.logs.v2" does not exist`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, map[string]string{"OUTPUTS": "akto.api.logs.v2=v2"})
+			log := logger.NewLogger(cfg.LogLevelService, nil)
+
+			err := checkSource(cfg, &fakeMetadata{topics: tt.source}, log)
+			if err == nil {
+				err = checkDestination(cfg, &fakeMetadata{topics: tt.destination}, log)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("check = %v, want success", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("check = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
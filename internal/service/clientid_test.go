@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestClientIDSource(t *testing.T) {
+	withAccount := `{"akto_account_id":"payload-7","request":{}}`
+	tests := []struct {
+		source string
+		header string // client_id header; "" sends none
+		value  string
+		want   string
+	}{
+		{"config", "header-5", withAccount, "1000"},
+		{"header", "header-5", withAccount, "header-5"},
+		{"header", "", withAccount, "1000"},
+		{"payload", "header-5", withAccount, "payload-7"},
+		{"payload", "", `{"request":{}}`, "1000"},
+		{"payload", "", `{"akto_account_id":7}`, "1000"},
+		{"payload", "", `not json`, "1000"},
+		{"auto", "header-5", withAccount, "header-5"},
+		{"auto", "", withAccount, "payload-7"},
+		{"auto", "", `{"request":{}}`, "1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+" "+tt.header+" "+tt.value, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"CLIENT_ID_SOURCE": tt.source}))
+			msg := sourceMessage(tt.value, 0)
+			if tt.header != "" {
+				msg.Headers = []kafkalib.Header{{Key: "client_id", Value: []byte(tt.header)}}
+			}
+			if got := s.resolveClientID(msg); got != tt.want {
+				t.Errorf("resolveClientID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/kafka"
+	"client-message-transformer/internal/logger"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// sourceConsumer is the subset of the Kafka consumer the service uses
+type sourceConsumer interface {
+	lagSource
+	ReadMessage(timeout time.Duration) (*kafkalib.Message, error)
+	SubscribeTopics(topics []string, rebalanceCb kafkalib.RebalanceCb) error
+	CommitOffsets(offsets []kafkalib.TopicPartition) ([]kafkalib.TopicPartition, error)
+	Pause(partitions []kafkalib.TopicPartition) error
+	Resume(partitions []kafkalib.TopicPartition) error
+	Seek(partition kafkalib.TopicPartition, ignoredTimeoutMs int) error
+	Close() error
+}
+
+// destinationProducer is the subset of the Kafka producer the service uses
+type destinationProducer interface {
+	Produce(msg *kafkalib.Message, deliveryChan chan kafkalib.Event) error
+	Events() chan kafkalib.Event
+	Flush(timeoutMs int) int
+	Len() int
+	Close()
+}
+
+// connectConsumer creates the source consumer and waits for its brokers,
+// recreating it with a reloaded password after retryable authentication failures
+func connectConsumer(cfg *config.Config, authRetry *kafka.AuthRetry, log *logger.Logger, kafkaLog *logger.Logger) (*kafkalib.Consumer, error) {
+	var consumer *kafkalib.Consumer
+	err := authRetry.Connect("Source", kafkaLog, func() error {
+		password, err := cfg.SourcePassword()
+		if err != nil {
+			return err
+		}
+		consumerCfg := &kafka.ClientConfig{
+			Brokers:                cfg.SourceBrokers,
+			ConsumerGroup:          cfg.ConsumerGroup,
+			Topic:                  strings.Join(cfg.SourceTopics, ","),
+			SASLEnabled:            cfg.SourceSASLEnabled,
+			SASLMechanism:          cfg.SourceSASLMechanism,
+			SASLUsername:           cfg.SourceSASLUsername,
+			SASLPassword:           password,
+			SecurityProtocol:       cfg.SourceSecurityProtocol,
+			SSLCALocation:          cfg.SourceSSLCALocation,
+			SSLCertificateLocation: cfg.SourceSSLCertificateLocation,
+			SSLKeyLocation:         cfg.SourceSSLKeyLocation,
+			MaxPollIntervalMs:      cfg.MaxPollIntervalMs,
+			Logger:                 kafkaLog,
+		}
+		client, err := kafka.NewConsumer(consumerCfg)
+		if err != nil {
+			return fmt.Errorf("failed to create consumer: %w", err)
+		}
+
+		log.Info(fmt.Sprintf("⏳ Waiting up to %v for source brokers to be ready...", cfg.BrokerReadyTimeout))
+		if err := kafka.WaitForBrokers(client, cfg.BrokerReadyTimeout, kafkaLog); err != nil {
+			client.Close()
+			return fmt.Errorf("source brokers not ready: %w", err)
+		}
+		consumer = client
+		return nil
+	})
+	return consumer, err
+}
+
+// connectProducer creates a destination producer and waits for its brokers,
+// recreating it with a reloaded password after retryable authentication failures
+func connectProducer(cfg *config.Config, authRetry *kafka.AuthRetry, log *logger.Logger, kafkaLog *logger.Logger) (*kafkalib.Producer, error) {
+	var producer *kafkalib.Producer
+	err := authRetry.Connect("Destination", kafkaLog, func() error {
+		password, err := cfg.DestinationPassword()
+		if err != nil {
+			return err
+		}
+		producerCfg := &kafka.ClientConfig{
+			Brokers:                cfg.DestinationBrokers,
+			SASLEnabled:            cfg.DestinationSASLEnabled,
+			SASLMechanism:          cfg.DestinationSASLMechanism,
+			SASLUsername:           cfg.DestinationSASLUsername,
+			SASLPassword:           password,
+			SecurityProtocol:       cfg.DestinationSecurityProtocol,
+			SSLCALocation:          cfg.DestinationSSLCALocation,
+			SSLCertificateLocation: cfg.DestinationSSLCertificateLocation,
+			SSLKeyLocation:         cfg.DestinationSSLKeyLocation,
+			RequestTimeoutMs:       cfg.RequestTimeoutMs,
+			DeliveryTimeoutMs:      cfg.DeliveryTimeoutMs,
+			Compression:            cfg.DestinationCompression,
+			Logger:                 kafkaLog,
+		}
+		client, err := kafka.NewProducer(producerCfg)
+		if err != nil {
+			return fmt.Errorf("failed to create producer: %w", err)
+		}
+
+		log.Info(fmt.Sprintf("⏳ Waiting up to %v for destination brokers to be ready...", cfg.BrokerReadyTimeout))
+		if err := kafka.WaitForBrokers(client, cfg.BrokerReadyTimeout, kafkaLog); err != nil {
+			client.Close()
+			return fmt.Errorf("destination brokers not ready: %w", err)
+		}
+		producer = client
+		return nil
+	})
+	return producer, err
+}
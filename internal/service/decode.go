@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// decodeSource returns a copy of the message with its value decoded from
+// SOURCE_FORMAT into JSON, or nil when decoding failed. Tombstones pass
+// through unchanged.
+func (s *TransformerService) decodeSource(ctx context.Context, kafkaMsg *kafkalib.Message) *kafkalib.Message {
+	if kafkaMsg.Value == nil {
+		return kafkaMsg
+	}
+
+	value, err := s.decoder.Decode(ctx, kafkaMsg.Value)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("❌ Failed to decode %s message (topic: %s, partition: %d, offset: %v): %v",
+			s.config.SourceFormat, *kafkaMsg.TopicPartition.Topic, kafkaMsg.TopicPartition.Partition, kafkaMsg.TopicPartition.Offset, err))
+		clientID := s.resolveClientID(kafkaMsg)
+		s.metrics.IncrementReceivedFor(clientID)
+		s.metrics.IncrementFailedFor(clientID)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStageDecode, err)
+		return nil
+	}
+
+	msg := *kafkaMsg
+	msg.Value = value
+	return &msg
+}
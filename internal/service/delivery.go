@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// isRetryableDelivery reports whether a delivery error is a transient
+// partition-leadership change worth retrying rather than a permanent failure
+func isRetryableDelivery(err error) bool {
+	kafkaErr, ok := err.(kafkalib.Error)
+	if !ok {
+		return false
+	}
+	switch kafkaErr.Code() {
+	case kafkalib.ErrLeaderNotAvailable, kafkalib.ErrNotLeaderForPartition:
+		return true
+	}
+	return false
+}
+
+// pendingDelivery rides along as a produced message's Opaque so the delivery
+// handler can retry or report it
+type pendingDelivery struct {
+	producer  destinationProducer
+	message   *kafkalib.Message
+	attempt   int
+	onFailure func(error)   // Called once delivery has failed for good, may be nil
+	ticket    *offsetTicket // Source message held in flight until the report, may be nil
+}
+
+// produce enqueues a message without waiting for its delivery; the outcome
+// is handled by handleDeliveries. The value is copied because retries
+// re-produce the message after the caller's buffers have been reused. The
+// source message in ctx stays uncommittable until the delivery report.
+func (s *TransformerService) produce(ctx context.Context, producer destinationProducer, message *kafkalib.Message, onFailure func(error)) error {
+	if message.Value != nil {
+		message.Value = append([]byte(nil), message.Value...)
+	}
+	pending := &pendingDelivery{
+		producer:  producer,
+		message:   message,
+		onFailure: onFailure,
+		ticket:    offsetTicketFrom(ctx),
+	}
+	message.Opaque = pending
+
+	if pending.ticket != nil {
+		pending.ticket.hold()
+	}
+	if err := producer.Produce(message, nil); err != nil {
+		pending.done()
+		return err
+	}
+	return nil
+}
+
+// done releases the source message once delivery has finished either way
+func (pending *pendingDelivery) done() {
+	if pending.ticket != nil {
+		pending.ticket.release()
+	}
+}
+
+// handleDeliveries drains a producer's delivery reports until the producer is
+// closed, retrying leader-unavailable failures up to PRODUCE_LEADER_RETRIES
+// times and counting the rest as failed
+func (s *TransformerService) handleDeliveries(producer destinationProducer) {
+	defer s.deliveryWG.Done()
+
+	for event := range producer.Events() {
+		switch ev := event.(type) {
+		case *kafkalib.Message:
+			pending, _ := ev.Opaque.(*pendingDelivery)
+			err := ev.TopicPartition.Error
+			if err == nil {
+				if pending != nil {
+					pending.done()
+				}
+				continue
+			}
+			if pending != nil && isRetryableDelivery(err) && pending.attempt < s.config.ProduceLeaderRetries {
+				s.retryDelivery(pending, err)
+				continue
+			}
+			s.deliveryFailed(pending, *ev.TopicPartition.Topic, err)
+
+		case kafkalib.Error:
+			s.logger.Warn(fmt.Sprintf("Producer error: %v", ev))
+		}
+	}
+}
+
+// maxDeliveryBackoff caps the delay before a delivery retry, however many
+// PRODUCE_LEADER_RETRIES are configured
+const maxDeliveryBackoff = 30 * time.Second
+
+// deliveryRetries tracks the retries waiting out their backoff so shutdown
+// can drain them before the producers close
+type deliveryRetries struct {
+	mu      sync.Mutex
+	pending map[*pendingDelivery]scheduledRetry
+	changed chan struct{} // Signalled when a retry is sent
+	stopped bool          // Set once drained; later retries fail at once
+}
+
+// scheduledRetry is a retry waiting for its timer
+type scheduledRetry struct {
+	timer *time.Timer
+	cause error
+}
+
+// deliveryBackoff returns the delay before a retry: ProduceRetryBackoff
+// doubled for each earlier attempt, capped at maxDeliveryBackoff
+func (s *TransformerService) deliveryBackoff(attempt int) time.Duration {
+	backoff := s.config.ProduceRetryBackoff
+	for i := 0; i < attempt && backoff < maxDeliveryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDeliveryBackoff {
+		backoff = maxDeliveryBackoff
+	}
+	return backoff
+}
+
+// retryDelivery re-produces a message after an exponential backoff
+func (s *TransformerService) retryDelivery(pending *pendingDelivery, cause error) {
+	backoff := s.deliveryBackoff(pending.attempt)
+	pending.attempt++
+	topic := *pending.message.TopicPartition.Topic
+
+	r := &s.retries
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		s.deliveryFailed(pending, topic, fmt.Errorf("%w (not retried, shutting down)", cause))
+		return
+	}
+	defer r.mu.Unlock()
+	if r.pending == nil {
+		r.pending = make(map[*pendingDelivery]scheduledRetry)
+		r.changed = make(chan struct{}, 1)
+	}
+
+	s.logger.Warn(fmt.Sprintf("🔁 Delivery to %s failed (%v), retrying in %v (attempt %d/%d)",
+		topic, cause, backoff, pending.attempt, s.config.ProduceLeaderRetries))
+	r.pending[pending] = scheduledRetry{cause: cause, timer: time.AfterFunc(backoff, func() {
+		// Produce under the lock so a drained retry is never sent to a closed producer
+		r.mu.Lock()
+		if _, ok := r.pending[pending]; !ok {
+			r.mu.Unlock()
+			return
+		}
+		delete(r.pending, pending)
+		err := pending.producer.Produce(pending.message, nil)
+		select {
+		case r.changed <- struct{}{}:
+		default:
+		}
+		r.mu.Unlock()
+
+		if err != nil {
+			s.deliveryFailed(pending, topic, fmt.Errorf("%w (retry failed: %v)", cause, err))
+		}
+	})}
+}
+
+// drainRetries waits until the scheduled retries are sent or the deadline
+// passes, failing those still waiting. Retries scheduled afterwards fail at
+// once, as the producers are about to close.
+func (s *TransformerService) drainRetries(deadline time.Time) {
+	r := &s.retries
+	for {
+		r.mu.Lock()
+		if len(r.pending) == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-time.After(time.Until(deadline)):
+		}
+	}
+
+	r.stopped = true
+	abandoned := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	for pending, retry := range abandoned {
+		retry.timer.Stop()
+		s.deliveryFailed(pending, *pending.message.TopicPartition.Topic, fmt.Errorf("%w (retry still pending at shutdown)", retry.cause))
+	}
+}
+
+// producedClientID returns the client_id header set on a produced message
+func producedClientID(message *kafkalib.Message) string {
+	for _, header := range message.Headers {
+		if header.Key == "client_id" {
+			return string(header.Value)
+		}
+	}
+	return defaultClientID
+}
+
+// deliveryFailed logs and counts a message that could not be delivered
+func (s *TransformerService) deliveryFailed(pending *pendingDelivery, topic string, err error) {
+	s.logger.Error(fmt.Sprintf("❌ Delivery to %s failed: %v", topic, err))
+	if pending == nil {
+		s.metrics.IncrementFailed()
+		return
+	}
+	s.metrics.IncrementFailedFor(producedClientID(pending.message))
+	// Dead-lettering holds the source message again before it is released
+	if pending.onFailure != nil {
+		pending.onFailure(fmt.Errorf("delivery failed: %w", err))
+	}
+	pending.done()
+}
+
+// flushProducers waits for queued messages to be delivered, bounded by the
+// shutdown deadline
+func (s *TransformerService) flushProducers(deadline time.Time) {
+	for _, producer := range []destinationProducer{s.producer, s.protoProducer} {
+		timeoutMs := int(time.Until(deadline).Milliseconds())
+		if timeoutMs < 0 {
+			timeoutMs = 0
+		}
+		if remaining := producer.Flush(timeoutMs); remaining > 0 {
+			s.logger.Warn(fmt.Sprintf("⚠️  %d messages undelivered at shutdown", remaining))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestIsRetryableDelivery(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"leader not available", kafkalib.NewError(kafkalib.ErrLeaderNotAvailable, "no leader", false), true},
+		{"not leader for partition", kafkalib.NewError(kafkalib.ErrNotLeaderForPartition, "not leader", false), true},
+		{"message too large", kafkalib.NewError(kafkalib.ErrMsgSizeTooLarge, "too large", false), false},
+		{"not a kafka error", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableDelivery(tt.err); got != tt.want {
+				t.Errorf("isRetryableDelivery(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderRetries(t *testing.T) {
+	leaderDown := kafkalib.NewError(kafkalib.ErrLeaderNotAvailable, "no leader", false)
+	tests := []struct {
+		name         string
+		retries      int
+		deliveries   []error
+		wantProduced int
+		wantFailed   int64
+	}{
+		{"delivered first time", 3, nil, 1, 0},
+		{"leader recovers", 3, []error{leaderDown}, 2, 0},
+		{"leader recovers on the last retry", 2, []error{leaderDown, leaderDown}, 3, 0},
+		{"retries exhausted", 1, []error{leaderDown, leaderDown}, 2, 1},
+		{"permanent failure", 3, []error{kafkalib.NewError(kafkalib.ErrMsgSizeTooLarge, "too large", false)}, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"PRODUCE_LEADER_RETRIES": strconv.Itoa(tt.retries),
+				"PRODUCE_RETRY_BACKOFF":  "1ms",
+			}))
+			s.sink.deliveries = tt.deliveries
+			topic := "client.traffic"
+			s.source.assignment = []kafkalib.TopicPartition{{Topic: &topic, Partition: 0}}
+			s.start(t)
+			s.source.send(sourceMessage(sampleCapture, 0))
+
+			// The source offset is committed once delivery has finished either way
+			eventually(t, 2*time.Second, func() bool {
+				for _, call := range s.source.commitCalls() {
+					for _, tp := range call {
+						if tp.Offset == 1 {
+							return true
+						}
+					}
+				}
+				return false
+			}, "offset 0 was never committed")
+
+			if got := len(s.sink.messages("akto.api.logs")); got != tt.wantProduced {
+				t.Errorf("produced %d times, want %d", got, tt.wantProduced)
+			}
+			if got := s.metrics.GetSnapshot()["failed"].(int64); got != tt.wantFailed {
+				t.Errorf("failed = %d, want %d", got, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		{500 * time.Millisecond, 0, 500 * time.Millisecond},
+		{500 * time.Millisecond, 1, time.Second},
+		{500 * time.Millisecond, 3, 4 * time.Second},
+		{500 * time.Millisecond, 10, maxDeliveryBackoff},
+		{500 * time.Millisecond, 1000, maxDeliveryBackoff}, // Would overflow unchecked
+		{time.Minute, 0, maxDeliveryBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base.String()+"/"+strconv.Itoa(tt.attempt), func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"PRODUCE_RETRY_BACKOFF": tt.base.String()}))
+			if got := s.deliveryBackoff(tt.attempt); got != tt.want {
+				t.Errorf("deliveryBackoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrainRetriesAtShutdown(t *testing.T) {
+	leaderDown := kafkalib.NewError(kafkalib.ErrLeaderNotAvailable, "no leader", false)
+	tests := []struct {
+		name         string
+		backoff      string
+		stopTimeout  time.Duration
+		wantProduced int
+		wantFailed   int64
+	}{
+		{"retry sent before the deadline", "200ms", 5 * time.Second, 2, 0},
+		{"retry still pending at the deadline", "1m", 100 * time.Millisecond, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"PRODUCE_LEADER_RETRIES": "3",
+				"PRODUCE_RETRY_BACKOFF":  tt.backoff,
+			}))
+			s.sink.deliveries = []error{leaderDown}
+			topic := "client.traffic"
+			s.source.assignment = []kafkalib.TopicPartition{{Topic: &topic, Partition: 0}}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := s.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			s.source.send(sourceMessage(sampleCapture, 0))
+			eventually(t, 2*time.Second, func() bool {
+				s.retries.mu.Lock()
+				defer s.retries.mu.Unlock()
+				return len(s.retries.pending) == 1
+			}, "no delivery retry was scheduled")
+
+			// Stop closes the fake producer, so a retry sent afterwards would panic
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), tt.stopTimeout)
+			defer stopCancel()
+			s.Stop(stopCtx, "test finished")
+
+			if got := len(s.sink.messages("akto.api.logs")); got != tt.wantProduced {
+				t.Errorf("produced %d times, want %d", got, tt.wantProduced)
+			}
+			if got := s.metrics.GetSnapshot()["failed"].(int64); got != tt.wantFailed {
+				t.Errorf("failed = %d, want %d", got, tt.wantFailed)
+			}
+			committed := false
+			for _, call := range s.source.commitCalls() {
+				for _, tp := range call {
+					committed = committed || tp.Offset == 1
+				}
+			}
+			if !committed {
+				t.Error("offset 0 was not committed at shutdown")
+			}
+		})
+	}
+}
+
+func BenchmarkHandleMessage(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"flat", nil},
+		{"proto", map[string]string{"OUTPUT_FORMAT": "proto"}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			s := newTestService(b, testConfig(b, bm.env))
+			s.start(b)
+			msg := sourceMessage(sampleCapture, 0)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				msg.TopicPartition.Offset = kafkalib.Offset(i)
+				s.handleMessage(context.Background(), msg)
+				if i%1000 == 999 {
+					s.sink.reset()
+					s.proto.reset()
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Stages recorded in the dlq_stage header of dead-lettered messages
+const (
+	dlqStageDecode    = "decode"
+	dlqStageTransform = "transform"
+	dlqStageMarshal   = "marshal"
+	dlqStagePublish   = "publish"
+	dlqStageOutput    = "output"
+	dlqStageDeadline  = "deadline"
+)
+
+// deadLetter copies a failed message's original bytes to DLQ_TOPIC with the
+// error, the failing stage and the failure time as headers. Without a DLQ the
+// message stays dropped and counted as failed.
+func (s *TransformerService) deadLetter(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, stage string, cause error) {
+	if s.config.DLQTopic == "" {
+		return
+	}
+
+	err := s.forwardRaw(ctx, s.config.DLQTopic, clientID, kafkaMsg,
+		kafkalib.Header{Key: "dlq_error", Value: []byte(cause.Error())},
+		kafkalib.Header{Key: "dlq_stage", Value: []byte(stage)},
+		kafkalib.Header{Key: "dlq_timestamp", Value: []byte(time.Now().Format(time.RFC3339))},
+	)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to dead-letter message: %v", err))
+		return
+	}
+	s.logger.Debug(fmt.Sprintf("☠️  Dead-lettered message to %s (stage: %s)", s.config.DLQTopic, stage))
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestDeadLetter(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		value     string
+		failTopic string // Destination topic whose produce fails
+		wantStage string // "" when nothing is dead-lettered
+		wantError string
+	}{
+		{"transform failure", nil, `{"request":`, "", dlqStageTransform, "unexpected end of JSON input"},
+		{"proto transform failure", map[string]string{"OUTPUT_FORMAT": "proto"}, `{"request":`, "", dlqStageTransform, "unexpected end of JSON input"},
+		{"shape failure", nil, `{"info":{}}`, "", dlqStageTransform, "missing"},
+		{"publish failure", nil, sampleCapture, "akto.api.logs", dlqStagePublish, "queue full"},
+		{"no DLQ topic", map[string]string{"DLQ_TOPIC": ""}, `{"request":`, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"DLQ_TOPIC": "akto.api.dlq", "MAX_RETRIES": "0"}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			s := newTestService(t, testConfig(t, env))
+			if tt.failTopic != "" {
+				s.sink.produceErrs = map[string]error{tt.failTopic: kafkalib.NewError(kafkalib.ErrQueueFull, "queue full", false)}
+			}
+			s.handleMessage(context.Background(), sourceMessage(tt.value, 5))
+
+			dead := s.sink.messages("akto.api.dlq")
+			if tt.wantStage == "" {
+				if len(dead) != 0 {
+					t.Errorf("dead-lettered %d messages without a DLQ topic", len(dead))
+				}
+				return
+			}
+			if len(dead) != 1 {
+				t.Fatalf("dead-lettered %d messages, want 1", len(dead))
+			}
+			if string(dead[0].Value) != tt.value {
+				t.Errorf("dead-lettered value = %s, want the original bytes %s", dead[0].Value, tt.value)
+			}
+			if stage := headerValue(dead[0], "dlq_stage"); stage != tt.wantStage {
+				t.Errorf("dlq_stage = %q, want %q", stage, tt.wantStage)
+			}
+			if cause := headerValue(dead[0], "dlq_error"); !strings.Contains(cause, tt.wantError) {
+				t.Errorf("dlq_error = %q, want it to mention %q", cause, tt.wantError)
+			}
+			if _, err := time.Parse(time.RFC3339, headerValue(dead[0], "dlq_timestamp")); err != nil {
+				t.Errorf("dlq_timestamp is not RFC3339: %v", err)
+			}
+			if got := s.metrics.GetSnapshot()["failed"].(int64); got != 1 {
+				t.Errorf("failed = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// downstreamProbeTimeout bounds a single downstream health request
+const downstreamProbeTimeout = 5 * time.Second
+
+// probeDownstream reports whether the downstream health endpoint answers 2xx
+func probeDownstream(ctx context.Context, client *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, downstreamProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// monitorDownstream polls DOWNSTREAM_HEALTH_URL and pauses consumption while
+// the downstream is unhealthy, resuming once it recovers
+func (s *TransformerService) monitorDownstream(ctx context.Context) {
+	defer s.wg.Done()
+
+	client := &http.Client{}
+	ticker := time.NewTicker(s.config.DownstreamHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := probeDownstream(ctx, client, s.config.DownstreamHealthURL)
+			healthy := err == nil
+			wasHealthy := s.downstreamHealthy.Swap(healthy)
+
+			switch {
+			case !healthy:
+				if wasHealthy {
+					s.logger.Warn(fmt.Sprintf("⏸️  Downstream unhealthy, pausing consumption: %v", err))
+				}
+				// Re-pause every tick so partitions assigned by a rebalance stay paused
+				s.setConsumptionPaused(true)
+			case !wasHealthy:
+				s.logger.Info("▶️  Downstream healthy again, resuming consumption")
+				s.setConsumptionPaused(false)
+			}
+		}
+	}
+}
+
+// setConsumptionPaused pauses or resumes every assigned partition
+func (s *TransformerService) setConsumptionPaused(paused bool) {
+	assignment, err := s.consumer.Assignment()
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to read assignment: %v", err))
+		return
+	}
+	if len(assignment) == 0 {
+		return
+	}
+
+	if paused {
+		err = s.consumer.Pause(assignment)
+	} else {
+		err = s.consumer.Resume(assignment)
+	}
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to change partition pause state: %v", err))
+	}
+}
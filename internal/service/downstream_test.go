@@ -0,0 +1,83 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fakeHealthEndpoint answers 200 while healthy is set and 503 otherwise
+func fakeHealthEndpoint(t *testing.T, healthy *atomic.Bool) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestDownstreamHealthPausesConsumption(t *testing.T) {
+	var healthy atomic.Bool
+	s := newTestService(t, testConfig(t, map[string]string{
+		"DOWNSTREAM_HEALTH_URL":      fakeHealthEndpoint(t, &healthy),
+		"DOWNSTREAM_HEALTH_INTERVAL": "10ms",
+	}))
+	topic := "client.traffic"
+	s.source.assignment = []kafkalib.TopicPartition{{Topic: &topic, Partition: 0}}
+	s.start(t)
+
+	eventually(t, 5*time.Second, func() bool {
+		paused, _ := s.source.pauseCalls()
+		return len(paused) > 0
+	}, "consumption was not paused while the downstream was unhealthy")
+	if _, resumed := s.source.pauseCalls(); len(resumed) != 0 {
+		t.Errorf("resumed %v while the downstream was unhealthy", resumed)
+	}
+
+	healthy.Store(true)
+	eventually(t, 5*time.Second, func() bool {
+		_, resumed := s.source.pauseCalls()
+		return len(resumed) > 0
+	}, "consumption did not resume once the downstream recovered")
+}
+
+func TestResumePoolWhileDownstreamUnhealthy(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthy     bool
+		wantResumed int
+	}{
+		{"healthy downstream resumes", true, 1},
+		{"unhealthy downstream stays paused", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"SOURCE_TOPIC":            "client.traffic,client.bulk",
+				"SOURCE_TOPIC_WEIGHTS":    "client.traffic=1,client.bulk=1",
+				"MAX_CONCURRENT_MESSAGES": "2",
+			}))
+			s.downstreamHealthy.Store(tt.healthy)
+			pool := s.topicPools["client.traffic"]
+
+			// Saturate the pool so the next message pauses its partition
+			if !s.admit(pool, sourceMessage(sampleCapture, 0)) {
+				t.Fatal("first message was not admitted")
+			}
+			if s.admit(pool, sourceMessage(sampleCapture, 1)) {
+				t.Fatal("message admitted to a saturated pool")
+			}
+			s.release(pool)
+
+			if _, resumed := s.source.pauseCalls(); len(resumed) != tt.wantResumed {
+				t.Errorf("resumed %d times after a slot freed, want %d", len(resumed), tt.wantResumed)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"hash/fnv"
+)
+
+// partitionID identifies a source partition
+type partitionID struct {
+	topic     string
+	partition int32
+}
+
+// duplicateDetector flags a message whose content hash equals the previous
+// message on the same partition, the usual signature of an upstream producer
+// retry. It is not safe for concurrent use; the read loop owns it.
+type duplicateDetector struct {
+	last map[partitionID]uint64
+}
+
+// newDuplicateDetector creates an empty detector
+func newDuplicateDetector() *duplicateDetector {
+	return &duplicateDetector{last: make(map[partitionID]uint64)}
+}
+
+// observe records a message and reports whether it repeats its predecessor
+func (d *duplicateDetector) observe(topic string, partition int32, value []byte) bool {
+	hash := fnv.New64a()
+	hash.Write(value)
+	sum := hash.Sum64()
+
+	id := partitionID{topic: topic, partition: partition}
+	previous, seen := d.last[id]
+	d.last[id] = sum
+	return seen && previous == sum
+}
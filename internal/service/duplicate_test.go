@@ -0,0 +1,50 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuplicateDetector(t *testing.T) {
+	type delivery struct {
+		partition int32
+		value     string
+		want      bool
+	}
+	tests := []struct {
+		name       string
+		deliveries []delivery
+	}{
+		{"consecutive identical", []delivery{{0, "a", false}, {0, "a", true}, {0, "a", true}}},
+		{"distinct", []delivery{{0, "a", false}, {0, "b", false}, {0, "c", false}}},
+		{"repeat after a different message", []delivery{{0, "a", false}, {0, "b", false}, {0, "a", false}}},
+		{"partitions tracked separately", []delivery{{0, "a", false}, {1, "a", false}, {0, "a", true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := newDuplicateDetector()
+			for i, d := range tt.deliveries {
+				if got := detector.observe("client.traffic", d.partition, []byte(d.value)); got != d.want {
+					t.Errorf("delivery %d (%d/%q): duplicate = %v, want %v", i, d.partition, d.value, got, d.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectDuplicates(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"DETECT_DUPLICATES": "true"}))
+	other := strings.Replace(sampleCapture, "/users", "/orders", 1)
+	for i, value := range []string{sampleCapture, sampleCapture, other, sampleCapture} {
+		s.source.send(sourceMessage(value, int64(i)))
+	}
+	s.start(t)
+
+	eventually(t, 5*time.Second, func() bool {
+		return len(s.sink.messages("akto.api.logs")) == 4
+	}, "likely duplicates were not all published")
+	if got := s.metrics.GetSnapshot()["likely_duplicate"].(int64); got != 1 {
+		t.Errorf("likely_duplicate = %d, want 1", got)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// envelope wraps a transformed record for downstreams that expect
+// { "schema": ..., "payload": {...}, "meta": {...} }
+type envelope struct {
+	Schema  string                 `json:"schema"`
+	Payload map[string]interface{} `json:"payload"`
+	Meta    envelopeMeta           `json:"meta"`
+}
+
+// envelopeMeta describes where and when an enveloped record was produced
+type envelopeMeta struct {
+	ClientID    string `json:"clientId"`
+	SourceTopic string `json:"sourceTopic"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// wrapEnvelope wraps a record in the configured envelope
+func (s *TransformerService) wrapEnvelope(clientID string, kafkaMsg *kafkalib.Message, record map[string]interface{}) envelope {
+	return envelope{
+		Schema:  s.config.EnvelopeSchema,
+		Payload: record,
+		Meta: envelopeMeta{
+			ClientID:    clientID,
+			SourceTopic: *kafkaMsg.TopicPartition.Topic,
+			Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		envelope bool
+	}{
+		{"enveloped", map[string]string{"ENVELOPE": "true", "ENVELOPE_SCHEMA": "akto.traffic.v1"}, true},
+		{"plain record", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, tt.env))
+			s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+			produced := s.sink.messages("akto.api.logs")
+			if len(produced) != 1 {
+				t.Fatalf("produced %d messages, want 1", len(produced))
+			}
+			var value map[string]interface{}
+			if err := json.Unmarshal(produced[0].Value, &value); err != nil {
+				t.Fatalf("decode value: %v", err)
+			}
+
+			if !tt.envelope {
+				if value["path"] != "/users?id=1" || value["payload"] != nil || value["meta"] != nil {
+					t.Errorf("value = %v, want the bare flat record", value)
+				}
+				return
+			}
+
+			if len(value) != 3 || value["schema"] != "akto.traffic.v1" {
+				t.Errorf("envelope = %v, want schema, payload and meta only", value)
+			}
+			payload, _ := value["payload"].(map[string]interface{})
+			if payload["path"] != "/users?id=1" || payload["method"] != "GET" {
+				t.Errorf("payload = %v, want the flat record", payload)
+			}
+			meta, _ := value["meta"].(map[string]interface{})
+			if meta["clientId"] != "1000" || meta["sourceTopic"] != "client.traffic" {
+				t.Errorf("meta = %v, want client 1000 from client.traffic", meta)
+			}
+			if timestamp, _ := meta["timestamp"].(string); !validRFC3339(timestamp) {
+				t.Errorf("meta.timestamp = %q, want RFC 3339", meta["timestamp"])
+			}
+		})
+	}
+}
+
+// validRFC3339 reports whether a value is an RFC 3339 timestamp
+func validRFC3339(value string) bool {
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/logger"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// fakeConsumer serves queued messages and errors and records the calls the
+// service makes
+type fakeConsumer struct {
+	mu         sync.Mutex
+	messages   chan *kafkalib.Message
+	errs       []error // Returned by ReadMessage ahead of queued messages
+	assignment []kafkalib.TopicPartition
+	subscribed []string
+	commits    [][]kafkalib.TopicPartition
+	paused     [][]kafkalib.TopicPartition
+	resumed    [][]kafkalib.TopicPartition
+	seeks      []kafkalib.TopicPartition
+	closed     bool
+
+	// Offsets answered to lag queries
+	committed  map[partitionKey]kafkalib.Offset
+	watermarks map[partitionKey][2]int64
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{
+		messages:   make(chan *kafkalib.Message, 1000),
+		committed:  make(map[partitionKey]kafkalib.Offset),
+		watermarks: make(map[partitionKey][2]int64),
+	}
+}
+
+// send queues a source message for ReadMessage
+func (f *fakeConsumer) send(msg *kafkalib.Message) {
+	f.messages <- msg
+}
+
+func (f *fakeConsumer) ReadMessage(timeout time.Duration) (*kafkalib.Message, error) {
+	f.mu.Lock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		f.mu.Unlock()
+		return nil, err
+	}
+	f.mu.Unlock()
+
+	select {
+	case msg := <-f.messages:
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, kafkalib.NewError(kafkalib.ErrTimedOut, "timed out", false)
+	}
+}
+
+func (f *fakeConsumer) SubscribeTopics(topics []string, rebalanceCb kafkalib.RebalanceCb) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribed = append(f.subscribed, topics...)
+	return nil
+}
+
+func (f *fakeConsumer) CommitOffsets(offsets []kafkalib.TopicPartition) ([]kafkalib.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commits = append(f.commits, offsets)
+	return offsets, nil
+}
+
+func (f *fakeConsumer) Pause(partitions []kafkalib.TopicPartition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = append(f.paused, partitions)
+	return nil
+}
+
+func (f *fakeConsumer) Resume(partitions []kafkalib.TopicPartition) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resumed = append(f.resumed, partitions)
+	return nil
+}
+
+func (f *fakeConsumer) Seek(partition kafkalib.TopicPartition, ignoredTimeoutMs int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seeks = append(f.seeks, partition)
+	return nil
+}
+
+func (f *fakeConsumer) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeConsumer) Assignment() ([]kafkalib.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]kafkalib.TopicPartition(nil), f.assignment...), nil
+}
+
+func (f *fakeConsumer) Committed(partitions []kafkalib.TopicPartition, timeoutMs int) ([]kafkalib.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	committed := make([]kafkalib.TopicPartition, len(partitions))
+	for i, tp := range partitions {
+		offset, ok := f.committed[partitionKey{topic: *tp.Topic, partition: tp.Partition}]
+		if !ok {
+			offset = kafkalib.OffsetInvalid
+		}
+		committed[i] = kafkalib.TopicPartition{Topic: tp.Topic, Partition: tp.Partition, Offset: offset}
+	}
+	return committed, nil
+}
+
+func (f *fakeConsumer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	watermarks := f.watermarks[partitionKey{topic: topic, partition: partition}]
+	return watermarks[0], watermarks[1], nil
+}
+
+// commitCalls returns the offsets passed to each CommitOffsets call
+func (f *fakeConsumer) commitCalls() [][]kafkalib.TopicPartition {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]kafkalib.TopicPartition(nil), f.commits...)
+}
+
+// subscribedTopics returns the topics passed to SubscribeTopics
+func (f *fakeConsumer) subscribedTopics() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.subscribed...)
+}
+
+// pauseCalls returns the partitions passed to each Pause and Resume call
+func (f *fakeConsumer) pauseCalls() (paused, resumed [][]kafkalib.TopicPartition) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]kafkalib.TopicPartition(nil), f.paused...), append([][]kafkalib.TopicPartition(nil), f.resumed...)
+}
+
+// fakeProducer records produced messages and reports their delivery on its
+// events channel
+type fakeProducer struct {
+	mu          sync.Mutex
+	events      chan kafkalib.Event
+	produced    []*kafkalib.Message
+	produceErrs map[string]error // Returned by Produce for messages to a topic
+	failures    int              // Produce calls failed before the rest succeed
+	attempts    int              // Produce calls made
+	deliveries  []error          // Delivery outcomes consumed in order; success once empty
+	closeOnce   sync.Once
+}
+
+func newFakeProducer() *fakeProducer {
+	return &fakeProducer{events: make(chan kafkalib.Event, 1000)}
+}
+
+func (f *fakeProducer) Produce(msg *kafkalib.Message, deliveryChan chan kafkalib.Event) error {
+	f.mu.Lock()
+	f.attempts++
+	if f.failures > 0 {
+		f.failures--
+		f.mu.Unlock()
+		return kafkalib.NewError(kafkalib.ErrTransport, "broker unreachable", false)
+	}
+	if err := f.produceErrs[*msg.TopicPartition.Topic]; err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	produced := *msg
+	f.produced = append(f.produced, &produced)
+	var deliveryErr error
+	if len(f.deliveries) > 0 {
+		deliveryErr = f.deliveries[0]
+		f.deliveries = f.deliveries[1:]
+	}
+	f.mu.Unlock()
+
+	report := *msg
+	report.TopicPartition.Error = deliveryErr
+	f.events <- &report
+	return nil
+}
+
+func (f *fakeProducer) Events() chan kafkalib.Event {
+	return f.events
+}
+
+func (f *fakeProducer) Flush(timeoutMs int) int {
+	return 0
+}
+
+func (f *fakeProducer) Len() int {
+	return len(f.events)
+}
+
+func (f *fakeProducer) Close() {
+	f.closeOnce.Do(func() { close(f.events) })
+}
+
+// reset forgets the messages produced so far
+func (f *fakeProducer) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.produced = nil
+}
+
+// messages returns the messages produced to topic, in order
+func (f *fakeProducer) messages(topic string) []*kafkalib.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var messages []*kafkalib.Message
+	for _, msg := range f.produced {
+		if *msg.TopicPartition.Topic == topic {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// testConfig loads the configuration defaults with the required variables
+// set, then the overrides
+func testConfig(t testing.TB, overrides map[string]string) *config.Config {
+	t.Helper()
+	env := map[string]string{
+		"CLIENT_ID":           "1000",
+		"SOURCE_BROKERS":      "source:9092",
+		"SOURCE_TOPIC":        "client.traffic",
+		"DESTINATION_BROKERS": "destination:9092",
+		"DESTINATION_TOPIC":   "akto.api.logs",
+		"CONSUMER_GROUP":      "transformer",
+		"LOG_LEVEL":           "ERROR",
+		"COMMIT_INTERVAL":     "20ms",
+		"PROCESSING_TIMEOUT":  "10ms",
+		"HEALTH_PORT":         "0",
+		"METRICS_PORT":        "0",
+	}
+	for key, value := range overrides {
+		env[key] = value
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// testService is a service wired to fake Kafka clients
+type testService struct {
+	*TransformerService
+	source *fakeConsumer
+	sink   *fakeProducer
+	proto  *fakeProducer
+}
+
+// newTestService creates a service for cfg around fake Kafka clients
+func newTestService(t testing.TB, cfg *config.Config) *testService {
+	t.Helper()
+	source, sink, proto := newFakeConsumer(), newFakeProducer(), newFakeProducer()
+	s, err := newService(cfg, logger.NewLogger(cfg.LogLevelService, nil), source, sink, proto)
+	if err != nil {
+		t.Fatalf("newService: %v", err)
+	}
+	return &testService{TransformerService: s, source: source, sink: sink, proto: proto}
+}
+
+// start runs the service until the test ends
+func (s *testService) start(t testing.TB) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer stopCancel()
+		s.Stop(stopCtx, "test finished")
+		cancel()
+	})
+}
+
+// sourceMessage builds a source message at an offset of client.traffic partition 0
+func sourceMessage(value string, offset int64) *kafkalib.Message {
+	topic := "client.traffic"
+	return &kafkalib.Message{
+		TopicPartition: kafkalib.TopicPartition{Topic: &topic, Partition: 0, Offset: kafkalib.Offset(offset)},
+		Value:          []byte(value),
+	}
+}
+
+// sampleCapture is a complete nested client message
+const sampleCapture = `{"request":{"url":"https://api.example.com/users?id=1","method":"GET",` +
+	`"headers":"{\"Content-Type\":\"application/json\",\"Authorization\":\"Bearer secret-token\"}","body":""},` +
+	`"response":{"statusCode":200,"headers":"{\"Content-Type\":\"application/json\"}","body":"{\"id\":1}"},` +
+	`"info":{"ip":"203.0.113.7","dateTime":1700000000,"responseTime":12}}`
+
+// eventually polls cond until it holds or the timeout elapses
+func eventually(t *testing.T, timeout time.Duration, cond func() bool, format string, args ...interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// headerValue returns the value of a produced message's header
+func headerValue(msg *kafkalib.Message, key string) string {
+	for _, header := range msg.Headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
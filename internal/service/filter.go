@@ -0,0 +1,21 @@
+package service
+
+import (
+	"net"
+	"strings"
+)
+
+// isPrivateIP reports whether ip is an RFC1918, unique-local or loopback address
+func isPrivateIP(ip string) bool {
+	// Tolerate "host:port" and comma-separated forwarding chains by using the first entry
+	ip = strings.TrimSpace(strings.Split(ip, ",")[0])
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	return parsed.IsPrivate() || parsed.IsLoopback()
+}
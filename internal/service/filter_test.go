@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.10", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"192.168.1.10:443", true},
+		{"10.0.0.1, 203.0.113.7", true},
+		{"203.0.113.7", false},
+		{"203.0.113.7, 10.0.0.1", false},
+		{"2001:db8::1", false},
+		{"", false},
+		{"not-an-ip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := isPrivateIP(tt.ip); got != tt.want {
+				t.Errorf("isPrivateIP(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropPrivateIPs(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"DROP_PRIVATE_IPS": "true"}))
+
+	tests := []struct {
+		ip        string
+		published int
+	}{
+		{"10.0.0.8", 0},
+		{"203.0.113.7", 1},
+	}
+	for i, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			before := len(s.sink.messages("akto.api.logs"))
+			capture := strings.Replace(sampleCapture, "203.0.113.7", tt.ip, 1)
+			s.handleMessage(context.Background(), sourceMessage(capture, int64(i)))
+
+			if got := len(s.sink.messages("akto.api.logs")) - before; got != tt.published {
+				t.Errorf("published %d messages from %s, want %d", got, tt.ip, tt.published)
+			}
+		})
+	}
+	if got := s.metrics.GetSnapshot()["skipped_private_ip"].(int64); got != 1 {
+		t.Errorf("skipped_private_ip = %d, want 1", got)
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// heapCheckInterval is how often the heap is sampled; ReadMemStats stops the
+// world, so it is kept off the per-message path
+const heapCheckInterval = time.Second
+
+// monitorHeap samples the heap and flags when it exceeds MAX_HEAP_MB
+func (s *TransformerService) monitorHeap(ctx context.Context) {
+	defer s.wg.Done()
+
+	limit := uint64(s.config.MaxHeapMB) << 20
+	ticker := time.NewTicker(heapCheckInterval)
+	defer ticker.Stop()
+
+	var stats runtime.MemStats
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runtime.ReadMemStats(&stats)
+			over := stats.HeapAlloc > limit
+			if over != s.heapOverLimit.Swap(over) {
+				if over {
+					s.logger.Warn(fmt.Sprintf("🐘 Heap %d MB over MAX_HEAP_MB=%d, processing one message at a time", stats.HeapAlloc>>20, s.config.MaxHeapMB))
+				} else {
+					s.logger.Info(fmt.Sprintf("🐘 Heap back to %d MB, restoring full concurrency", stats.HeapAlloc>>20))
+				}
+			}
+		}
+	}
+}
+
+// waitForHeapHeadroom holds new work while the heap is over MAX_HEAP_MB until
+// in-flight messages drain, so memory recovers while consumption continues one
+// message at a time. It returns false if the service stops while waiting.
+func (s *TransformerService) waitForHeapHeadroom(ctx context.Context) bool {
+	for s.heapOverLimit.Load() && s.inFlight() > 0 {
+		select {
+		case <-s.stopChan:
+			return false
+		case <-ctx.Done():
+			return false
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+// inFlight counts messages being processed across the shared and per-topic pools
+func (s *TransformerService) inFlight() int {
+	count := len(s.semaphore)
+	for _, pool := range s.topicPools {
+		count += len(pool.slots)
+	}
+	return count
+}
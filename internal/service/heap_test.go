@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWaitForHeapHeadroom(t *testing.T) {
+	tests := []struct {
+		name      string
+		overLimit bool
+		inFlight  int
+		drainIn   time.Duration // Frees the in-flight slots after this long; 0 never
+		cancel    bool
+		want      bool
+		wantWait  time.Duration // Minimum time spent waiting
+	}{
+		{"under the limit while busy", false, 2, 0, false, true, 0},
+		{"over the limit while idle", true, 0, 0, false, true, 0},
+		{"over the limit waits for in-flight work", true, 2, 50 * time.Millisecond, false, true, 50 * time.Millisecond},
+		{"stopped while waiting", true, 2, 0, true, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"MAX_HEAP_MB": "64"}))
+			s.heapOverLimit.Store(tt.overLimit)
+			for i := 0; i < tt.inFlight; i++ {
+				s.semaphore <- true
+			}
+			if tt.drainIn > 0 {
+				time.AfterFunc(tt.drainIn, func() {
+					for i := 0; i < tt.inFlight; i++ {
+						<-s.semaphore
+					}
+				})
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			start := time.Now()
+			if got := s.waitForHeapHeadroom(ctx); got != tt.want {
+				t.Errorf("waitForHeapHeadroom = %v, want %v", got, tt.want)
+			}
+			if waited := time.Since(start); waited < tt.wantWait {
+				t.Errorf("returned after %v, before in-flight work drained at %v", waited, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestMonitorHeap(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"MAX_HEAP_MB": "1"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	s.wg.Add(1)
+	go s.monitorHeap(ctx)
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+
+	// Hold well over a megabyte of live heap until the monitor notices
+	ballast := make([]byte, 16<<20)
+	for i := range ballast {
+		ballast[i] = byte(i)
+	}
+	eventually(t, 3*heapCheckInterval, s.heapOverLimit.Load, "heap over MAX_HEAP_MB was never flagged")
+	runtime.KeepAlive(ballast)
+}
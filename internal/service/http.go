@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// statusResponse is the JSON body served by /status
+type statusResponse struct {
+	State              string              `json:"state"`
+	InFlight           int                 `json:"in_flight"`
+	MaxConcurrent      int                 `json:"max_concurrent"`
+	ProducerQueue      int                 `json:"producer_queue"`
+	ProtoProducerQueue int                 `json:"proto_producer_queue"`
+	Assignment         []partitionResponse `json:"assignment"`
+	TopicInFlight      map[string]int      `json:"topic_in_flight,omitempty"` // Busy workers of each weighted topic
+}
+
+// partitionResponse describes one assigned source partition
+type partitionResponse struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+}
+
+// startHTTPServer serves the operational endpoints on HEALTH_PORT
+func (s *TransformerService) startHTTPServer() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+
+	// Listen up front so bind errors fail Start instead of a background goroutine
+	addr := fmt.Sprintf(":%d", s.config.HealthPort)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error(fmt.Sprintf("HTTP server error: %v", err))
+		}
+	}()
+
+	s.logger.Info(fmt.Sprintf("🌐 HTTP server listening on %s", addr))
+	return nil
+}
+
+// stopHTTPServer shuts the HTTP server down, releasing its port
+func (s *TransformerService) stopHTTPServer(ctx context.Context) {
+	if s.httpServer == nil {
+		return
+	}
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.logger.Warn(fmt.Sprintf("HTTP server shutdown: %v", err))
+	}
+}
+
+// currentState returns the lifecycle state, reporting a running service as
+// paused while the downstream is unhealthy
+func (s *TransformerService) currentState() string {
+	state := s.state.Load().(string)
+	if state == stateRunning && !s.downstreamHealthy.Load() {
+		return statePaused
+	}
+	return state
+}
+
+// handleHealthz reports live once the processing goroutines are running
+func (s *TransformerService) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	switch s.currentState() {
+	case stateRunning, statePaused:
+		fmt.Fprintln(w, "ok")
+	default:
+		http.Error(w, s.currentState(), http.StatusServiceUnavailable)
+	}
+}
+
+// handleReadyz reports ready once the consumer is subscribed (the producer is
+// connected by New), until consumer errors persist past CONSUMER_ERROR_WINDOW.
+// A service paused for an unhealthy downstream is live but not ready.
+func (s *TransformerService) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if state := s.currentState(); state != stateRunning {
+		http.Error(w, state, http.StatusServiceUnavailable)
+		return
+	}
+	if since := s.consumerErrorsSince.Load(); since != 0 {
+		if failing := time.Since(time.Unix(0, since)); failing > s.config.ConsumerErrorWindow {
+			http.Error(w, fmt.Sprintf("consumer failing for %v", failing.Round(time.Second)), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	fmt.Fprintln(w, "ready")
+}
+
+// handleStatus reports live backlog: in-flight work, producer queues and assignment
+func (s *TransformerService) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := statusResponse{
+		State:              s.currentState(),
+		InFlight:           s.inFlight(),
+		MaxConcurrent:      cap(s.semaphore),
+		ProducerQueue:      s.producer.Len(),
+		ProtoProducerQueue: s.protoProducer.Len(),
+		Assignment:         []partitionResponse{},
+	}
+	if len(s.topicPools) > 0 {
+		status.TopicInFlight = make(map[string]int, len(s.topicPools))
+		for topic, pool := range s.topicPools {
+			status.TopicInFlight[topic] = len(pool.slots)
+		}
+	}
+
+	assignment, err := s.consumer.Assignment()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read assignment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, tp := range assignment {
+		status.Assignment = append(status.Assignment, partitionResponse{
+			Topic:     *tp.Topic,
+			Partition: tp.Partition,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// getStatus serves /status and decodes the response
+func getStatus(t *testing.T, s *testService) statusResponse {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	s.handleStatus(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("/status returned %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Every field must be present, even at zero
+	var fields map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("decode /status: %v", err)
+	}
+	for _, field := range []string{"state", "in_flight", "max_concurrent", "producer_queue", "proto_producer_queue", "assignment"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("/status is missing %q", field)
+		}
+	}
+
+	var status statusResponse
+	json.Unmarshal(recorder.Body.Bytes(), &status)
+	return status
+}
+
+func TestStatusSaturated(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"MAX_CONCURRENT_MESSAGES": "2"}))
+	s.state.Store(stateRunning)
+	topic := "client.traffic"
+	s.source.assignment = []kafkalib.TopicPartition{{Topic: &topic, Partition: 0}, {Topic: &topic, Partition: 1}}
+
+	idle := getStatus(t, s)
+	if idle.InFlight != 0 || idle.MaxConcurrent != 2 || idle.ProducerQueue != 0 {
+		t.Errorf("idle status = %+v, want nothing in flight of 2", idle)
+	}
+
+	// Fill every worker slot and leave delivery reports outstanding
+	s.semaphore <- true
+	s.semaphore <- true
+	for i := 0; i < 3; i++ {
+		s.sink.Produce(sourceMessage("{}", int64(i)), nil)
+	}
+	s.proto.Produce(sourceMessage("{}", 0), nil)
+
+	status := getStatus(t, s)
+	if status.State != stateRunning {
+		t.Errorf("state = %q, want %q", status.State, stateRunning)
+	}
+	if status.InFlight != status.MaxConcurrent || status.InFlight != 2 {
+		t.Errorf("in_flight = %d of %d, want saturated at 2", status.InFlight, status.MaxConcurrent)
+	}
+	if status.ProducerQueue != 3 || status.ProtoProducerQueue != 1 {
+		t.Errorf("producer queues = %d, %d, want 3, 1", status.ProducerQueue, status.ProtoProducerQueue)
+	}
+	if len(status.Assignment) != 2 || status.Assignment[1].Topic != topic || status.Assignment[1].Partition != 1 {
+		t.Errorf("assignment = %+v, want both partitions of %s", status.Assignment, topic)
+	}
+}
+
+func TestStatusCountsTopicPools(t *testing.T) {
+	tests := []struct {
+		name     string
+		busy     int // Messages admitted to client.bulk
+		quiet    int // Messages admitted to client.traffic
+		unpooled int // Workers held on the shared semaphore
+	}{
+		{"idle", 0, 0, 0},
+		{"busy topic only", 2, 0, 0},
+		{"busy and quiet topics", 2, 1, 0},
+		{"pooled and unpooled work", 1, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"SOURCE_TOPIC":            "client.traffic,client.bulk",
+				"SOURCE_TOPIC_WEIGHTS":    "client.traffic=1,client.bulk=1",
+				"MAX_CONCURRENT_MESSAGES": "4",
+			}))
+			admit := func(topic string, n int) {
+				for i := 0; i < n; i++ {
+					msg := sourceMessage(sampleCapture, int64(i))
+					msg.TopicPartition.Topic = &topic
+					if !s.admit(s.topicPools[topic], msg) {
+						t.Fatalf("message %d of %s was not admitted", i, topic)
+					}
+				}
+			}
+			admit("client.bulk", tt.busy)
+			admit("client.traffic", tt.quiet)
+			for i := 0; i < tt.unpooled; i++ {
+				s.semaphore <- true
+			}
+
+			status := getStatus(t, s)
+			if want := tt.busy + tt.quiet + tt.unpooled; status.InFlight != want {
+				t.Errorf("in_flight = %d, want %d", status.InFlight, want)
+			}
+			if status.TopicInFlight["client.bulk"] != tt.busy || status.TopicInFlight["client.traffic"] != tt.quiet {
+				t.Errorf("topic_in_flight = %v, want client.bulk=%d client.traffic=%d", status.TopicInFlight, tt.busy, tt.quiet)
+			}
+		})
+	}
+}
+
+func TestProbes(t *testing.T) {
+	tests := []struct {
+		name           string
+		state          string
+		downstreamDown bool
+		failingFor     time.Duration // Consumer errors persisting this long; 0 for none
+		wantHealthz    int
+		wantReadyz     int
+	}{
+		{"starting", stateStarting, false, 0, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"running", stateRunning, false, 0, http.StatusOK, http.StatusOK},
+		{"paused for the downstream", stateRunning, true, 0, http.StatusOK, http.StatusServiceUnavailable},
+		{"brief consumer errors", stateRunning, false, time.Second, http.StatusOK, http.StatusOK},
+		{"persistent consumer errors", stateRunning, false, time.Hour, http.StatusOK, http.StatusServiceUnavailable},
+		{"stopping", stateStopping, false, 0, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"CONSUMER_ERROR_WINDOW": "1m"}))
+			s.state.Store(tt.state)
+			s.downstreamHealthy.Store(!tt.downstreamDown)
+			if tt.failingFor > 0 {
+				s.consumerErrorsSince.Store(time.Now().Add(-tt.failingFor).UnixNano())
+			}
+
+			probes := []struct {
+				path    string
+				handler http.HandlerFunc
+				want    int
+			}{
+				{"/healthz", s.handleHealthz, tt.wantHealthz},
+				{"/readyz", s.handleReadyz, tt.wantReadyz},
+			}
+			for _, probe := range probes {
+				recorder := httptest.NewRecorder()
+				probe.handler(recorder, httptest.NewRequest(http.MethodGet, probe.path, nil))
+				if recorder.Code != probe.want {
+					t.Errorf("%s = %d (%s), want %d", probe.path, recorder.Code, recorder.Body.String(), probe.want)
+				}
+			}
+		})
+	}
+}
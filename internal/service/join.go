@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// captureJoiner holds partial captures, a request without its response or the
+// reverse, until the other half arrives or CAPTURE_JOIN_WINDOW passes
+type captureJoiner struct {
+	mu      sync.Mutex
+	pending map[string]*partialCapture
+	stopped bool
+}
+
+// partialCapture is one buffered half of an exchange
+type partialCapture struct {
+	ctx        context.Context
+	kafkaMsg   *kafkalib.Message
+	hasRequest bool
+	timer      *time.Timer
+}
+
+// newCaptureJoiner creates an empty joiner
+func newCaptureJoiner() *captureJoiner {
+	return &captureJoiner{pending: make(map[string]*partialCapture)}
+}
+
+// processCapture processes a message, first decoding it from the source format
+// and joining it with the buffered other half of its exchange when capture
+// joining is enabled
+func (s *TransformerService) processCapture(ctx context.Context, kafkaMsg *kafkalib.Message) {
+	if s.decoder != nil {
+		if kafkaMsg = s.decodeSource(ctx, kafkaMsg); kafkaMsg == nil {
+			return
+		}
+	}
+	if s.joiner != nil {
+		if kafkaMsg = s.joinCapture(ctx, kafkaMsg); kafkaMsg == nil {
+			return
+		}
+	}
+	s.processMessage(ctx, kafkaMsg)
+}
+
+// joinCapture returns the message to process now: the joined capture when
+// the message completes a buffered half, the message itself when it is not
+// partial, or nil when it was buffered to wait for its other half.
+//
+// A buffered message stays in flight, so its offset is not committed until the
+// capture it ends up in has been delivered.
+func (s *TransformerService) joinCapture(ctx context.Context, kafkaMsg *kafkalib.Message) *kafkalib.Message {
+	id, hasRequest, hasResponse := captureParts(kafkaMsg.Value, s.config.CaptureIDField)
+	if id == "" || hasRequest == hasResponse {
+		return kafkaMsg
+	}
+
+	j := s.joiner
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stopped {
+		return kafkaMsg
+	}
+
+	if other, ok := j.pending[id]; ok && other.hasRequest != hasRequest {
+		delete(j.pending, id)
+		other.timer.Stop()
+
+		joined, err := mergeCaptures(other.kafkaMsg.Value, kafkaMsg.Value)
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("Failed to join capture %s: %v", id, err))
+			s.wg.Add(1)
+			go s.flushCapture(other)
+			return kafkaMsg
+		}
+		// The joined capture carries the buffered half, so that half finishes with it
+		if ticket := offsetTicketFrom(ctx); ticket != nil {
+			ticket.link(offsetTicketFrom(other.ctx))
+		} else if ticket := offsetTicketFrom(other.ctx); ticket != nil {
+			ticket.release()
+		}
+		s.metrics.IncrementCapturesJoined()
+		s.logger.Debug(fmt.Sprintf("Joined capture %s", id))
+
+		msg := *kafkaMsg
+		msg.Value = joined
+		return &msg
+	}
+
+	// A repeated half of a buffered capture is processed on its own
+	if _, ok := j.pending[id]; ok {
+		return kafkaMsg
+	}
+
+	if ticket := offsetTicketFrom(ctx); ticket != nil {
+		ticket.hold()
+	}
+	capture := &partialCapture{ctx: ctx, kafkaMsg: kafkaMsg, hasRequest: hasRequest}
+	capture.timer = time.AfterFunc(s.config.CaptureJoinWindow, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		if j.stopped || j.pending[id] != capture {
+			return
+		}
+		delete(j.pending, id)
+		s.wg.Add(1)
+		go s.flushCapture(capture)
+	})
+	j.pending[id] = capture
+	return nil
+}
+
+// flushCapture processes a buffered half on its own
+func (s *TransformerService) flushCapture(capture *partialCapture) {
+	defer s.wg.Done()
+	if ticket := offsetTicketFrom(capture.ctx); ticket != nil {
+		defer ticket.release()
+	}
+	s.processMessage(capture.ctx, capture.kafkaMsg)
+}
+
+// stopJoiner stops buffering and drops the captures still waiting. Their
+// offsets stay uncommitted, so they are read again after a restart.
+func (s *TransformerService) stopJoiner() {
+	if s.joiner == nil {
+		return
+	}
+	j := s.joiner
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.stopped = true
+	for _, capture := range j.pending {
+		capture.timer.Stop()
+	}
+	if len(j.pending) > 0 {
+		s.logger.Info(fmt.Sprintf("🧩 %d partial captures left uncommitted", len(j.pending)))
+	}
+}
+
+// captureParts reads a capture's correlation ID from the dot-separated idField
+// path and reports which of the request and response sections it carries
+func captureParts(data []byte, idField string) (id string, hasRequest, hasResponse bool) {
+	var input map[string]interface{}
+	if err := decodeNumbers(data, &input); err != nil {
+		return "", false, false
+	}
+	_, hasRequest = input["request"].(map[string]interface{})
+	_, hasResponse = input["response"].(map[string]interface{})
+
+	var value interface{} = input
+	for _, key := range strings.Split(idField, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", hasRequest, hasResponse
+		}
+		value = object[key]
+	}
+	switch v := value.(type) {
+	case string:
+		id = v
+	case json.Number:
+		id = v.String()
+	}
+	return id, hasRequest, hasResponse
+}
+
+// mergeCaptures combines the two halves of an exchange. Sections both carry,
+// such as info, are merged field by field, the first half winning conflicts.
+func mergeCaptures(first, second []byte) ([]byte, error) {
+	var merged, other map[string]interface{}
+	if err := decodeNumbers(first, &merged); err != nil {
+		return nil, err
+	}
+	if err := decodeNumbers(second, &other); err != nil {
+		return nil, err
+	}
+	for key, value := range other {
+		existing, ok := merged[key]
+		if !ok {
+			merged[key] = value
+			continue
+		}
+		section, isObject := existing.(map[string]interface{})
+		otherSection, otherIsObject := value.(map[string]interface{})
+		if !isObject || !otherIsObject {
+			continue
+		}
+		for field, fieldValue := range otherSection {
+			if _, ok := section[field]; !ok {
+				section[field] = fieldValue
+			}
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// decodeNumbers unmarshals JSON keeping numbers exact
+func decodeNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// captureHalf returns sampleCapture with a capture ID and without the section
+// named by drop ("request", "response" or "" for a complete capture)
+func captureHalf(t *testing.T, id string, drop string) string {
+	t.Helper()
+	var capture map[string]interface{}
+	if err := json.Unmarshal([]byte(sampleCapture), &capture); err != nil {
+		t.Fatalf("decode sampleCapture: %v", err)
+	}
+	capture["info"].(map[string]interface{})["captureId"] = id
+	if drop != "" {
+		delete(capture, drop)
+	}
+	data, err := json.Marshal(capture)
+	if err != nil {
+		t.Fatalf("encode capture: %v", err)
+	}
+	return string(data)
+}
+
+func TestJoinCaptures(t *testing.T) {
+	tests := []struct {
+		name   string
+		values func(t *testing.T) []string
+		want   []bool // captureComplete of each published record, in order
+	}{
+		{"request then response", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "response"), captureHalf(t, "a", "request")}
+		}, []bool{true}},
+		{"response then request", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "request"), captureHalf(t, "a", "response")}
+		}, []bool{true}},
+		{"complete capture passes through", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "")}
+		}, []bool{true}},
+		{"lone request flushed after the window", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "response")}
+		}, []bool{false}},
+		{"lone response flushed after the window", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "request")}
+		}, []bool{false}},
+		{"different captures are not joined", func(t *testing.T) []string {
+			return []string{captureHalf(t, "a", "response"), captureHalf(t, "b", "request")}
+		}, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"CAPTURE_JOIN_WINDOW": "100ms",
+				"CAPTURE_ID_FIELD":    "info.captureId",
+			}))
+			s.start(t)
+
+			for i, value := range tt.values(t) {
+				s.source.send(sourceMessage(value, int64(i)))
+			}
+			eventually(t, 2*time.Second, func() bool { return len(s.sink.messages("akto.api.logs")) >= len(tt.want) },
+				"published %d records, want %d", len(s.sink.messages("akto.api.logs")), len(tt.want))
+			time.Sleep(150 * time.Millisecond) // Past the window, nothing more is published
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != len(tt.want) {
+				t.Fatalf("published %d records, want %d", len(published), len(tt.want))
+			}
+			for i, msg := range published {
+				var record map[string]interface{}
+				if err := json.Unmarshal(msg.Value, &record); err != nil {
+					t.Fatalf("decode record: %v", err)
+				}
+				if record["captureComplete"] != tt.want[i] {
+					t.Errorf("record %d captureComplete = %v, want %v", i, record["captureComplete"], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"client-message-transformer/internal/transformer"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// kafkaHeaderValue returns the first value of a source message header, matched
+// case-insensitively. Headers listed in BINARY_HEADERS are base64-decoded,
+// falling back to the raw bytes when the value is not valid base64.
+func (s *TransformerService) kafkaHeaderValue(kafkaMsg *kafkalib.Message, name string) (string, bool) {
+	for _, header := range kafkaMsg.Headers {
+		if !strings.EqualFold(header.Key, name) {
+			continue
+		}
+		if s.isBinaryHeader(header.Key) {
+			if decoded, err := base64.StdEncoding.DecodeString(string(header.Value)); err == nil {
+				return string(decoded), true
+			}
+		}
+		return string(header.Value), true
+	}
+	return "", false
+}
+
+// isBinaryHeader reports whether a header is listed in BINARY_HEADERS
+func (s *TransformerService) isBinaryHeader(name string) bool {
+	for _, binary := range s.config.BinaryHeaders {
+		if strings.EqualFold(binary, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withSourceHeaders appends the source message's headers to the ones set by
+// the transformer, skipping any key the transformer already set
+func withSourceHeaders(headers []kafkalib.Header, kafkaMsg *kafkalib.Message) []kafkalib.Header {
+	set := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		set[header.Key] = true
+	}
+	for _, header := range kafkaMsg.Headers {
+		if !set[header.Key] {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// withTruncationHeader marks a published message whose record had a body cut
+// at MAX_BODY_BYTES, so consumers of protobuf output can tell too
+func withTruncationHeader(headers []kafkalib.Header, record map[string]interface{}) []kafkalib.Header {
+	if truncated, _ := record[transformer.FieldBodyTruncated].(bool); truncated {
+		headers = append(headers, kafkalib.Header{Key: transformer.FieldBodyTruncated, Value: []byte("true")})
+	}
+	return headers
+}
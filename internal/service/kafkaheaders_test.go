@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestKafkaHeaderValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		binary  string
+		key     string
+		value   string
+		want    string
+		wantHit bool
+	}{
+		{"base64 header decoded", "client_id", "client_id", "Y2xpZW50LTQy", "client-42", true},
+		{"binary list matched case-insensitively", "CLIENT_ID", "Client_Id", "Y2xpZW50LTQy", "client-42", true},
+		{"invalid base64 kept raw", "client_id", "client_id", "client-42!", "client-42!", true},
+		{"normal header untouched", "", "client_id", "Y2xpZW50LTQy", "Y2xpZW50LTQy", true},
+		{"other binary header does not apply", "x-trace", "client_id", "Y2xpZW50LTQy", "Y2xpZW50LTQy", true},
+		{"missing header", "client_id", "x-other", "Y2xpZW50LTQy", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"BINARY_HEADERS": tt.binary}))
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Headers = []kafkalib.Header{{Key: tt.key, Value: []byte(tt.value)}}
+
+			got, ok := s.kafkaHeaderValue(msg, "client_id")
+			if got != tt.want || ok != tt.wantHit {
+				t.Errorf("kafkaHeaderValue = %q, %v, want %q, %v", got, ok, tt.want, tt.wantHit)
+			}
+		})
+	}
+}
+
+func TestBinaryClientIDHeader(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"BINARY_HEADERS": "client_id", "CLIENT_ID_SOURCE": "header"}))
+	msg := sourceMessage(sampleCapture, 0)
+	msg.Headers = []kafkalib.Header{{Key: "client_id", Value: []byte("Y2xpZW50LTQy")}}
+	if got := s.resolveClientID(msg); got != "client-42" {
+		t.Errorf("resolveClientID = %q, want the decoded client-42", got)
+	}
+}
+
+func TestPreserveSourceHeaders(t *testing.T) {
+	const upstreamTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const traceparent = "00-" + upstreamTrace + "-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name            string
+		preserve        bool
+		tracing         bool
+		wantTenant      string
+		wantTraceparent string // "trace" expects a new span of the upstream trace
+	}{
+		{"not preserved", false, false, "", traceparent},
+		{"preserved", true, false, "acme", traceparent},
+		{"preserved with tracing", true, true, "acme", "trace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without a tracer provider spans are no-ops that still carry the
+			// upstream context
+			if tt.tracing {
+				recordSpans(t)
+			} else {
+				previous := otel.GetTextMapPropagator()
+				otel.SetTextMapPropagator(propagation.TraceContext{})
+				t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+			}
+			s := newTestService(t, testConfig(t, map[string]string{"PRESERVE_SOURCE_HEADERS": strconv.FormatBool(tt.preserve)}))
+
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Headers = []kafkalib.Header{
+				{Key: "traceparent", Value: []byte(traceparent)},
+				{Key: "x-tenant", Value: []byte("acme")},
+				{Key: "transformed_at", Value: []byte("spoofed")},
+			}
+			s.handleMessage(context.Background(), msg)
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			out := published[0]
+			if got := headerValue(out, "x-tenant"); got != tt.wantTenant {
+				t.Errorf("x-tenant = %q, want %q", got, tt.wantTenant)
+			}
+			if got := headerValue(out, "transformed_at"); got == "spoofed" {
+				t.Errorf("transformed_at = %q, want the transformer's own value", got)
+			}
+			got := headerValue(out, "traceparent")
+			switch tt.wantTraceparent {
+			case "trace":
+				if !strings.Contains(got, upstreamTrace) || got == traceparent {
+					t.Errorf("traceparent = %q, want a new span of trace %s", got, upstreamTrace)
+				}
+			default:
+				if got != tt.wantTraceparent {
+					t.Errorf("traceparent = %q, want %q", got, tt.wantTraceparent)
+				}
+			}
+		})
+	}
+}
+
+func TestPreserveSourceKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		preserve bool
+		key      []byte
+		want     string
+	}{
+		{"not preserved", false, []byte("source-key"), "1000"},
+		{"preserved", true, []byte("source-key"), "source-key"},
+		{"preserved without a source key", true, nil, "1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"PRESERVE_SOURCE_KEY": strconv.FormatBool(tt.preserve)}))
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Key = tt.key
+			s.handleMessage(context.Background(), msg)
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			if got := string(published[0].Key); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+
+	"client-message-transformer/internal/transformer"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// keyPlaceholder matches {field} placeholders in a KEY_TEMPLATE
+var keyPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// messageKey builds the Kafka message key for a transformed record
+func (s *TransformerService) messageKey(clientID string, record map[string]interface{}) string {
+	if s.config.SpreadKey {
+		return spreadKey(clientID, record)
+	}
+	if s.config.KeyTemplate == "" {
+		return clientID
+	}
+	return renderKeyTemplate(s.config.KeyTemplate, record)
+}
+
+// messagePartition pins a key to a partition when COMPUTE_PARTITION is on,
+// and otherwise leaves the choice to the producer's partitioner
+func (s *TransformerService) messagePartition(key string) int32 {
+	if !s.config.ComputePartition {
+		return kafkalib.PartitionAny
+	}
+	return computePartition(key, s.config.PartitionCount)
+}
+
+// computePartition maps a key to a partition by its FNV-1a hash
+func computePartition(key string, partitionCount int) int32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int32(hash.Sum32() % uint32(partitionCount))
+}
+
+// spreadKey hashes clientID:method:pathTemplate so one client's traffic spreads
+// across partitions while requests to the same endpoint stay together
+func spreadKey(clientID string, record map[string]interface{}) string {
+	method, _ := record["method"].(string)
+	path, _ := record["path"].(string)
+
+	hash := fnv.New64a()
+	hash.Write([]byte(clientID + ":" + method + ":" + transformer.TemplatizePath(path)))
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// renderKeyTemplate substitutes {field} placeholders with record values,
+// using an empty string for fields the record does not carry
+func renderKeyTemplate(template string, record map[string]interface{}) string {
+	return keyPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := record[placeholder[1:len(placeholder)-1]]
+		if !ok || value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	})
+}
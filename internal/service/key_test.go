@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"client-message-transformer/internal/config"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestMessageKey(t *testing.T) {
+	record := map[string]interface{}{
+		"akto_account_id": "1000",
+		"method":          "GET",
+		"path":            "/users/42",
+		"statusCode":      "200",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"client ID by default", "", "client-1"},
+		{"single field", "{path}", "/users/42"},
+		{"composite", "{akto_account_id}:{method}:{path}", "1000:GET:/users/42"},
+		{"literal text kept", "acct-{akto_account_id}", "acct-1000"},
+		{"missing field is empty", "{method}:{apiVersion}", "GET:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TransformerService{config: &config.Config{KeyTemplate: tt.template}}
+			if got := s.messageKey("client-1", record); got != tt.want {
+				t.Errorf("messageKey with %q = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpreadKey(t *testing.T) {
+	record := func(method string, path string) map[string]interface{} {
+		return map[string]interface{}{"method": method, "path": path}
+	}
+
+	tests := []struct {
+		name string
+		a, b map[string]interface{}
+		same bool
+	}{
+		{"same request", record("GET", "/users/42"), record("GET", "/users/42"), true},
+		{"IDs share the endpoint", record("GET", "/users/42"), record("GET", "/users/7?x=1"), true},
+		{"methods differ", record("GET", "/users/42"), record("POST", "/users/42"), false},
+		{"paths differ", record("GET", "/users/42"), record("GET", "/orders/42"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := spreadKey("1000", tt.a), spreadKey("1000", tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("spreadKey = %q and %q, want same=%v", a, b, tt.same)
+			}
+		})
+	}
+
+	if spreadKey("1000", record("GET", "/users")) == spreadKey("2000", record("GET", "/users")) {
+		t.Error("spreadKey is the same for different clients")
+	}
+
+	// One client's distinct endpoints spread over partitions
+	const partitions = 8
+	buckets := make(map[uint64]int)
+	for i := 0; i < 64; i++ {
+		key := spreadKey("1000", record("GET", fmt.Sprintf("/resource%d/list", i)))
+		value, err := strconv.ParseUint(key, 16, 64)
+		if err != nil {
+			t.Fatalf("spreadKey %q is not hex: %v", key, err)
+		}
+		buckets[value%partitions]++
+	}
+	if len(buckets) < partitions {
+		t.Errorf("64 endpoints hashed into %d of %d partitions: %v", len(buckets), partitions, buckets)
+	}
+	for bucket, count := range buckets {
+		if count > 20 {
+			t.Errorf("partition %d got %d of 64 endpoints", bucket, count)
+		}
+	}
+}
+
+func TestMessageKeySpread(t *testing.T) {
+	s := &TransformerService{config: &config.Config{SpreadKey: true, KeyTemplate: "{path}"}}
+	record := map[string]interface{}{"method": "GET", "path": "/users/42"}
+	if got, want := s.messageKey("1000", record), spreadKey("1000", record); got != want {
+		t.Errorf("messageKey = %q, want the spread key %q over KEY_TEMPLATE", got, want)
+	}
+}
+
+func TestComputePartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantFixed bool
+	}{
+		{"computed", map[string]string{"COMPUTE_PARTITION": "true", "PARTITION_COUNT": "6"}, true},
+		{"producer partitioner", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, tt.env))
+			for i := 0; i < 3; i++ {
+				s.handleMessage(context.Background(), sourceMessage(sampleCapture, int64(i)))
+			}
+
+			produced := s.sink.messages("akto.api.logs")
+			if len(produced) != 3 {
+				t.Fatalf("produced %d messages, want 3", len(produced))
+			}
+			for _, msg := range produced {
+				partition := msg.TopicPartition.Partition
+				if !tt.wantFixed {
+					if partition != kafkalib.PartitionAny {
+						t.Errorf("partition = %d, want PartitionAny", partition)
+					}
+					continue
+				}
+				// Same key, same partition, within the configured count
+				if want := computePartition(string(msg.Key), 6); partition != want || partition < 0 || partition >= 6 {
+					t.Errorf("partition = %d for key %q, want %d", partition, msg.Key, want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputePartitionRange(t *testing.T) {
+	counts := make(map[int32]int)
+	for i := 0; i < 600; i++ {
+		partition := computePartition(fmt.Sprintf("client-%d", i), 6)
+		if partition < 0 || partition >= 6 {
+			t.Fatalf("computePartition = %d, want within [0, 6)", partition)
+		}
+		counts[partition]++
+	}
+	if len(counts) != 6 {
+		t.Errorf("600 keys landed on %d of 6 partitions: %v", len(counts), counts)
+	}
+	if computePartition("client-1", 6) != computePartition("client-1", 6) {
+		t.Error("computePartition is not deterministic")
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"client-message-transformer/internal/metrics"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// lagQueryTimeoutMs bounds each offset query made while computing lag
+const lagQueryTimeoutMs = 5000
+
+// lagSource is the subset of the consumer used to compute lag
+type lagSource interface {
+	Assignment() ([]kafkalib.TopicPartition, error)
+	Committed(partitions []kafkalib.TopicPartition, timeoutMs int) ([]kafkalib.TopicPartition, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+}
+
+// computeLag returns the per-partition lag (high watermark minus committed
+// offset) of the assigned partitions and their total
+func computeLag(source lagSource) ([]metrics.PartitionLag, int64, error) {
+	assignment, err := source.Assignment()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read assignment: %w", err)
+	}
+	if len(assignment) == 0 {
+		return nil, 0, nil
+	}
+
+	committed, err := source.Committed(assignment, lagQueryTimeoutMs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read committed offsets: %w", err)
+	}
+
+	var lags []metrics.PartitionLag
+	var total int64
+	for _, tp := range committed {
+		low, high, err := source.QueryWatermarkOffsets(*tp.Topic, tp.Partition, lagQueryTimeoutMs)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to query watermarks for %s[%d]: %w", *tp.Topic, tp.Partition, err)
+		}
+
+		// Without a committed offset everything retained is still unconsumed
+		position := int64(tp.Offset)
+		if position < 0 {
+			position = low
+		}
+
+		lag := high - position
+		if lag < 0 {
+			lag = 0
+		}
+		lags = append(lags, metrics.PartitionLag{Topic: *tp.Topic, Partition: tp.Partition, Lag: lag})
+		total += lag
+	}
+	return lags, total, nil
+}
+
+// lagAlerter fires once lag has stayed above the threshold for the sustain
+// window, and re-arms after lag recovers
+type lagAlerter struct {
+	threshold  int64
+	sustain    time.Duration
+	aboveSince time.Time
+	fired      bool
+}
+
+// observe records a lag sample and reports whether an alert should fire now
+func (a *lagAlerter) observe(lag int64, now time.Time) bool {
+	if lag <= a.threshold {
+		a.aboveSince = time.Time{}
+		a.fired = false
+		return false
+	}
+	if a.aboveSince.IsZero() {
+		a.aboveSince = now
+	}
+	if a.fired || now.Sub(a.aboveSince) < a.sustain {
+		return false
+	}
+	a.fired = true
+	return true
+}
+
+// monitorLag periodically computes consumer lag, recording it in the metrics
+// and alerting when it stays high. It runs off the consume loop since offset
+// queries wait on the brokers.
+func (s *TransformerService) monitorLag(ctx context.Context) {
+	defer s.wg.Done()
+
+	alerter := &lagAlerter{threshold: s.config.LagAlertThreshold, sustain: s.config.LagAlertDuration}
+	ticker := time.NewTicker(s.config.LagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			lags, total, err := computeLag(s.consumer)
+			if err != nil {
+				s.logger.Warn(fmt.Sprintf("Lag check failed: %v", err))
+				continue
+			}
+			s.metrics.SetConsumerLag(lags)
+			s.logger.Debug(fmt.Sprintf("Consumer lag: %d messages", total))
+
+			if s.config.LagAlertThreshold > 0 && alerter.observe(total, time.Now()) {
+				s.sendLagAlert(ctx, total)
+			}
+		}
+	}
+}
+
+// sendLagAlert logs and, when a webhook is configured, posts a lag alert
+func (s *TransformerService) sendLagAlert(ctx context.Context, lag int64) {
+	message := fmt.Sprintf("consumer lag %d has exceeded %d for over %v", lag, s.config.LagAlertThreshold, s.config.LagAlertDuration)
+	s.logger.Warn(fmt.Sprintf("🚨 %s", message))
+
+	if s.alerts == nil {
+		return
+	}
+	err := s.alerts.Send(ctx, "consumer_lag", message, map[string]interface{}{
+		"lag":            lag,
+		"threshold":      s.config.LagAlertThreshold,
+		"consumer_group": s.config.ConsumerGroup,
+		"client_id":      s.config.ClientID,
+	})
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to send lag alert: %v", err))
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/alert"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestLagAlerter(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		samples []int64 // One per second
+		want    []bool
+	}{
+		{"below threshold", []int64{10, 100, 50}, []bool{false, false, false}},
+		{"fires once sustained", []int64{150, 150, 150, 150}, []bool{false, false, true, false}},
+		{"dip resets the window", []int64{150, 150, 10, 150, 150, 150}, []bool{false, false, false, false, false, true}},
+		{"re-arms after recovery", []int64{150, 150, 150, 10, 150, 150, 150}, []bool{false, false, true, false, false, false, true}},
+		{"threshold itself is not above", []int64{100, 100, 100}, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerter := &lagAlerter{threshold: 100, sustain: 2 * time.Second}
+			for i, lag := range tt.samples {
+				if got := alerter.observe(lag, start.Add(time.Duration(i)*time.Second)); got != tt.want[i] {
+					t.Errorf("sample %d (lag %d): fired = %v, want %v", i, lag, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLagAlertWebhook(t *testing.T) {
+	alerts := make(chan alert.Alert, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received alert.Alert
+		json.NewDecoder(r.Body).Decode(&received)
+		alerts <- received
+	}))
+	defer webhook.Close()
+
+	s := newTestService(t, testConfig(t, map[string]string{
+		"ALERT_WEBHOOK_URL":   webhook.URL,
+		"LAG_ALERT_THRESHOLD": "100",
+		"LAG_ALERT_DURATION":  "1ms",
+		"LAG_CHECK_INTERVAL":  "10ms",
+	}))
+	topic := "client.traffic"
+	s.source.assignment = []kafkalib.TopicPartition{{Topic: &topic, Partition: 0}, {Topic: &topic, Partition: 1}}
+	s.source.committed[partitionKey{topic: topic, partition: 0}] = 100
+	s.source.watermarks[partitionKey{topic: topic, partition: 0}] = [2]int64{0, 200}
+	s.source.watermarks[partitionKey{topic: topic, partition: 1}] = [2]int64{50, 120}
+	s.start(t)
+
+	select {
+	case received := <-alerts:
+		if received.Name != "consumer_lag" {
+			t.Errorf("alert = %q, want consumer_lag", received.Name)
+		}
+		// 100 behind on partition 0 and 70 never-committed on partition 1
+		if lag, _ := received.Details["lag"].(float64); lag != 170 {
+			t.Errorf("alert lag = %v, want 170", received.Details["lag"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no lag alert was sent")
+	}
+
+	// The alert fires once until lag recovers
+	select {
+	case received := <-alerts:
+		t.Errorf("second alert while lag stayed high: %+v", received)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestComputeLag(t *testing.T) {
+	topic := "client.traffic"
+	tests := []struct {
+		name       string
+		partitions int32
+		committed  map[int32]kafkalib.Offset
+		watermarks map[int32][2]int64
+		want       []int64 // Lag of each partition
+		wantTotal  int64
+	}{
+		{"nothing assigned", 0, nil, nil, nil, 0},
+		{"behind the high watermark", 1, map[int32]kafkalib.Offset{0: 100}, map[int32][2]int64{0: {0, 250}}, []int64{150}, 150},
+		{"caught up", 1, map[int32]kafkalib.Offset{0: 250}, map[int32][2]int64{0: {0, 250}}, []int64{0}, 0},
+		{"nothing committed counts from the low watermark", 1, nil, map[int32][2]int64{0: {40, 100}}, []int64{60}, 60},
+		{"committed past a truncated log", 1, map[int32]kafkalib.Offset{0: 300}, map[int32][2]int64{0: {0, 250}}, []int64{0}, 0},
+		{"several partitions", 3, map[int32]kafkalib.Offset{0: 10, 2: 5}, map[int32][2]int64{0: {0, 30}, 1: {0, 7}, 2: {0, 5}}, []int64{20, 7, 0}, 27},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newFakeConsumer()
+			for partition := int32(0); partition < tt.partitions; partition++ {
+				source.assignment = append(source.assignment, kafkalib.TopicPartition{Topic: &topic, Partition: partition})
+			}
+			for partition, offset := range tt.committed {
+				source.committed[partitionKey{topic: topic, partition: partition}] = offset
+			}
+			for partition, watermarks := range tt.watermarks {
+				source.watermarks[partitionKey{topic: topic, partition: partition}] = watermarks
+			}
+
+			lags, total, err := computeLag(source)
+			if err != nil {
+				t.Fatalf("computeLag: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total lag = %d, want %d", total, tt.wantTotal)
+			}
+			if len(lags) != len(tt.want) {
+				t.Fatalf("lags = %v, want %d partitions", lags, len(tt.want))
+			}
+			for i, lag := range lags {
+				if lag.Topic != topic || lag.Partition != int32(i) || lag.Lag != tt.want[i] {
+					t.Errorf("lag %d = %+v, want %s[%d] lag %d", i, lag, topic, i, tt.want[i])
+				}
+			}
+		})
+	}
+}
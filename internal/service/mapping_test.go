@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMappingFile(t *testing.T) {
+	const mapping = "path: req.uri\nmethod: req.verb\nrequestHeaders: req.headers\n" +
+		"responsePayload: res.body\nstatusCode: res.status\ntype: {value: HTTP/2}\n"
+	const capture = `{"req":{"uri":"/users","verb":"GET","headers":{"Authorization":"Bearer secret-token"}},` +
+		`"res":{"status":200,"body":"{\"id\":1}"}}`
+
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantResponse string
+		wantScheme   interface{}
+	}{
+		{"mapping alone", nil, `{"id":1}`, nil},
+		{"with DROP_RESPONSE_BODY", map[string]string{"DROP_RESPONSE_BODY": "true"}, "", nil},
+		{"with EXTRACT_AUTH_SCHEME", map[string]string{"EXTRACT_AUTH_SCHEME": "true"}, `{"id":1}`, "Bearer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mapping.yaml")
+			if err := os.WriteFile(path, []byte(mapping), 0o600); err != nil {
+				t.Fatalf("write mapping: %v", err)
+			}
+			env := map[string]string{"MAPPING_FILE": path}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			s := newTestService(t, testConfig(t, env))
+			s.handleMessage(context.Background(), sourceMessage(capture, 0))
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(published[0].Value, &record); err != nil {
+				t.Fatalf("decode record: %v", err)
+			}
+			if record["responsePayload"] != tt.wantResponse {
+				t.Errorf("responsePayload = %q, want %q", record["responsePayload"], tt.wantResponse)
+			}
+			if record["authScheme"] != tt.wantScheme {
+				t.Errorf("authScheme = %v, want %v", record["authScheme"], tt.wantScheme)
+			}
+			if tt.wantScheme != nil && strings.Contains(record["requestHeaders"].(string), "secret-token") {
+				t.Errorf("requestHeaders = %s, want the credential redacted", record["requestHeaders"])
+			}
+			if record["type"] != "HTTP/2" || record["path"] != "/users" {
+				t.Errorf("record lost mapped fields: %v", record)
+			}
+		})
+	}
+}
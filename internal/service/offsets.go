@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// partitionKey identifies a source partition
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets tracks one partition's unfinished messages
+type partitionOffsets struct {
+	inFlight  map[kafkalib.Offset]*offsetTicket
+	next      kafkalib.Offset // One past the highest offset read
+	committed kafkalib.Offset // Last offset committed
+}
+
+// offsetTracker finds, per partition, the highest offset safe to commit so
+// that no offset is committed ahead of a message that has not finished
+// processing and delivery (at-least-once)
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+// offsetTicket holds a source message in flight while its handler runs and
+// while any message produced for it awaits its delivery report
+type offsetTicket struct {
+	tracker *offsetTracker
+	key     partitionKey
+	offset  kafkalib.Offset
+	started time.Time
+	pending atomic.Int32
+	warned  bool          // Set once the message has been reported as stuck
+	parked  bool          // Rewound unprocessed; its hold passes to the redelivery, see park
+	linked  *offsetTicket // Held until this message finishes, see link
+}
+
+// newOffsetTracker creates an empty tracker
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+// begin puts a message in flight. A message read again returns the ticket it
+// already holds: a parked message hands its hold to the redelivery, and any
+// other redelivery of an in-flight offset takes a hold of its own, so the
+// offset stays uncommitted until every delivery has finished.
+func (t *offsetTracker) begin(tp kafkalib.TopicPartition) *offsetTicket {
+	key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	partition := t.partitions[key]
+	if partition == nil {
+		partition = &partitionOffsets{
+			inFlight:  make(map[kafkalib.Offset]*offsetTicket),
+			committed: kafkalib.OffsetInvalid,
+		}
+		t.partitions[key] = partition
+	}
+	if ticket, ok := partition.inFlight[tp.Offset]; ok {
+		if ticket.parked {
+			ticket.parked = false
+		} else {
+			ticket.hold()
+		}
+		return ticket
+	}
+
+	ticket := &offsetTicket{tracker: t, key: key, offset: tp.Offset, started: time.Now()}
+	ticket.pending.Store(1)
+	partition.inFlight[tp.Offset] = ticket
+	if tp.Offset+1 > partition.next {
+		partition.next = tp.Offset + 1
+	}
+	return ticket
+}
+
+// hold keeps the message in flight until a matching release
+func (ticket *offsetTicket) hold() {
+	ticket.pending.Add(1)
+}
+
+// park keeps an unprocessed message in flight after its partition was rewound
+// to it, leaving its hold for the redelivery to take over in begin
+func (ticket *offsetTicket) park() {
+	t := ticket.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ticket.parked = true
+}
+
+// link hands one hold on other to this message, releasing it when this
+// message finishes. Used when this message carries other's content.
+func (ticket *offsetTicket) link(other *offsetTicket) {
+	ticket.linked = other
+}
+
+// release drops one hold, finishing the message when none remain
+func (ticket *offsetTicket) release() {
+	if ticket.pending.Add(-1) > 0 {
+		return
+	}
+	if ticket.linked != nil {
+		defer ticket.linked.release()
+	}
+
+	t := ticket.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if partition := t.partitions[ticket.key]; partition != nil && partition.inFlight[ticket.offset] == ticket {
+		delete(partition.inFlight, ticket.offset)
+	}
+}
+
+// watermark returns the offset to commit for a partition: its lowest
+// unfinished offset, or one past the highest offset read when all are done
+func (partition *partitionOffsets) watermark() kafkalib.Offset {
+	low := partition.next
+	for offset := range partition.inFlight {
+		if offset < low {
+			low = offset
+		}
+	}
+	return low
+}
+
+// commitable returns the partitions whose watermark moved past the last commit
+func (t *offsetTracker) commitable(assignment []kafkalib.TopicPartition) []kafkalib.TopicPartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var offsets []kafkalib.TopicPartition
+	for _, tp := range assignment {
+		partition := t.partitions[partitionKey{topic: *tp.Topic, partition: tp.Partition}]
+		if partition == nil {
+			continue
+		}
+		if watermark := partition.watermark(); watermark > partition.committed {
+			offsets = append(offsets, kafkalib.TopicPartition{Topic: tp.Topic, Partition: tp.Partition, Offset: watermark})
+		}
+	}
+	return offsets
+}
+
+// markCommitted records offsets acknowledged by the group coordinator
+func (t *offsetTracker) markCommitted(offsets []kafkalib.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range offsets {
+		if partition := t.partitions[partitionKey{topic: *tp.Topic, partition: tp.Partition}]; partition != nil {
+			partition.committed = tp.Offset
+		}
+	}
+}
+
+// stuck returns messages in flight for longer than threshold that have not
+// been reported before, marking them reported
+func (t *offsetTracker) stuck(threshold time.Duration) []*offsetTicket {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var tickets []*offsetTicket
+	for _, partition := range t.partitions {
+		for _, ticket := range partition.inFlight {
+			if !ticket.warned && time.Since(ticket.started) > threshold {
+				ticket.warned = true
+				tickets = append(tickets, ticket)
+			}
+		}
+	}
+	return tickets
+}
+
+// forget drops revoked partitions so a later reassignment starts clean
+func (t *offsetTracker) forget(partitions []kafkalib.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range partitions {
+		delete(t.partitions, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+	}
+}
+
+// offsetTicketKey carries a message's offset ticket through its context
+type offsetTicketKey struct{}
+
+// withOffsetTicket attaches a ticket to a message's context
+func withOffsetTicket(ctx context.Context, ticket *offsetTicket) context.Context {
+	return context.WithValue(ctx, offsetTicketKey{}, ticket)
+}
+
+// offsetTicketFrom returns the ticket attached to ctx, or nil
+func offsetTicketFrom(ctx context.Context) *offsetTicket {
+	ticket, _ := ctx.Value(offsetTicketKey{}).(*offsetTicket)
+	return ticket
+}
+
+// commitOffsets commits each assigned partition's watermark
+func (s *TransformerService) commitOffsets(partitions []kafkalib.TopicPartition) {
+	offsets := s.offsets.commitable(partitions)
+	if len(offsets) == 0 {
+		return
+	}
+	if _, err := s.consumer.CommitOffsets(offsets); err != nil {
+		s.logger.Warn(fmt.Sprintf("Commit failed: %v", err))
+		return
+	}
+	s.offsets.markCommitted(offsets)
+}
+
+// commitAssigned commits the watermarks of every assigned partition
+func (s *TransformerService) commitAssigned() {
+	assignment, err := s.consumer.Assignment()
+	if err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to read assignment: %v", err))
+		return
+	}
+	s.commitOffsets(assignment)
+}
+
+// rebalance commits what is safe for revoked partitions before they move to
+// another consumer, then forgets them
+func (s *TransformerService) rebalance(consumer *kafkalib.Consumer, event kafkalib.Event) error {
+	if revoked, ok := event.(kafkalib.RevokedPartitions); ok {
+		s.commitOffsets(revoked.Partitions)
+		s.offsets.forget(revoked.Partitions)
+	}
+	return nil
+}
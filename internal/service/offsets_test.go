@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// offsetStep is one tracker operation on an offset of client.traffic partition 0
+type offsetStep struct {
+	op     string // begin, release, park or hold
+	offset int64
+}
+
+func TestOffsetTracker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []offsetStep
+		want  kafkalib.Offset // Offset to commit; OffsetInvalid when nothing is
+	}{
+		{"nothing read", nil, kafkalib.OffsetInvalid},
+		{"all finished in order", []offsetStep{
+			{"begin", 0}, {"begin", 1}, {"release", 0}, {"release", 1},
+		}, 2},
+		{"later message finished first", []offsetStep{
+			{"begin", 0}, {"begin", 1}, {"begin", 2}, {"release", 2}, {"release", 1},
+		}, 0},
+		{"gap closes once the earliest finishes", []offsetStep{
+			{"begin", 0}, {"begin", 1}, {"begin", 2}, {"release", 2}, {"release", 0},
+		}, 1},
+		{"held for delivery after processing", []offsetStep{
+			{"begin", 0}, {"hold", 0}, {"release", 0},
+		}, 0},
+		{"delivery report finishes the message", []offsetStep{
+			{"begin", 0}, {"hold", 0}, {"release", 0}, {"release", 0},
+		}, 1},
+		{"redelivered while in flight", []offsetStep{
+			{"begin", 0}, {"begin", 0}, {"release", 0},
+		}, 0},
+		{"redelivered while in flight, both finished", []offsetStep{
+			{"begin", 0}, {"begin", 0}, {"release", 0}, {"release", 0},
+		}, 1},
+		{"parked for a rewind", []offsetStep{
+			{"begin", 0}, {"begin", 1}, {"park", 1}, {"release", 0},
+		}, 1},
+		{"parked message finished after redelivery", []offsetStep{
+			{"begin", 0}, {"park", 0}, {"begin", 0}, {"release", 0},
+		}, 1},
+	}
+	topic := "client.traffic"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			tickets := make(map[int64]*offsetTicket)
+			for _, step := range tt.steps {
+				switch step.op {
+				case "begin":
+					tickets[step.offset] = tracker.begin(kafkalib.TopicPartition{Topic: &topic, Partition: 0, Offset: kafkalib.Offset(step.offset)})
+				case "release":
+					tickets[step.offset].release()
+				case "park":
+					tickets[step.offset].park()
+				case "hold":
+					tickets[step.offset].hold()
+				}
+			}
+
+			got := kafkalib.OffsetInvalid
+			if offsets := tracker.commitable([]kafkalib.TopicPartition{{Topic: &topic, Partition: 0}}); len(offsets) > 0 {
+				got = offsets[0].Offset
+			}
+			if got != tt.want {
+				t.Errorf("commitable offset = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/tracing"
+	"client-message-transformer/internal/transformer"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"google.golang.org/protobuf/proto"
+)
+
+// publishOutputs produces one transformed message to every configured extra
+// output, rendering each in its own output version. The message has already
+// reached the main destination, so a failing output is counted and
+// dead-lettered on its own without failing the message or the other outputs.
+func (s *TransformerService) publishOutputs(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, record map[string]interface{}, recordJSON []byte) {
+	if s.config.OutputSink == config.OutputSinkStdout {
+		return // The stdout sink replaces the Kafka outputs
+	}
+
+	// Render each version at most once, however many outputs use it
+	var protoBytes []byte
+	var protoErr error
+	for _, output := range s.config.Outputs {
+		value := recordJSON
+		contentType := "application/json"
+		if output.Version == config.OutputVersionV2 {
+			if protoBytes == nil && protoErr == nil {
+				protoBytes, protoErr = s.renderV2(record)
+			}
+			if protoErr != nil {
+				s.outputFailed(ctx, clientID, kafkaMsg, output, protoErr)
+				continue
+			}
+			value = protoBytes
+			contentType = "application/x-protobuf"
+		}
+
+		if err := s.produceOutput(ctx, clientID, kafkaMsg, record, output, value, contentType); err != nil {
+			s.outputFailed(ctx, clientID, kafkaMsg, output, err)
+		}
+	}
+}
+
+// renderV2 encodes a transformed record as the v2 protobuf output
+func (s *TransformerService) renderV2(record map[string]interface{}) ([]byte, error) {
+	protoPayload, err := transformer.TransformToProtoFromFlat(record, s.transformOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", config.OutputVersionV2, err)
+	}
+	protoBytes, err := proto.Marshal(protoPayload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proto message: %w", err)
+	}
+	return protoBytes, nil
+}
+
+// outputFailed logs, counts and dead-letters a message that could not reach
+// one extra output
+func (s *TransformerService) outputFailed(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, output config.Output, err error) {
+	s.logger.Error(fmt.Sprintf("Failed to publish to output %s: %v", output.Topic, err))
+	s.metrics.IncrementOutputFailed(output.Topic)
+	s.deadLetter(ctx, clientID, kafkaMsg, dlqStageOutput, fmt.Errorf("output %s: %w", output.Topic, err))
+}
+
+// produceOutput sends a rendered message to one extra output topic
+func (s *TransformerService) produceOutput(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, record map[string]interface{}, output config.Output, value []byte, contentType string) error {
+	topic := output.Topic
+	headers := []kafkalib.Header{
+		{Key: "client_id", Value: []byte(clientID)},
+		{Key: "content_type", Value: []byte(contentType)},
+		{Key: "transformer_version", Value: []byte(output.Version)},
+		{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
+	}
+	headers = withTruncationHeader(headers, record)
+	tracing.Inject(ctx, &headers)
+
+	producer := s.producer
+	if output.Version == config.OutputVersionV2 {
+		producer = s.protoProducer
+	}
+
+	err := s.produce(ctx, producer,
+		&kafkalib.Message{
+			TopicPartition: kafkalib.TopicPartition{
+				Topic:     &topic,
+				Partition: kafkalib.PartitionAny,
+			},
+			Key:     []byte(s.messageKey(clientID, record)),
+			Value:   value,
+			Headers: headers,
+		},
+		func(err error) {
+			s.metrics.IncrementOutputFailed(topic)
+			s.deadLetter(ctx, clientID, kafkaMsg, dlqStageOutput, fmt.Errorf("output %s: %w", topic, err))
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce %s message to %s: %w", output.Version, topic, err)
+	}
+
+	s.logVerbose(fmt.Sprintf("📤 Published %s to %s (client: %s)", output.Version, topic, clientID))
+	return nil
+}
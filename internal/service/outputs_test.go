@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOutputs(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"OUTPUTS": "akto.api.logs.v1=v1,akto.api.logs.v2=v2"}))
+	s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+	tests := []struct {
+		topic       string
+		producer    *fakeProducer
+		contentType string
+		decode      func(value []byte) (string, error) // Returns the decoded path
+	}{
+		{"akto.api.logs.v1", s.sink, "application/json", func(value []byte) (string, error) {
+			var record map[string]interface{}
+			err := json.Unmarshal(value, &record)
+			path, _ := record["path"].(string)
+			return path, err
+		}},
+		{"akto.api.logs.v2", s.proto, "application/x-protobuf", func(value []byte) (string, error) {
+			var payload trafficpb.HttpResponseParam
+			err := proto.Unmarshal(value, &payload)
+			return payload.Path, err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.topic, func(t *testing.T) {
+			produced := tt.producer.messages(tt.topic)
+			if len(produced) != 1 {
+				t.Fatalf("produced %d messages to %s, want 1", len(produced), tt.topic)
+			}
+			if got := headerValue(produced[0], "content_type"); got != tt.contentType {
+				t.Errorf("content_type = %q, want %q", got, tt.contentType)
+			}
+			path, err := tt.decode(produced[0].Value)
+			if err != nil {
+				t.Fatalf("decode %s: %v", tt.topic, err)
+			}
+			if path != "/users?id=1" {
+				t.Errorf("path = %q, want /users?id=1", path)
+			}
+		})
+	}
+}
+
+func TestOutputFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing string // Output topic whose produce fails
+		healthy string
+	}{
+		{"v1 fails", "akto.api.logs.v1", "akto.api.logs.v2"},
+		{"v2 fails", "akto.api.logs.v2", "akto.api.logs.v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"OUTPUTS":   "akto.api.logs.v1=v1,akto.api.logs.v2=v2",
+				"DLQ_TOPIC": "akto.api.dlq",
+			}))
+			outputProducer(s, tt.failing).produceErrs = map[string]error{tt.failing: kafkalib.NewError(kafkalib.ErrQueueFull, "queue full", false)}
+			s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+			// The main destination and the other output still get the message
+			if got := len(s.sink.messages("akto.api.logs")); got != 1 {
+				t.Errorf("published %d messages to the main destination, want 1", got)
+			}
+			if got := len(outputProducer(s, tt.healthy).messages(tt.healthy)); got != 1 {
+				t.Errorf("published %d messages to %s, want 1", got, tt.healthy)
+			}
+
+			snapshot := s.metrics.GetSnapshot()
+			if published, failed := snapshot["published"].(int64), snapshot["failed"].(int64); published != 1 || failed != 0 {
+				t.Errorf("published/failed = %d/%d, want the message counted published", published, failed)
+			}
+			if got := snapshot["output_failed_by_topic"].(map[string]int64); got[tt.failing] != 1 || len(got) != 1 {
+				t.Errorf("output_failed_by_topic = %v, want %s:1", got, tt.failing)
+			}
+
+			dead := s.sink.messages("akto.api.dlq")
+			if len(dead) != 1 {
+				t.Fatalf("dead-lettered %d messages, want 1", len(dead))
+			}
+			if stage := headerValue(dead[0], "dlq_stage"); stage != dlqStageOutput {
+				t.Errorf("dlq_stage = %q, want %q", stage, dlqStageOutput)
+			}
+			if cause := headerValue(dead[0], "dlq_error"); !strings.Contains(cause, tt.failing) {
+				t.Errorf("dlq_error = %q, want it to name %s", cause, tt.failing)
+			}
+			if string(dead[0].Value) != sampleCapture {
+				t.Errorf("dead-lettered %q, want the source message", dead[0].Value)
+			}
+		})
+	}
+}
+
+// outputProducer returns the fake producer an extra output topic is sent through
+func outputProducer(s *testService, topic string) *fakeProducer {
+	if strings.HasSuffix(topic, ".v2") {
+		return s.proto
+	}
+	return s.sink
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// startMetricsServer serves Prometheus /metrics on METRICS_PORT
+func (s *TransformerService) startMetricsServer() error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(s.metrics.Registry(), promhttp.HandlerOpts{}))
+
+	// Listen up front so bind errors fail Start instead of a background goroutine
+	addr := fmt.Sprintf(":%d", s.config.MetricsPort)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.metricsServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error(fmt.Sprintf("Metrics server error: %v", err))
+		}
+	}()
+
+	s.logger.Info(fmt.Sprintf("📊 Prometheus metrics on %s/metrics", addr))
+	return nil
+}
+
+// stopMetricsServer shuts the metrics server down, releasing its port
+func (s *TransformerService) stopMetricsServer(ctx context.Context) {
+	if s.metricsServer == nil {
+		return
+	}
+	if err := s.metricsServer.Shutdown(ctx); err != nil {
+		s.logger.Warn(fmt.Sprintf("Metrics server shutdown: %v", err))
+	}
+}
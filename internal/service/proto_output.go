@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"client-message-transformer/internal/tracing"
+	"client-message-transformer/internal/transformer"
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+// handleProtoMessage transforms a message straight to HttpResponseParam and
+// publishes the marshaled protobuf to the destination topic (OUTPUT_FORMAT=proto)
+func (s *TransformerService) handleProtoMessage(ctx context.Context, span trace.Span, startTime time.Time, clientID string, kafkaMsg *kafkalib.Message) {
+	_, transformSpan := tracing.Tracer().Start(ctx, "transform")
+	payload, truncation, err := transformer.TransformToProto(kafkaMsg.Value, clientID, s.transformOpts)
+	transformSpan.End()
+	if err != nil {
+		s.recordTransformError(span, clientID, kafkaMsg, err)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStageTransform, err)
+		s.logMessageSummary("failed", clientID, nil, startTime)
+		return
+	}
+
+	s.logVerbose("✅ Message transformed successfully")
+	s.metrics.IncrementTransformedFor(clientID)
+
+	// Routing, keys and filters work on the flat field names
+	record := protoRecord(payload)
+	truncation.Mark(record)
+	if s.config.FieldMissMetrics {
+		s.countFieldMisses(record)
+	}
+
+	if s.config.DropPrivateIPs && isPrivateIP(payload.Ip) {
+		s.logger.Debug(fmt.Sprintf("Skipping message from private IP %s", payload.Ip))
+		s.metrics.IncrementSkippedPrivateIP()
+		s.auditDropped(ctx, clientID, kafkaMsg, dropReasonPrivateIP)
+		return
+	}
+
+	if s.skip != nil && s.skip.matches(record) {
+		s.logger.Debug(fmt.Sprintf("Skipping %s %s (status %d) by skip rules", payload.Method, payload.Path, payload.StatusCode))
+		s.metrics.IncrementSkipped()
+		s.auditDropped(ctx, clientID, kafkaMsg, dropReasonSkipped)
+		return
+	}
+
+	// Stop before publishing if the message ran past its deadline or the
+	// service is stopping
+	if s.abandoned(ctx, clientID, kafkaMsg) || ctx.Err() != nil {
+		return
+	}
+
+	data, err := proto.Marshal(payload)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to marshal proto: %v", err))
+		s.metrics.IncrementFailedFor(clientID)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStageMarshal, err)
+		return
+	}
+
+	err = s.withRetries(ctx, "Publish", func() error {
+		return s.publishMessage(ctx, clientID, kafkaMsg, record, data)
+	})
+	if err != nil && s.abandoned(ctx, clientID, kafkaMsg) {
+		return
+	}
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to publish: %v", err))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		s.metrics.IncrementFailedFor(clientID)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStagePublish, err)
+		return
+	}
+
+	s.metrics.IncrementPublishedFor(clientID)
+	s.metrics.AddProcessingTime(time.Since(startTime))
+	s.logMessageSummary("published", clientID, record, startTime)
+
+	s.logger.Debug(fmt.Sprintf("✅ Message processed in %v (client: %s)", time.Since(startTime), clientID))
+}
+
+// protoRecord exposes a protobuf payload's fields under their flat-format
+// names for status routing, key templates and filters
+func protoRecord(payload *trafficpb.HttpResponseParam) map[string]interface{} {
+	return map[string]interface{}{
+		"method":          payload.Method,
+		"path":            payload.Path,
+		"type":            payload.Type,
+		"statusCode":      strconv.Itoa(int(payload.StatusCode)),
+		"status":          payload.Status,
+		"ip":              payload.Ip,
+		"time":            strconv.Itoa(int(payload.Time)),
+		"apiCollectionId": payload.ApiCollectionId,
+		"akto_account_id": payload.AktoAccountId,
+		"source":          payload.Source,
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"client-message-transformer/internal/transformer"
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProtoOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		capture     string
+		wantSkipped bool
+	}{
+		{"published", nil, sampleCapture, false},
+		{"skipped by method", map[string]string{"SKIP_METHODS": "OPTIONS"}, strings.Replace(sampleCapture, `"GET"`, `"OPTIONS"`, 1), true},
+		{"skipped by path", map[string]string{"SKIP_PATHS": "/users"}, sampleCapture, true},
+		{"skipped by status", map[string]string{"SKIP_STATUS_CODES": "2xx"}, sampleCapture, true},
+		{"skip rules not matching", map[string]string{"SKIP_METHODS": "OPTIONS", "SKIP_PATHS": "/health"}, sampleCapture, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"OUTPUT_FORMAT": "proto"}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			s := newTestService(t, testConfig(t, env))
+			s.handleMessage(context.Background(), sourceMessage(tt.capture, 0))
+
+			published := s.sink.messages("akto.api.logs")
+			if tt.wantSkipped {
+				if len(published) != 0 {
+					t.Errorf("published %d messages, want the message skipped", len(published))
+				}
+				if got := s.metrics.GetSnapshot()["skipped"].(int64); got != 1 {
+					t.Errorf("skipped = %d, want 1", got)
+				}
+				return
+			}
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			var payload trafficpb.HttpResponseParam
+			if err := proto.Unmarshal(published[0].Value, &payload); err != nil {
+				t.Fatalf("published value is not an HttpResponseParam: %v", err)
+			}
+			if payload.Method != "GET" || payload.Path != "/users?id=1" || payload.StatusCode != 200 {
+				t.Errorf("payload = %v, want GET /users?id=1 200", &payload)
+			}
+		})
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, s *testService, published []byte)
+	}{
+		{"json", func(t *testing.T, s *testService, published []byte) {
+			want, err := transformer.TransformMessage([]byte(sampleCapture), "1000", s.transformOpts)
+			if err != nil {
+				t.Fatalf("TransformMessage: %v", err)
+			}
+			wantJSON, _ := json.Marshal(want)
+			var got, wantRecord map[string]interface{}
+			json.Unmarshal(wantJSON, &wantRecord)
+			if err := json.Unmarshal(published, &got); err != nil {
+				t.Fatalf("published value is not JSON: %v", err)
+			}
+			if !reflect.DeepEqual(got, wantRecord) {
+				t.Errorf("published %s, want %s", published, wantJSON)
+			}
+		}},
+		{"proto", func(t *testing.T, s *testService, published []byte) {
+			want, _, err := transformer.TransformToProto([]byte(sampleCapture), "1000", s.transformOpts)
+			if err != nil {
+				t.Fatalf("TransformToProto: %v", err)
+			}
+			var got trafficpb.HttpResponseParam
+			if err := proto.Unmarshal(published, &got); err != nil {
+				t.Fatalf("published value is not an HttpResponseParam: %v", err)
+			}
+			if !proto.Equal(&got, want) {
+				t.Errorf("published %v, want %v", &got, want)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"OUTPUT_FORMAT": tt.format}))
+			s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			tt.check(t, s, published[0].Value)
+		})
+	}
+}
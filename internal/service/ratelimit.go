@@ -0,0 +1,84 @@
+package service
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"client-message-transformer/internal/metrics"
+
+	"golang.org/x/time/rate"
+)
+
+// maxClientLimiters caps the per-client buckets. Client IDs come from message
+// headers or payloads, so clients seen once the cap is reached share a single
+// bucket instead of growing the map without bound.
+const maxClientLimiters = 1000
+
+// limiterSweepInterval spaces out the sweeps for idle buckets made while the
+// map is at its cap
+const limiterSweepInterval = time.Second
+
+// clientLimiters hands out an independent token bucket per client ID so one
+// noisy tenant cannot starve the others
+type clientLimiters struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	limiters  map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// newClientLimiters creates per-client buckets refilling at perSecond messages/sec
+func newClientLimiters(perSecond float64) *clientLimiters {
+	return &clientLimiters{
+		limit:    rate.Limit(perSecond),
+		burst:    int(math.Ceil(perSecond)),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// allow consumes a token for the client, reporting false when it is over its rate
+func (c *clientLimiters) allow(clientID string) bool {
+	c.mu.Lock()
+	limiter := c.limiter(clientID, time.Now())
+	c.mu.Unlock()
+
+	return limiter.Allow()
+}
+
+// limiter returns a client's bucket, creating it if needed. At the cap, idle
+// buckets are swept first and clients that still do not fit share the
+// metrics.OtherClients bucket. The caller must hold the lock.
+func (c *clientLimiters) limiter(clientID string, now time.Time) *rate.Limiter {
+	if limiter, ok := c.limiters[clientID]; ok {
+		return limiter
+	}
+	if len(c.limiters) >= maxClientLimiters {
+		c.sweep(now)
+	}
+	if len(c.limiters) >= maxClientLimiters {
+		clientID = metrics.OtherClients
+		if limiter, ok := c.limiters[clientID]; ok {
+			return limiter
+		}
+	}
+	limiter := rate.NewLimiter(c.limit, c.burst)
+	c.limiters[clientID] = limiter
+	return limiter
+}
+
+// sweep drops the buckets that have refilled completely, at most once per
+// limiterSweepInterval. A full bucket behaves exactly like a new one, so
+// dropping it loses nothing. The caller must hold the lock.
+func (c *clientLimiters) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < limiterSweepInterval {
+		return
+	}
+	c.lastSweep = now
+	for clientID, limiter := range c.limiters {
+		if limiter.TokensAt(now) >= float64(c.burst) {
+			delete(c.limiters, clientID)
+		}
+	}
+}
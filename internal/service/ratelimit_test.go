@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/metrics"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestClientLimiters(t *testing.T) {
+	limits := newClientLimiters(2)
+
+	tests := []struct {
+		clientID string
+		sends    int
+		allowed  int
+	}{
+		{"noisy", 5, 2},
+		{"quiet", 2, 2},
+		{"noisy", 1, 0}, // Still over its rate
+	}
+	for _, tt := range tests {
+		allowed := 0
+		for i := 0; i < tt.sends; i++ {
+			if limits.allow(tt.clientID) {
+				allowed++
+			}
+		}
+		if allowed != tt.allowed {
+			t.Errorf("client %s: %d of %d allowed, want %d", tt.clientID, allowed, tt.sends, tt.allowed)
+		}
+	}
+}
+
+func TestClientLimitersCapped(t *testing.T) {
+	tests := []struct {
+		name        string
+		swept       time.Duration // Since the last sweep, when one was made
+		advance     time.Duration
+		wantOwn     bool
+		wantBuckets int
+	}{
+		{"busy buckets kept", 0, 0, false, maxClientLimiters + 1},
+		{"refilled buckets swept", 0, time.Second, true, 1},
+		{"sweep waits for its interval", limiterSweepInterval / 4, time.Second / 2, false, maxClientLimiters + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := newClientLimiters(2)
+			start := time.Now()
+			for c := 0; c < maxClientLimiters; c++ {
+				limits.limiter(fmt.Sprintf("client-%d", c), start).AllowN(start, 1)
+			}
+			if tt.swept > 0 {
+				limits.lastSweep = start.Add(-tt.swept)
+			}
+
+			now := start.Add(tt.advance)
+			limiter := limits.limiter("newcomer", now)
+			if own := limits.limiters["newcomer"] == limiter; own != tt.wantOwn {
+				t.Errorf("newcomer has its own bucket = %v, want %v", own, tt.wantOwn)
+			}
+			if !tt.wantOwn && limits.limiters[metrics.OtherClients] != limiter {
+				t.Errorf("newcomer does not share the %s bucket", metrics.OtherClients)
+			}
+			if got := len(limits.limiters); got != tt.wantBuckets {
+				t.Errorf("%d buckets, want %d", got, tt.wantBuckets)
+			}
+		})
+	}
+}
+
+func TestPerClientRate(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"PER_CLIENT_RATE": "2"}))
+
+	// The noisy client sends four messages back to back, the quiet one two
+	for i, clientID := range []string{"noisy", "noisy", "quiet", "noisy", "quiet", "noisy"} {
+		msg := sourceMessage(sampleCapture, int64(i))
+		msg.Headers = []kafkalib.Header{{Key: "client_id", Value: []byte(clientID)}}
+		s.handleMessage(context.Background(), msg)
+	}
+
+	published := make(map[string]int)
+	for _, msg := range s.sink.messages("akto.api.logs") {
+		published[headerValue(msg, "client_id")]++
+	}
+	if published["noisy"] != 2 || published["quiet"] != 2 {
+		t.Errorf("published per client = %v, want noisy:2 quiet:2", published)
+	}
+
+	snapshot := s.metrics.GetSnapshot()
+	if got := snapshot["rate_limited"].(int64); got != 2 {
+		t.Errorf("rate_limited = %d, want 2", got)
+	}
+	byClient := snapshot["rate_limited_by_client"].(map[string]int64)
+	if byClient["noisy"] != 2 || byClient["quiet"] != 0 {
+		t.Errorf("rate_limited_by_client = %v, want only noisy:2", byClient)
+	}
+}
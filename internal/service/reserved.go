@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// quarantined reports whether RESERVED_CLIENT_POLICY=quarantine diverts a
+// message whose client ID is reserved
+func (s *TransformerService) quarantined(clientID string) bool {
+	if s.config.ReservedClientPolicy != "quarantine" {
+		return false
+	}
+	for _, reserved := range s.config.ReservedClientIDs {
+		if clientID == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// publishQuarantined sends a message with a reserved client ID, raw, to QUARANTINE_TOPIC
+func (s *TransformerService) publishQuarantined(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message) error {
+	if err := s.forwardRaw(ctx, s.config.QuarantineTopic, clientID, kafkaMsg); err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("🚧 Quarantined message with reserved client ID %s to %s", clientID, s.config.QuarantineTopic))
+	return nil
+}
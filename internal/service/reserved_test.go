@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestReservedClientQuarantine(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		clientID       string // client_id header
+		wantQuarantine bool
+	}{
+		{"reserved client quarantined", "quarantine", "0", true},
+		{"other reserved client quarantined", "quarantine", "test", true},
+		{"regular client published", "quarantine", "2000", false},
+		{"reserved client allowed", "allow", "0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"RESERVED_CLIENT_POLICY": tt.policy,
+				"RESERVED_CLIENT_IDS":    "0,test",
+				"QUARANTINE_TOPIC":       "transformer-quarantine",
+			}))
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Headers = []kafkalib.Header{{Key: "client_id", Value: []byte(tt.clientID)}}
+			s.handleMessage(context.Background(), msg)
+
+			quarantined := s.sink.messages("transformer-quarantine")
+			published := s.sink.messages("akto.api.logs")
+			if !tt.wantQuarantine {
+				if len(quarantined) != 0 || len(published) != 1 {
+					t.Errorf("quarantined %d and published %d, want the message published", len(quarantined), len(published))
+				}
+				return
+			}
+			if len(quarantined) != 1 || len(published) != 0 {
+				t.Fatalf("quarantined %d and published %d, want the message quarantined", len(quarantined), len(published))
+			}
+			if string(quarantined[0].Value) != sampleCapture {
+				t.Errorf("quarantined value = %s, want the raw source message", quarantined[0].Value)
+			}
+			if got := s.metrics.GetSnapshot()["quarantined"].(int64); got != 1 {
+				t.Errorf("quarantined = %d, want 1", got)
+			}
+		})
+	}
+}
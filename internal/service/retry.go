@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// withRetries runs fn, retrying failures up to MAX_RETRIES times with
+// exponential backoff. It gives up early, returning the last error, when the
+// message is abandoned or the service is stopping.
+func (s *TransformerService) withRetries(ctx context.Context, step string, fn func() error) error {
+	retryDelay := s.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > s.config.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		s.logger.Warn(fmt.Sprintf("⏳ %s attempt %d/%d failed (%v), retrying in %v...", step, attempt, s.config.MaxRetries+1, err, retryDelay))
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return err
+		case <-s.stopChan:
+			return err
+		}
+		retryDelay = time.Duration(float64(retryDelay) * 1.5) // Exponential backoff with 1.5x multiplier
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestPublishRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxRetries    int
+		failures      int
+		wantAttempts  int
+		wantPublished bool
+	}{
+		{"first attempt succeeds", 3, 0, 1, true},
+		{"succeeds after failures", 3, 2, 3, true},
+		{"succeeds on the last retry", 3, 3, 4, true},
+		{"retries exhausted", 3, 4, 4, false},
+		{"retries disabled", 0, 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"MAX_RETRIES":   strconv.Itoa(tt.maxRetries),
+				"RETRY_BACKOFF": "1ms",
+			}))
+			s.sink.failures = tt.failures
+			s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+			if s.sink.attempts != tt.wantAttempts {
+				t.Errorf("Produce called %d times, want %d", s.sink.attempts, tt.wantAttempts)
+			}
+			if published := len(s.sink.messages("akto.api.logs")) == 1; published != tt.wantPublished {
+				t.Errorf("published = %v, want %v", published, tt.wantPublished)
+			}
+			wantFailed := int64(0)
+			if !tt.wantPublished {
+				wantFailed = 1
+			}
+			if got := s.metrics.GetSnapshot()["failed"].(int64); got != wantFailed {
+				t.Errorf("failed = %d, want %d", got, wantFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// sampledOut decides whether a message falls outside SAMPLE_RATE
+func (s *TransformerService) sampledOut() bool {
+	return s.config.SampleRate < 1 && rand.Float64() >= s.config.SampleRate
+}
+
+// publishSampledOut sends a sampled-out message, raw, to SAMPLED_OUT_TOPIC
+// when one is configured so it can be kept in cheaper cold storage
+func (s *TransformerService) publishSampledOut(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message) error {
+	if s.config.SampledOutTopic == "" {
+		return nil
+	}
+	if err := s.forwardRaw(ctx, s.config.SampledOutTopic, clientID, kafkaMsg); err != nil {
+		return err
+	}
+	s.logger.Debug(fmt.Sprintf("🧊 Sampled-out message sent to %s", s.config.SampledOutTopic))
+	return nil
+}
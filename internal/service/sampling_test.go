@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        string
+		coldTopic   string
+		wantDest    int
+		wantCold    int
+		wantSampled int64
+	}{
+		{"keep everything", "1", "transformer-cold-storage", 3, 0, 0},
+		{"sample out to the cold topic", "0", "transformer-cold-storage", 0, 3, 3},
+		{"sample out without a cold topic", "0", "", 0, 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{
+				"SAMPLE_RATE":       tt.rate,
+				"SAMPLED_OUT_TOPIC": tt.coldTopic,
+			}))
+			for offset := int64(0); offset < 3; offset++ {
+				s.handleMessage(context.Background(), sourceMessage(sampleCapture, offset))
+			}
+
+			if got := len(s.sink.messages("akto.api.logs")); got != tt.wantDest {
+				t.Errorf("published %d to the destination, want %d", got, tt.wantDest)
+			}
+			cold := s.sink.messages("transformer-cold-storage")
+			if len(cold) != tt.wantCold {
+				t.Fatalf("published %d to the cold topic, want %d", len(cold), tt.wantCold)
+			}
+			for _, msg := range cold {
+				if string(msg.Value) != sampleCapture {
+					t.Errorf("cold message = %s, want the raw source message", msg.Value)
+				}
+			}
+			if got := s.metrics.GetSnapshot()["sampled_out"].(int64); got != tt.wantSampled {
+				t.Errorf("sampled_out = %d, want %d", got, tt.wantSampled)
+			}
+		})
+	}
+}
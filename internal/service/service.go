@@ -1,36 +1,88 @@
 package service
 
 import (
+	"client-message-transformer/internal/alert"
+	"client-message-transformer/internal/codec"
 	"client-message-transformer/internal/config"
 	"client-message-transformer/internal/kafka"
 	"client-message-transformer/internal/logger"
 	"client-message-transformer/internal/metrics"
+	"client-message-transformer/internal/statsd"
+	"client-message-transformer/internal/tracing"
 	"client-message-transformer/internal/transformer"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/proto"
 )
 
+// Service lifecycle states reported by the HTTP endpoints
+const (
+	stateStarting = "starting"
+	stateRunning  = "running"
+	statePaused   = "paused" // Running, but consumption paused for an unhealthy downstream
+	stateStopping = "stopping"
+)
+
 // TransformerService handles message transformation
 type TransformerService struct {
 	config        *config.Config
-	consumer      *kafkalib.Consumer
-	producer      *kafkalib.Producer
-	protoProducer *kafkalib.Producer // Second producer for proto messages
+	consumer      sourceConsumer
+	producer      destinationProducer
+	protoProducer destinationProducer // Second producer for proto messages
 	logger        *logger.Logger
 	metrics       *metrics.Metrics
+	transformOpts *transformer.Options
+	decoder       codec.Decoder   // Source decoding, nil for JSON sources
+	limiter       *rate.Limiter   // Caps produce throughput, nil when unlimited
+	clientLimits  *clientLimiters // Per-client rate limits, nil when unlimited
+	skip          *skipRules      // SKIP_* filters, nil when none are configured
+	statsd        *statsd.Client
+	statsdLast    map[string]int64      // Counter totals at the last StatsD push
+	semaphore     chan bool             // Bounds concurrent message processing
+	topicPools    map[string]*topicPool // Worker shares of weighted source topics
+	offsets       *offsetTracker        // Commit watermarks of in-flight source messages
+	joiner        *captureJoiner        // Partial captures awaiting their other half, nil when disabled
+	httpServer    *http.Server
+	metricsServer *http.Server // Prometheus /metrics, nil when METRICS_PORT is 0
+	state         atomic.Value // Lifecycle state reported by /status
+	stopTracing   func(context.Context) error
+	startedAt     time.Time
+	alerts        *alert.Webhook // Alert notifications, nil when no webhook is configured
+	// downstreamHealthy is false while DOWNSTREAM_HEALTH_URL reports unhealthy
+	downstreamHealthy atomic.Bool
+	// consumerErrorsSince is when the current run of consumer errors began
+	// (unix nanoseconds), 0 while the consumer is healthy
+	consumerErrorsSince atomic.Int64
+	// heapOverLimit is true while the heap exceeds MAX_HEAP_MB
+	heapOverLimit atomic.Bool
 	stopChan      chan bool
+	fatalChan     chan error
 	wg            sync.WaitGroup
+	deliveryWG    sync.WaitGroup  // Delivery-report handlers, done once producers close
+	retries       deliveryRetries // Delivery retries waiting out their backoff
 }
 
 // New creates a new transformer service
 func New(cfg *config.Config) (*TransformerService, error) {
-	log := logger.NewLogger(cfg.LogLevel)
+	log := logger.NewLogger(cfg.LogLevelService, nil)
+	kafkaLog := logger.NewLogger(cfg.LogLevelKafka, nil)
 
 	log.Info("╔════════════════════════════════════════════════════════════╗")
 	log.Info("║        Initializing Kafka Transformer Service             ║")
@@ -39,50 +91,45 @@ func New(cfg *config.Config) (*TransformerService, error) {
 
 	log.Info("📋 === SOURCE BROKER DETAILS ===")
 	log.Info(fmt.Sprintf("   🔗 Bootstrap Servers: %s", cfg.SourceBrokers))
-	log.Info(fmt.Sprintf("   📍 Topic: %s", cfg.SourceTopic))
+	log.Info(fmt.Sprintf("   📍 Topics: %s", strings.Join(cfg.SourceTopics, ", ")))
 	log.Info(fmt.Sprintf("   👥 Consumer Group: %s", cfg.ConsumerGroup))
 	log.Info("")
 
 	log.Info("📋 === DESTINATION BROKER DETAILS ===")
 	log.Info(fmt.Sprintf("   🔗 Bootstrap Servers: %s", cfg.DestinationBrokers))
 	log.Info(fmt.Sprintf("   📍 Topic: %s", cfg.DestinationTopic))
+	if cfg.Passthrough {
+		log.Info("   📦 Output Format: passthrough (source bytes unchanged)")
+	} else {
+		log.Info(fmt.Sprintf("   📦 Output Format: %s", cfg.OutputFormat))
+	}
+	if cfg.AuditTopic != "" {
+		log.Info(fmt.Sprintf("   📍 Audit Topic: %s", cfg.AuditTopic))
+	}
+	for _, output := range cfg.Outputs {
+		log.Info(fmt.Sprintf("   📍 Output: %s (%s)", output.Topic, output.Version))
+	}
 	log.Info("")
 
-	log.Info("⏳ Waiting for Kafka brokers to be ready...")
-	time.Sleep(5 * time.Second) // Give Kafka time to fully initialize
+	authRetry, err := kafka.NewAuthRetry(cfg.AuthRetryErrors, cfg.AuthRetries, cfg.AuthRetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_RETRY_ERRORS: %w", err)
+	}
 
 	// Create consumer
-	consumerCfg := &kafka.ClientConfig{
-		Brokers:          cfg.SourceBrokers,
-		ConsumerGroup:    cfg.ConsumerGroup,
-		Topic:            cfg.SourceTopic,
-		SASLEnabled:      cfg.SourceSASLEnabled,
-		SASLMechanism:    cfg.SourceSASLMechanism,
-		SASLUsername:     cfg.SourceSASLUsername,
-		SASLPassword:     cfg.SourceSASLPassword,
-		SecurityProtocol: cfg.SourceSecurityProtocol,
-	}
 	log.Info(fmt.Sprintf("� Attempting to connect to source broker: %s", cfg.SourceBrokers))
-	consumer, err := kafka.NewConsumer(consumerCfg)
+	consumer, err := connectConsumer(cfg, authRetry, log, kafkaLog)
 	if err != nil {
-		log.Error(fmt.Sprintf("❌ Failed to create consumer: %v", err))
+		log.Error(fmt.Sprintf("❌ Failed to connect consumer: %v", err))
 		return nil, err
 	}
 	log.Info("✅ Consumer connected to source broker successfully")
 
 	// Create producer
 	log.Info(fmt.Sprintf("� Attempting to connect to destination broker: %s", cfg.DestinationBrokers))
-	producerCfg := &kafka.ClientConfig{
-		Brokers:          cfg.DestinationBrokers,
-		SASLEnabled:      cfg.DestinationSASLEnabled,
-		SASLMechanism:    cfg.DestinationSASLMechanism,
-		SASLUsername:     cfg.DestinationSASLUsername,
-		SASLPassword:     cfg.DestinationSASLPassword,
-		SecurityProtocol: cfg.DestinationSecurityProtocol,
-	}
-	producer, err := kafka.NewProducer(producerCfg)
+	producer, err := connectProducer(cfg, authRetry, log, kafkaLog)
 	if err != nil {
-		log.Error(fmt.Sprintf("❌ Failed to create producer: %v", err))
+		log.Error(fmt.Sprintf("❌ Failed to connect producer: %v", err))
 		consumer.Close()
 		return nil, err
 	}
@@ -90,7 +137,7 @@ func New(cfg *config.Config) (*TransformerService, error) {
 
 	// Create second producer for proto messages (same broker, different topic)
 	log.Info("🚀 Creating second producer for proto messages (akto.api.logs2)")
-	protoProducer, err := kafka.NewProducer(producerCfg)
+	protoProducer, err := connectProducer(cfg, authRetry, log, kafkaLog)
 	if err != nil {
 		log.Error(fmt.Sprintf("❌ Failed to create proto producer: %v", err))
 		consumer.Close()
@@ -99,14 +146,12 @@ func New(cfg *config.Config) (*TransformerService, error) {
 	}
 	log.Info("✅ Proto producer created successfully")
 
-	service := &TransformerService{
-		config:        cfg,
-		consumer:      consumer,
-		producer:      producer,
-		protoProducer: protoProducer,
-		logger:        log,
-		metrics:       metrics.New(),
-		stopChan:      make(chan bool),
+	service, err := newService(cfg, log, consumer, producer, protoProducer)
+	if err != nil {
+		consumer.Close()
+		producer.Close()
+		protoProducer.Close()
+		return nil, err
 	}
 
 	log.Info("")
@@ -115,7 +160,7 @@ func New(cfg *config.Config) (*TransformerService, error) {
 	log.Info("╚════════════════════════════════════════════════════════════╝")
 	log.Info("")
 	log.Info("📥 SOURCE CONFIGURATION:")
-	log.Info(fmt.Sprintf("   Broker: %s | Topic: %s | Group: %s", cfg.SourceBrokers, cfg.SourceTopic, cfg.ConsumerGroup))
+	log.Info(fmt.Sprintf("   Broker: %s | Topic: %s | Group: %s", cfg.SourceBrokers, strings.Join(cfg.SourceTopics, ","), cfg.ConsumerGroup))
 	log.Info("")
 	log.Info("📤 DESTINATION CONFIGURATION:")
 	log.Info(fmt.Sprintf("   Broker: %s | Topic: %s", cfg.DestinationBrokers, cfg.DestinationTopic))
@@ -126,19 +171,166 @@ func New(cfg *config.Config) (*TransformerService, error) {
 	return service, nil
 }
 
+// newService wires a service around connected Kafka clients. The caller
+// closes the clients if it fails.
+func newService(cfg *config.Config, log *logger.Logger, consumer sourceConsumer, producer destinationProducer, protoProducer destinationProducer) (*TransformerService, error) {
+	service := &TransformerService{
+		config:        cfg,
+		consumer:      consumer,
+		producer:      producer,
+		protoProducer: protoProducer,
+		logger:        log,
+		metrics:       metrics.New(),
+		offsets:       newOffsetTracker(),
+		transformOpts: &transformer.Options{
+			Logger:                   logger.NewLogger(cfg.LogLevelTransformer, nil),
+			CompactLog:               cfg.CompactMessageLog,
+			MaxJSONDepth:             cfg.MaxJSONDepth,
+			DropHeaders:              cfg.DropHeaders,
+			CoalesceDuplicateHeaders: cfg.CoalesceDuplicateHeaders,
+			ExtractAPIVersion:        cfg.ExtractAPIVersion,
+			APIVersionHeader:         cfg.APIVersionHeader,
+			TimeFormat:               cfg.TimeOutputFormat,
+			DateTimeUnit:             cfg.DateTimeUnit,
+			PayloadEncoding:          cfg.PayloadEncoding,
+			DecodeFailurePolicy:      cfg.DecodeFailurePolicy,
+			DecodeBase64Bodies:       cfg.DecodeBase64Bodies,
+			ParseFormBody:            cfg.ParseFormBody,
+			KeepHeaders:              cfg.KeepHeaders,
+			MaxHeaderValueSize:       cfg.MaxHeaderValueSize,
+			MaxBodyBytes:             cfg.MaxBodyBytes,
+			AllowResponseOnly:        cfg.CaptureJoinWindow > 0,
+			HeaderCase:               cfg.HeaderCase,
+			DechunkBodies:            cfg.DechunkBodies,
+			SniffContentType:         cfg.SniffContentType,
+			StructuredBody:           cfg.OutputStructuredBody,
+			IncludeRawHeaders:        cfg.ProtoRawHeaders,
+			DropResponseBody:         cfg.DropResponseBody,
+			ExtractAuthScheme:        cfg.ExtractAuthScheme,
+			AuthHeaders:              cfg.AuthHeaders,
+			InternalHostSuffixes:     cfg.InternalHostSuffixes,
+			HostToCollection:         cfg.HostToCollection,
+		},
+		semaphore: make(chan bool, cfg.MaxConcurrentMessages),
+		stopChan:  make(chan bool),
+		fatalChan: make(chan error, 1),
+	}
+
+	service.topicPools = newTopicPools(cfg.SourceTopicWeights, cfg.MaxConcurrentMessages)
+	for topic, pool := range service.topicPools {
+		log.Info(fmt.Sprintf("⚖️  Topic %s limited to %d concurrent messages", topic, cap(pool.slots)))
+	}
+
+	service.startedAt = time.Now()
+	service.state.Store(stateStarting)
+	service.downstreamHealthy.Store(true)
+
+	if cfg.MappingFile != "" {
+		mapping, err := transformer.LoadMapping(cfg.MappingFile)
+		if err != nil {
+			log.Error(fmt.Sprintf("❌ Failed to load field mapping: %v", err))
+			return nil, err
+		}
+		service.transformOpts.Mapping = mapping
+		log.Info(fmt.Sprintf("🗺️  Mapping %d fields from %s", len(mapping), cfg.MappingFile))
+	}
+
+	decoder, err := codec.New(cfg.SourceFormat, cfg.SchemaRegistryURL)
+	if err != nil {
+		log.Error(fmt.Sprintf("❌ Failed to set up source decoding: %v", err))
+		return nil, err
+	}
+	service.decoder = decoder
+	if service.decoder != nil {
+		log.Info(fmt.Sprintf("🧬 Decoding %s source messages (schema registry: %s)", cfg.SourceFormat, cfg.SchemaRegistryURL))
+	}
+
+	if cfg.CaptureJoinWindow > 0 {
+		service.joiner = newCaptureJoiner()
+		log.Info(fmt.Sprintf("🧩 Joining partial captures on %s within %v", cfg.CaptureIDField, cfg.CaptureJoinWindow))
+	}
+
+	service.skip = newSkipRules(cfg.SkipPaths, cfg.SkipStatusCodes, cfg.SkipMethods)
+	if service.skip != nil {
+		log.Info(fmt.Sprintf("🙈 Skipping paths %v, status codes %v, methods %v", cfg.SkipPaths, cfg.SkipStatusCodes, cfg.SkipMethods))
+	}
+
+	if cfg.PerClientRate > 0 {
+		service.clientLimits = newClientLimiters(cfg.PerClientRate)
+		log.Info(fmt.Sprintf("🚦 Per-client rate limited to %.2f messages/sec", cfg.PerClientRate))
+	}
+
+	stopTracing, err := tracing.Setup(context.Background(), cfg.OTelEndpoint, cfg.OTelServiceName)
+	if err != nil {
+		log.Error(fmt.Sprintf("❌ Failed to set up tracing: %v", err))
+		return nil, err
+	}
+	service.stopTracing = stopTracing
+	if cfg.OTelEndpoint != "" {
+		log.Info(fmt.Sprintf("🔭 Exporting traces to %s", cfg.OTelEndpoint))
+	}
+
+	if cfg.AlertWebhookURL != "" {
+		service.alerts = alert.NewWebhook(cfg.AlertWebhookURL)
+	}
+
+	if cfg.StatsDAddr != "" {
+		statsdClient, err := statsd.New(cfg.StatsDAddr, cfg.StatsDPrefix)
+		if err != nil {
+			log.Error(fmt.Sprintf("❌ Failed to create StatsD client: %v", err))
+			return nil, err
+		}
+		service.statsd = statsdClient
+		service.statsdLast = make(map[string]int64)
+		log.Info(fmt.Sprintf("📈 Pushing metrics to StatsD at %s every %v", cfg.StatsDAddr, cfg.StatsDInterval))
+	}
+
+	if cfg.MaxProduceRate > 0 {
+		burst := int(math.Ceil(cfg.MaxProduceRate))
+		service.limiter = rate.NewLimiter(rate.Limit(cfg.MaxProduceRate), burst)
+		log.Info(fmt.Sprintf("🚦 Produce rate limited to %.2f messages/sec", cfg.MaxProduceRate))
+	}
+
+	return service, nil
+}
+
 // Start begins processing messages
 func (s *TransformerService) Start(ctx context.Context) error {
-	// Wait additional time for broker metadata to be fully loaded
-	s.logger.Info("⏳ Waiting for broker metadata...")
-	time.Sleep(3 * time.Second)
+	if s.config.HealthPort > 0 {
+		if err := s.startHTTPServer(); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to start HTTP server: %v", err))
+			return err
+		}
+	}
+	if s.config.MetricsPort > 0 {
+		if err := s.startMetricsServer(); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to start metrics server: %v", err))
+			return err
+		}
+	}
+
+	// Let the HTTP endpoints warm up before consuming
+	if s.config.SubscribeDelay > 0 {
+		s.logger.Info(fmt.Sprintf("⏳ Delaying subscription by %v...", s.config.SubscribeDelay))
+		select {
+		case <-time.After(s.config.SubscribeDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 
-	err := s.consumer.SubscribeTopics([]string{s.config.SourceTopic}, nil)
+	// Delivery reports are handled off the processing path
+	s.deliveryWG.Add(2)
+	go s.handleDeliveries(s.producer)
+	go s.handleDeliveries(s.protoProducer)
+
+	err := s.consumer.SubscribeTopics(s.config.SourceTopics, s.rebalance)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Failed to subscribe: %v", err))
 		return err
 	}
 
-	s.logger.Info(fmt.Sprintf("✅ Subscribed to topic: %s", s.config.SourceTopic))
+	s.logger.Info(fmt.Sprintf("✅ Subscribed to topics: %s", strings.Join(s.config.SourceTopics, ", ")))
 
 	s.wg.Add(1)
 	go s.processMessages(ctx)
@@ -146,6 +338,27 @@ func (s *TransformerService) Start(ctx context.Context) error {
 	s.wg.Add(1)
 	go s.reportMetrics(ctx)
 
+	if s.config.LagCheckInterval > 0 {
+		s.wg.Add(1)
+		go s.monitorLag(ctx)
+	}
+
+	if s.config.MaxHeapMB > 0 {
+		s.wg.Add(1)
+		go s.monitorHeap(ctx)
+	}
+
+	if s.config.StuckWarnFraction > 0 {
+		s.wg.Add(1)
+		go s.monitorStuck(ctx)
+	}
+
+	if s.config.DownstreamHealthURL != "" {
+		s.wg.Add(1)
+		go s.monitorDownstream(ctx)
+	}
+
+	s.state.Store(stateRunning)
 	s.logger.Info("🚀 Message processing started")
 	return nil
 }
@@ -154,10 +367,14 @@ func (s *TransformerService) Start(ctx context.Context) error {
 func (s *TransformerService) processMessages(ctx context.Context) {
 	defer s.wg.Done()
 
-	semaphore := make(chan bool, s.config.MaxConcurrentMessages)
 	commitTicker := time.NewTicker(s.config.CommitInterval)
 	defer commitTicker.Stop()
 
+	var duplicates *duplicateDetector
+	if s.config.DetectDuplicates {
+		duplicates = newDuplicateDetector()
+	}
+
 	for {
 		select {
 		case <-s.stopChan:
@@ -169,10 +386,7 @@ func (s *TransformerService) processMessages(ctx context.Context) {
 			return
 
 		case <-commitTicker.C:
-			_, err := s.consumer.Commit()
-			if err != nil && err.(kafkalib.Error).Code() != kafkalib.ErrNoOffset {
-				s.logger.Warn(fmt.Sprintf("Commit failed: %v", err))
-			}
+			s.commitAssigned()
 
 		default:
 			msg, err := s.consumer.ReadMessage(s.config.ProcessingTimeout)
@@ -180,118 +394,408 @@ func (s *TransformerService) processMessages(ctx context.Context) {
 				kafkaErr, ok := err.(kafkalib.Error)
 				if ok && kafkaErr.Code() == kafkalib.ErrTimedOut {
 					// Timeout is normal, just continue
+					s.consumerErrorsSince.Store(0)
 					continue
 				}
+				if ok && kafkaErr.IsFatal() {
+					// Fatal errors leave the consumer unusable, so stop instead of spinning
+					s.logger.Error(fmt.Sprintf("💥 Fatal consumer error, stopping message processing: %v", err))
+					s.reportFatal(err)
+					return
+				}
 				s.logger.Error(fmt.Sprintf("Consumer error: %v (type: %T)", err, err))
+				s.consumerErrorsSince.CompareAndSwap(0, time.Now().UnixNano())
 				continue
 			}
+			s.consumerErrorsSince.Store(0)
 
 			// Message received!
-			s.logger.Info(fmt.Sprintf("📨 Message received from topic %s (size: %d bytes)", s.config.SourceTopic, len(msg.Value)))
+			s.logVerbose(fmt.Sprintf("📨 Message received from topic %s (size: %d bytes)", *msg.TopicPartition.Topic, len(msg.Value)))
 			s.logger.Debug(fmt.Sprintf("Message content: %s", string(msg.Value)))
 
-			semaphore <- true
+			// Track the message from the moment it is read so nothing past it is
+			// committed before it finishes, even if its partition is rewound
+			ticket := s.offsets.begin(msg.TopicPartition)
+			msgCtx := withOffsetTicket(ctx, ticket)
+
+			// Over MAX_HEAP_MB, let in-flight work drain before taking on more
+			if !s.waitForHeapHeadroom(ctx) {
+				return
+			}
+
+			// Weighted topics run in their own share of workers
+			pool := s.topicPools[*msg.TopicPartition.Topic]
+			if pool != nil && !s.admit(pool, msg) {
+				ticket.park()
+				s.logger.Debug(fmt.Sprintf("Topic %s saturated, pausing partition %d", *msg.TopicPartition.Topic, msg.TopicPartition.Partition))
+				continue
+			}
+
+			// Observability only: likely duplicates are still processed
+			if duplicates != nil && duplicates.observe(*msg.TopicPartition.Topic, msg.TopicPartition.Partition, msg.Value) {
+				s.logger.Debug(fmt.Sprintf("Likely duplicate of the previous message (partition: %d, offset: %v)", msg.TopicPartition.Partition, msg.TopicPartition.Offset))
+				s.metrics.IncrementLikelyDuplicate()
+			}
+
+			if pool != nil {
+				s.wg.Add(1)
+				go func(kafkaMsg *kafkalib.Message) {
+					defer s.wg.Done()
+					defer s.release(pool)
+					defer ticket.release()
+					s.processCapture(msgCtx, kafkaMsg)
+				}(msg)
+				continue
+			}
+
+			s.semaphore <- true
 			s.wg.Add(1)
 
 			go func(kafkaMsg *kafkalib.Message) {
 				defer s.wg.Done()
-				defer func() { <-semaphore }()
-				s.processMessage(kafkaMsg)
+				defer func() { <-s.semaphore }()
+				defer ticket.release()
+				s.processCapture(msgCtx, kafkaMsg)
 			}(msg)
 		}
 	}
 }
 
-// processMessage transforms a single message
-func (s *TransformerService) processMessage(kafkaMsg *kafkalib.Message) {
+// Fatal returns a channel that receives an error when the service hits an
+// unrecoverable failure and must be shut down
+func (s *TransformerService) Fatal() <-chan error {
+	return s.fatalChan
+}
+
+// reportFatal records an unrecoverable error without blocking
+func (s *TransformerService) reportFatal(err error) {
+	select {
+	case s.fatalChan <- err:
+	default:
+	}
+}
+
+// processMessage transforms a single message within the configured
+// per-message deadline. The message is handled on the worker's goroutine so
+// it never outlives its slot; handleMessage stops short of publishing once
+// the deadline has passed.
+func (s *TransformerService) processMessage(ctx context.Context, kafkaMsg *kafkalib.Message) {
+	if s.config.MessageDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.MessageDeadline)
+		defer cancel()
+	}
+	s.handleMessage(ctx, kafkaMsg)
+}
+
+// abandoned reports whether a message ran past its deadline, counting and
+// dead-lettering it once when it did. Callers return without publishing.
+func (s *TransformerService) abandoned(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message) bool {
+	if ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	s.metrics.IncrementDeadlineExceeded()
+	s.metrics.IncrementFailedFor(clientID)
+	s.logger.Warn(fmt.Sprintf("⏰ Message exceeded %v deadline, abandoning (topic: %s, partition: %d, offset: %v)",
+		s.config.MessageDeadline, *kafkaMsg.TopicPartition.Topic, kafkaMsg.TopicPartition.Partition, kafkaMsg.TopicPartition.Offset))
+	s.deadLetter(ctx, clientID, kafkaMsg, dlqStageDeadline, ctx.Err())
+	return true
+}
+
+// handleMessage runs the transform and publish steps for a single message
+func (s *TransformerService) handleMessage(ctx context.Context, kafkaMsg *kafkalib.Message) {
 	startTime := time.Now()
 
-	clientID := s.config.ClientID
-	s.logger.Info(fmt.Sprintf("🔄 Processing message for client: %s", clientID))
+	// Continue the upstream trace, if any, across consume, transform and produce
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, kafkaMsg.Headers), "process message",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.source.name", *kafkaMsg.TopicPartition.Topic),
+			attribute.Int("messaging.kafka.partition", int(kafkaMsg.TopicPartition.Partition)),
+			attribute.Int64("messaging.kafka.offset", int64(kafkaMsg.TopicPartition.Offset)),
+		))
+	defer span.End()
+
+	clientID := s.resolveClientID(kafkaMsg)
+	s.logVerbose(fmt.Sprintf("🔄 Processing message for client: %s", clientID))
 
-	s.metrics.IncrementReceived()
+	s.metrics.IncrementReceivedFor(clientID)
+
+	if s.clientLimits != nil && !s.clientLimits.allow(clientID) {
+		s.logger.Debug(fmt.Sprintf("Dropping message over rate limit (client: %s)", clientID))
+		s.metrics.IncrementRateLimited(clientID)
+		s.auditDropped(ctx, clientID, kafkaMsg, dropReasonRateLimited)
+		return
+	}
+
+	if s.quarantined(clientID) {
+		s.metrics.IncrementQuarantined()
+		if err := s.publishQuarantined(ctx, clientID, kafkaMsg); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to quarantine message: %v", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "publish failed")
+			s.metrics.IncrementFailedFor(clientID)
+		}
+		return
+	}
+
+	// Compacted topics delete keys with nil-value tombstones, which are not traffic
+	if kafkaMsg.Value == nil {
+		if err := s.handleTombstone(ctx, clientID, kafkaMsg); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to forward tombstone: %v", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "publish failed")
+			s.metrics.IncrementFailedFor(clientID)
+		}
+		return
+	}
+
+	if s.sampledOut() {
+		s.metrics.IncrementSampledOut()
+		if err := s.publishSampledOut(ctx, clientID, kafkaMsg); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to publish sampled-out message: %v", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "publish failed")
+			s.metrics.IncrementFailedFor(clientID)
+		}
+		return
+	}
+
+	// Passthrough mode mirrors the original bytes without transforming them
+	if s.config.Passthrough {
+		err := s.withRetries(ctx, "Publish", func() error {
+			return s.publishMessage(ctx, clientID, kafkaMsg, map[string]interface{}{}, kafkaMsg.Value)
+		})
+		if err != nil && s.abandoned(ctx, clientID, kafkaMsg) {
+			return
+		}
+		if err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to publish: %v", err))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "publish failed")
+			s.metrics.IncrementFailedFor(clientID)
+			s.deadLetter(ctx, clientID, kafkaMsg, dlqStagePublish, err)
+			return
+		}
+		s.metrics.IncrementPublishedFor(clientID)
+		s.metrics.AddProcessingTime(time.Since(startTime))
+		s.logMessageSummary("published", clientID, nil, startTime)
+		return
+	}
 
 	// Transform message
 	s.logger.Debug(fmt.Sprintf("Raw message: %s", string(kafkaMsg.Value)))
-	transformed, err := transformer.TransformMessage(kafkaMsg.Value, clientID)
+	if s.config.OutputFormat == "proto" {
+		s.handleProtoMessage(ctx, span, startTime, clientID, kafkaMsg)
+		return
+	}
+
+	_, transformSpan := tracing.Tracer().Start(ctx, "transform")
+	transformed, err := transformer.TransformMessage(kafkaMsg.Value, clientID, s.transformOpts)
+	transformSpan.End()
 	if err != nil {
-		s.logger.Error(fmt.Sprintf("❌ Transformation failed: %v", err))
-		s.metrics.IncrementFailed()
+		s.recordTransformError(span, clientID, kafkaMsg, err)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStageTransform, err)
+		s.logMessageSummary("failed", clientID, nil, startTime)
 		return
 	}
+	// Releasing on return is safe only because produce() copies the message
+	// value: the queued message and any retries never alias the map or the
+	// pooled buffer it is marshaled into
+	defer transformer.ReleaseOutput(transformed)
 
-	s.logger.Info("✅ Message transformed successfully")
-	s.metrics.IncrementTransformed()
+	s.logVerbose("✅ Message transformed successfully")
+	s.metrics.IncrementTransformedFor(clientID)
 
-	// Marshal to JSON
-	transformedJSON, err := json.Marshal(transformed)
+	if s.config.FieldMissMetrics {
+		s.countFieldMisses(transformed)
+	}
+
+	// Carry the broker-assigned time alongside the capture time
+	if s.config.EmitKafkaTimestamp && kafkaMsg.TimestampType != kafkalib.TimestampNotAvailable {
+		transformed["kafkaTimestamp"] = kafkaMsg.Timestamp.UnixMilli()
+	}
+
+	if s.config.DropPrivateIPs {
+		if ip, _ := transformed["ip"].(string); isPrivateIP(ip) {
+			s.logger.Debug(fmt.Sprintf("Skipping message from private IP %s", ip))
+			s.metrics.IncrementSkippedPrivateIP()
+			s.auditDropped(ctx, clientID, kafkaMsg, dropReasonPrivateIP)
+			return
+		}
+	}
+
+	if s.skip != nil && s.skip.matches(transformed) {
+		s.logger.Debug(fmt.Sprintf("Skipping %v %v (status %v) by skip rules", transformed["method"], transformed["path"], transformed["statusCode"]))
+		s.metrics.IncrementSkipped()
+		s.auditDropped(ctx, clientID, kafkaMsg, dropReasonSkipped)
+		return
+	}
+
+	// Stop before publishing if the message ran past its deadline or the
+	// service is stopping
+	if s.abandoned(ctx, clientID, kafkaMsg) || ctx.Err() != nil {
+		return
+	}
+
+	// Marshal to JSON, wrapped in the envelope when configured
+	var value interface{} = transformed
+	if s.config.Envelope {
+		value = s.wrapEnvelope(clientID, kafkaMsg, transformed)
+	}
+	buf, transformedJSON, err := marshalPooled(value)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Failed to marshal: %v", err))
-		s.metrics.IncrementFailed()
+		s.metrics.IncrementFailedFor(clientID)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStageMarshal, err)
 		return
 	}
+	defer releaseBuffer(buf)
 
 	// Publish to first topic (JSON format)
-	err = s.publishMessage(clientID, transformedJSON)
+	err = s.withRetries(ctx, "Publish", func() error {
+		return s.publishMessage(ctx, clientID, kafkaMsg, transformed, transformedJSON)
+	})
+	if err != nil && s.abandoned(ctx, clientID, kafkaMsg) {
+		return
+	}
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Failed to publish: %v", err))
-		s.metrics.IncrementFailed()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
+		s.metrics.IncrementFailedFor(clientID)
+		s.deadLetter(ctx, clientID, kafkaMsg, dlqStagePublish, err)
 		return
 	}
 
 	// Transform to proto and publish to second topic
-	protoPayload, err := transformer.TransformToProtoFromFlat(transformed)
+	protoPayload, err := transformer.TransformToProtoFromFlat(transformed, s.transformOpts)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("Failed to transform to proto: %v", err))
 		// Continue even if proto fails - don't fail the whole message
 	} else {
-		err = s.publishProtoMessage(clientID, protoPayload)
+		err = s.publishProtoMessage(ctx, clientID, protoPayload)
 		if err != nil {
 			s.logger.Error(fmt.Sprintf("Failed to publish proto: %v", err))
 			// Continue even if proto publish fails
 		}
 	}
 
-	s.metrics.IncrementPublished()
+	s.publishOutputs(ctx, clientID, kafkaMsg, transformed, transformedJSON)
+
+	s.metrics.IncrementPublishedFor(clientID)
 	s.metrics.AddProcessingTime(time.Since(startTime))
+	s.logMessageSummary("published", clientID, transformed, startTime)
 
 	s.logger.Debug(fmt.Sprintf("✅ Message processed in %v (client: %s)", time.Since(startTime), clientID))
 }
 
-// publishMessage sends transformed message to destination (non-blocking)
-func (s *TransformerService) publishMessage(clientID string, data []byte) error {
-	err := s.producer.Produce(
+// recordTransformError logs and counts a failed transformation
+func (s *TransformerService) recordTransformError(span trace.Span, clientID string, kafkaMsg *kafkalib.Message, err error) {
+	var shapeErr *transformer.ShapeError
+	if errors.Is(err, transformer.ErrJSONTooDeep) {
+		s.logger.Error(fmt.Sprintf("❌ Rejected overly nested message (topic: %s, partition: %d, offset: %v): %v",
+			*kafkaMsg.TopicPartition.Topic, kafkaMsg.TopicPartition.Partition, kafkaMsg.TopicPartition.Offset, err))
+		s.metrics.IncrementRejectedDeepJSON()
+	} else if errors.As(err, &shapeErr) {
+		s.logger.Error(fmt.Sprintf("❌ Rejected malformed message (topic: %s, partition: %d, offset: %v): %v",
+			*kafkaMsg.TopicPartition.Topic, kafkaMsg.TopicPartition.Partition, kafkaMsg.TopicPartition.Offset, err))
+	} else {
+		s.logger.Error(fmt.Sprintf("❌ Transformation failed: %v", err))
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "transformation failed")
+	s.metrics.IncrementFailedFor(clientID)
+}
+
+// publishMessage enqueues a transformed message for its destination, or writes
+// it to stdout when OUTPUT_SINK=stdout. A
+// TENANT_HEADER topic overrides STATUS_ROUTING. Messages that later fail
+// delivery are dead-lettered from the source message.
+func (s *TransformerService) publishMessage(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message, record map[string]interface{}, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.config.OutputSink == config.OutputSinkStdout {
+		if err := writeStdout(data); err != nil {
+			return fmt.Errorf("failed to write record to stdout: %w", err)
+		}
+		return nil
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("produce rate limiter: %w", err)
+		}
+	}
+
+	topic := s.tenantTopic(kafkaMsg)
+	if topic == "" {
+		topic = s.destinationTopic(record)
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "produce "+topic, trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	key := s.messageKey(clientID, record)
+	if s.config.PreserveSourceKey && len(kafkaMsg.Key) > 0 {
+		key = string(kafkaMsg.Key)
+	}
+
+	headers := []kafkalib.Header{
+		{Key: "client_id", Value: []byte(clientID)},
+		{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
+	}
+	headers = withTruncationHeader(headers, record)
+	if s.config.PreserveSourceHeaders {
+		headers = withSourceHeaders(headers, kafkaMsg)
+	}
+	// Continues the source trace, replacing a copied traceparent with our span
+	tracing.Inject(ctx, &headers)
+
+	err := s.produce(ctx, s.producer,
 		&kafkalib.Message{
 			TopicPartition: kafkalib.TopicPartition{
-				Topic:     &s.config.DestinationTopic,
-				Partition: kafkalib.PartitionAny,
-			},
-			Key:   []byte(clientID),
-			Value: data,
-			Headers: []kafkalib.Header{
-				{Key: "client_id", Value: []byte(clientID)},
-				{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
+				Topic:     &topic,
+				Partition: s.messagePartition(key),
 			},
+			Key:     []byte(key),
+			Value:   data,
+			Headers: headers,
+		},
+		func(err error) {
+			s.deadLetter(ctx, clientID, kafkaMsg, dlqStagePublish, err)
 		},
-		nil, // No delivery callback - non-blocking
 	)
-
 	if err != nil {
-		return fmt.Errorf("failed to produce message to %s: %w", s.config.DestinationTopic, err)
+		return fmt.Errorf("failed to produce message to %s: %w", topic, err)
 	}
 
-	// Flush to ensure message is queued
-	remaining := s.producer.Flush(5000) // 5 second timeout
-	if remaining > 0 {
-		s.logger.Error(fmt.Sprintf("⚠️  Warning: %d messages remained in queue after flush", remaining))
+	s.logVerbose(fmt.Sprintf("📤 Published to %s (client: %s)", topic, clientID))
+	return nil
+}
+
+// destinationTopic picks the output topic for a record, honoring STATUS_ROUTING
+func (s *TransformerService) destinationTopic(record map[string]interface{}) string {
+	if len(s.config.StatusRouting) == 0 {
+		return s.config.DestinationTopic
 	}
 
-	s.logger.Info(fmt.Sprintf("📤 Published to %s (client: %s)", s.config.DestinationTopic, clientID))
-	return nil
+	statusCode, _ := record["statusCode"].(string)
+	if topic, ok := s.config.StatusRouting[statusCode]; ok {
+		return topic
+	}
+	if len(statusCode) == 3 {
+		if topic, ok := s.config.StatusRouting[statusCode[:1]+"xx"]; ok {
+			return topic
+		}
+	}
+	return s.config.DestinationTopic
 }
 
 // publishProtoMessage sends protobuf message to akto.api.logs2 topic
-func (s *TransformerService) publishProtoMessage(clientID string, protoMsg interface{}) error {
+func (s *TransformerService) publishProtoMessage(ctx context.Context, clientID string, protoMsg interface{}) error {
+	if s.config.OutputSink == config.OutputSinkStdout {
+		return nil // The stdout sink replaces the Kafka outputs
+	}
 	// Import proto package is already done at the top
 	protoBytes, err := proto.Marshal(protoMsg.(proto.Message))
 	if err != nil {
@@ -299,64 +803,127 @@ func (s *TransformerService) publishProtoMessage(clientID string, protoMsg inter
 	}
 
 	protoTopic := "akto.api.logs2"
-	err = s.protoProducer.Produce(
+	headers := []kafkalib.Header{
+		{Key: "client_id", Value: []byte(clientID)},
+		{Key: "content_type", Value: []byte("application/x-protobuf")},
+		{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
+	}
+	tracing.Inject(ctx, &headers)
+
+	err = s.produce(ctx, s.protoProducer,
 		&kafkalib.Message{
 			TopicPartition: kafkalib.TopicPartition{
 				Topic:     &protoTopic,
 				Partition: kafkalib.PartitionAny,
 			},
-			Key:   []byte(clientID),
-			Value: protoBytes,
-			Headers: []kafkalib.Header{
-				{Key: "client_id", Value: []byte(clientID)},
-				{Key: "content_type", Value: []byte("application/x-protobuf")},
-				{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
-			},
+			Key:     []byte(clientID),
+			Value:   protoBytes,
+			Headers: headers,
 		},
-		nil, // No delivery callback - non-blocking
+		nil,
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to produce proto message to %s: %w", protoTopic, err)
 	}
 
-	// Flush to ensure message is queued
-	remaining := s.protoProducer.Flush(5000) // 5 second timeout
-	if remaining > 0 {
-		s.logger.Warn(fmt.Sprintf("⚠️  Warning: %d proto messages remained in queue after flush", remaining))
+	s.logVerbose(fmt.Sprintf("📤 Published proto to %s (client: %s, size: %d bytes)", protoTopic, clientID, len(protoBytes)))
+	return nil
+}
+
+// expectedFields are the flat output fields whose defaults signal that the
+// transformer found nothing to extract
+var expectedFields = map[string]string{
+	"method":     "",
+	"path":       "",
+	"statusCode": "0",
+	"ip":         "",
+}
+
+// countFieldMisses counts expected fields a transformed record left at their
+// empty/default value, surfacing upstream format drift
+func (s *TransformerService) countFieldMisses(record map[string]interface{}) {
+	for field, missing := range expectedFields {
+		if value, _ := record[field].(string); value == missing {
+			s.metrics.IncrementFieldMiss(field)
+		}
 	}
+}
 
-	s.logger.Info(fmt.Sprintf("📤 Published proto to %s (client: %s, size: %d bytes)", protoTopic, clientID, len(protoBytes)))
-	return nil
+// defaultClientID is returned by extractClientID when the configured source
+// does not carry a client ID
+const defaultClientID = "default-client"
+
+// resolveClientID returns the message's client ID, falling back to the
+// configured CLIENT_ID when the message does not carry one
+func (s *TransformerService) resolveClientID(kafkaMsg *kafkalib.Message) string {
+	clientID := s.extractClientID(kafkaMsg)
+	if clientID == defaultClientID {
+		return s.config.ClientID
+	}
+	return clientID
 }
 
-// extractClientID extracts client ID from message
+// extractClientID extracts client ID from message according to CLIENT_ID_SOURCE
 func (s *TransformerService) extractClientID(kafkaMsg *kafkalib.Message) string {
-	// Try headers
-	for _, header := range kafkaMsg.Headers {
-		if header.Key == "client_id" {
-			return string(header.Value)
-		}
+	switch s.config.ClientIDSource {
+	case "config":
+		return s.config.ClientID
+	case "header":
+		return s.headerClientID(kafkaMsg)
+	case "payload":
+		return payloadClientID(kafkaMsg)
 	}
 
-	// Try payload
-	var data map[string]interface{}
-	if err := json.Unmarshal(kafkaMsg.Value, &data); err == nil {
-		if clientID, ok := data["akto_account_id"].(string); ok && clientID != "" {
+	// auto: try headers, then payload
+	if clientID := s.headerClientID(kafkaMsg); clientID != defaultClientID {
+		return clientID
+	}
+	return payloadClientID(kafkaMsg)
+}
+
+// headerClientID reads the client_id header
+func (s *TransformerService) headerClientID(kafkaMsg *kafkalib.Message) string {
+	if clientID, ok := s.kafkaHeaderValue(kafkaMsg, "client_id"); ok && clientID != "" {
+		return clientID
+	}
+	return defaultClientID
+}
+
+// payloadClientID reads the akto_account_id field of the JSON payload. It
+// decodes into a one-field struct so the rest of the payload, which the
+// transformer decodes again, is scanned but never allocated.
+func payloadClientID(kafkaMsg *kafkalib.Message) string {
+	var payload struct {
+		AktoAccountID interface{} `json:"akto_account_id"`
+	}
+	if err := json.Unmarshal(kafkaMsg.Value, &payload); err == nil {
+		if clientID, ok := payload.AktoAccountID.(string); ok && clientID != "" {
 			return clientID
 		}
 	}
-
-	return "default-client"
+	return defaultClientID
 }
 
-// reportMetrics logs metrics periodically
+// reportMetrics logs metrics periodically and on SIGUSR1
 func (s *TransformerService) reportMetrics(ctx context.Context) {
 	defer s.wg.Done()
 
 	ticker := time.NewTicker(60 * time.Minute)
 	defer ticker.Stop()
 
+	// SIGUSR1 prints the metrics on demand; Stop unregisters it when this returns
+	dumpChan := make(chan os.Signal, 1)
+	signal.Notify(dumpChan, syscall.SIGUSR1)
+	defer signal.Stop(dumpChan)
+
+	// A nil channel never fires, so StatsD pushes only happen when configured
+	var statsdTick <-chan time.Time
+	if s.statsd != nil {
+		statsdTicker := time.NewTicker(s.config.StatsDInterval)
+		defer statsdTicker.Stop()
+		statsdTick = statsdTicker.C
+	}
+
 	for {
 		select {
 		case <-s.stopChan:
@@ -365,10 +932,18 @@ func (s *TransformerService) reportMetrics(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.printMetrics()
+		case <-dumpChan:
+			s.logger.Info("📊 SIGUSR1 received, printing metrics")
+			s.printMetrics()
+		case <-statsdTick:
+			s.pushStatsD()
 		}
 	}
 }
 
+// topClientsReported is how many of the busiest clients printMetrics lists
+const topClientsReported = 5
+
 // printMetrics logs current metrics
 func (s *TransformerService) printMetrics() {
 	snapshot := s.metrics.GetSnapshot()
@@ -378,15 +953,42 @@ func (s *TransformerService) printMetrics() {
 	s.logger.Info(fmt.Sprintf("   Transformed: %d messages", snapshot["transformed"].(int64)))
 	s.logger.Info(fmt.Sprintf("   Published:   %d messages", snapshot["published"].(int64)))
 	s.logger.Info(fmt.Sprintf("   Failed:      %d messages", snapshot["failed"].(int64)))
+	for topic, count := range snapshot["output_failed_by_topic"].(map[string]int64) {
+		s.logger.Info(fmt.Sprintf("      output %s: %d failed", topic, count))
+	}
+	byClient := snapshot["by_client"].(map[string]metrics.ClientCounts)
+	for _, clientID := range metrics.TopClients(byClient, topClientsReported) {
+		counts := byClient[clientID]
+		s.logger.Info(fmt.Sprintf("      %s: %d received, %d published, %d failed", clientID, counts.Received, counts.Published, counts.Failed))
+	}
+	s.logger.Info(fmt.Sprintf("   Lag:         %d messages", snapshot["consumer_lag"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Deadline:    %d messages exceeded", snapshot["deadline_exceeded"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Private IP:  %d messages skipped", snapshot["skipped_private_ip"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Skip Rules:  %d messages skipped", snapshot["skipped"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Rate Limit:  %d messages dropped", snapshot["rate_limited"].(int64)))
+	for clientID, count := range snapshot["rate_limited_by_client"].(map[string]int64) {
+		s.logger.Info(fmt.Sprintf("      %s: %d", clientID, count))
+	}
+	s.logger.Info(fmt.Sprintf("   Duplicates:  %d likely producer retries", snapshot["likely_duplicate"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Deep JSON:   %d messages rejected", snapshot["rejected_deep_json"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Sampled Out: %d messages", snapshot["sampled_out"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Quarantined: %d messages", snapshot["quarantined"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Joined:      %d partial captures", snapshot["captures_joined"].(int64)))
+	s.logger.Info(fmt.Sprintf("   Tombstones:  %d records", snapshot["tombstones"].(int64)))
+	for field, count := range snapshot["field_misses"].(map[string]int64) {
+		s.logger.Info(fmt.Sprintf("   Missing %s: %d messages", field, count))
+	}
 	s.logger.Info(fmt.Sprintf("   Avg Time:    %v", snapshot["avg_time"].(time.Duration)))
 	s.logger.Info("📊 ========================")
 }
 
-// Stop gracefully shuts down the service
-func (s *TransformerService) Stop(ctx context.Context) error {
-	s.logger.Info("Stopping service...")
+// Stop gracefully shuts down the service, recording why it stopped
+func (s *TransformerService) Stop(ctx context.Context, reason string) error {
+	s.logger.Info(fmt.Sprintf("Stopping service (%s)...", reason))
+	s.state.Store(stateStopping)
 
 	close(s.stopChan)
+	s.stopJoiner()
 
 	done := make(chan bool)
 	go func() {
@@ -401,11 +1003,41 @@ func (s *TransformerService) Stop(ctx context.Context) error {
 		s.logger.Warn("⚠️ Shutdown timeout exceeded")
 	}
 
-	s.consumer.Close()
+	s.stopHTTPServer(ctx)
+	s.stopMetricsServer(ctx)
+
+	// Deliver what is still queued within the shutdown budget
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	s.drainRetries(deadline)
+	s.flushProducers(deadline)
+
 	s.producer.Close()
 	s.protoProducer.Close()
+	s.deliveryWG.Wait()
+
+	// Every delivery report is handled, so commit everything that finished
+	s.commitAssigned()
+	s.consumer.Close()
+	if s.statsd != nil {
+		s.pushStatsD()
+		s.statsd.Close()
+	}
+	if err := s.stopTracing(ctx); err != nil {
+		s.logger.Warn(fmt.Sprintf("Tracing shutdown: %v", err))
+	}
 
 	s.logger.Info("✅ Service stopped")
 	s.printMetrics()
+
+	if s.config.ShutdownReportFile != "" {
+		if err := s.writeShutdownReport(reason); err != nil {
+			s.logger.Warn(fmt.Sprintf("Failed to write shutdown report: %v", err))
+		} else {
+			s.logger.Info(fmt.Sprintf("📝 Shutdown report written to %s", s.config.ShutdownReportFile))
+		}
+	}
 	return nil
 }
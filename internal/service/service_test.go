@@ -0,0 +1,442 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/config"
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+
+	"google.golang.org/protobuf/proto"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestDestinationTopic(t *testing.T) {
+	s := &TransformerService{config: &config.Config{
+		DestinationTopic: "akto.api.logs",
+		StatusRouting: map[string]string{
+			"5xx": "akto.api.errors",
+			"404": "akto.api.not-found",
+		},
+	}}
+
+	tests := []struct {
+		statusCode string
+		want       string
+	}{
+		{"500", "akto.api.errors"},
+		{"503", "akto.api.errors"},
+		{"404", "akto.api.not-found"},
+		{"200", "akto.api.logs"},
+		{"", "akto.api.logs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.statusCode, func(t *testing.T) {
+			got := s.destinationTopic(map[string]interface{}{"statusCode": tt.statusCode})
+			if got != tt.want {
+				t.Errorf("destinationTopic(%q) = %q, want %q", tt.statusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestinationTopicWithoutRouting(t *testing.T) {
+	s := &TransformerService{config: &config.Config{DestinationTopic: "akto.api.logs"}}
+	if got := s.destinationTopic(map[string]interface{}{"statusCode": "500"}); got != "akto.api.logs" {
+		t.Errorf("destinationTopic = %q, want akto.api.logs", got)
+	}
+}
+
+func TestFatalConsumerError(t *testing.T) {
+	s := newTestService(t, testConfig(t, nil))
+	s.source.errs = []error{
+		kafkalib.NewError(kafkalib.ErrTransport, "broker down", false),
+		kafkalib.NewError(kafkalib.ErrFatal, "fenced", true),
+	}
+	s.start(t)
+
+	select {
+	case err := <-s.Fatal():
+		var kafkaErr kafkalib.Error
+		if !errors.As(err, &kafkaErr) || !kafkaErr.IsFatal() {
+			t.Errorf("Fatal() = %v, want the fatal consumer error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fatal consumer error was not reported")
+	}
+
+	// The read loop has stopped, so queued messages stay unread
+	s.source.send(sourceMessage(sampleCapture, 0))
+	time.Sleep(100 * time.Millisecond)
+	if got := s.metrics.GetSnapshot()["received"].(int64); got != 0 {
+		t.Errorf("received = %d after the fatal error, want 0", got)
+	}
+}
+
+func TestTransientConsumerErrorKeepsReading(t *testing.T) {
+	s := newTestService(t, testConfig(t, nil))
+	s.source.errs = []error{kafkalib.NewError(kafkalib.ErrTransport, "broker down", false)}
+	s.source.send(sourceMessage(sampleCapture, 0))
+	s.start(t)
+
+	eventually(t, 5*time.Second, func() bool { return len(s.sink.messages("akto.api.logs")) == 1 },
+		"message after a transient error was not published")
+	select {
+	case err := <-s.Fatal():
+		t.Errorf("Fatal() = %v for a transient error", err)
+	default:
+	}
+}
+
+func TestMaxProduceRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        string
+		messages    int
+		minDuration time.Duration
+	}{
+		// A burst of 10 goes out at once, the next 5 at 10/s
+		{"throttled beyond the burst", "10", 15, 400 * time.Millisecond},
+		{"unthrottled within the burst", "10", 10, 0},
+		{"unlimited", "0", 15, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"MAX_PRODUCE_RATE": tt.rate}))
+			msg := sourceMessage(sampleCapture, 0)
+			start := time.Now()
+			for i := 0; i < tt.messages; i++ {
+				if err := s.publishMessage(context.Background(), "1000", msg, map[string]interface{}{}, msg.Value); err != nil {
+					t.Fatalf("publishMessage: %v", err)
+				}
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < tt.minDuration {
+				t.Errorf("published %d messages in %v, want at least %v", tt.messages, elapsed, tt.minDuration)
+			}
+			if tt.minDuration == 0 && elapsed > 200*time.Millisecond {
+				t.Errorf("published %d messages in %v, want no throttling", tt.messages, elapsed)
+			}
+			if got := len(s.sink.messages("akto.api.logs")); got != tt.messages {
+				t.Errorf("produced %d messages, want %d", got, tt.messages)
+			}
+		})
+	}
+}
+
+func TestMessageDeadline(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{
+		"MESSAGE_PROCESSING_DEADLINE": "100ms",
+		"MAX_RETRIES":                 "10",
+		"RETRY_BACKOFF":               "30ms",
+		"DLQ_TOPIC":                   "akto.api.dlq",
+	}))
+	// Publishing keeps failing and retrying, so it never finishes in time
+	s.sink.produceErrs = map[string]error{"akto.api.logs": kafkalib.NewError(kafkalib.ErrQueueFull, "queue full", false)}
+	s.source.send(sourceMessage(sampleCapture, 0))
+	s.start(t)
+
+	eventually(t, 5*time.Second, func() bool { return s.metrics.GetSnapshot()["deadline_exceeded"].(int64) == 1 },
+		"deadline was not recorded")
+	eventually(t, time.Second, func() bool { return s.inFlight() == 0 }, "worker slot not released")
+
+	// Give a straggling handler time to count or dead-letter again
+	time.Sleep(200 * time.Millisecond)
+	snapshot := s.metrics.GetSnapshot()
+	if snapshot["deadline_exceeded"].(int64) != 1 || snapshot["failed"].(int64) != 1 || snapshot["published"].(int64) != 0 {
+		t.Errorf("deadline_exceeded, failed, published = %d, %d, %d, want 1, 1, 0",
+			snapshot["deadline_exceeded"], snapshot["failed"], snapshot["published"])
+	}
+	dead := s.sink.messages("akto.api.dlq")
+	if len(dead) != 1 {
+		t.Fatalf("dead-lettered %d times, want once", len(dead))
+	}
+	if stage := headerValue(dead[0], "dlq_stage"); stage != dlqStageDeadline {
+		t.Errorf("dlq_stage = %q, want %q", stage, dlqStageDeadline)
+	}
+}
+
+func TestMessageWithinDeadline(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"MESSAGE_PROCESSING_DEADLINE": "5s"}))
+	s.source.send(sourceMessage(sampleCapture, 0))
+	s.start(t)
+
+	eventually(t, 5*time.Second, func() bool { return s.metrics.GetSnapshot()["published"].(int64) == 1 },
+		"message was not published")
+	if got := s.metrics.GetSnapshot()["deadline_exceeded"].(int64); got != 0 {
+		t.Errorf("deadline_exceeded = %d, want 0", got)
+	}
+}
+
+func TestSubscribeDelay(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+	}{
+		{0},
+		{150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.delay.String(), func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"SUBSCRIBE_DELAY": tt.delay.String()}))
+
+			// Observe the service while Start waits out the delay
+			begin := time.Now()
+			started := make(chan struct{})
+			go func() {
+				defer close(started)
+				s.start(t)
+			}()
+			if tt.delay > 0 {
+				time.Sleep(tt.delay / 3)
+				if got := s.source.subscribedTopics(); len(got) != 0 {
+					t.Errorf("subscribed to %v during SUBSCRIBE_DELAY", got)
+				}
+				if state := s.state.Load(); state != stateStarting {
+					t.Errorf("state = %v during SUBSCRIBE_DELAY, want %s", state, stateStarting)
+				}
+			}
+
+			<-started
+			if elapsed := time.Since(begin); elapsed < tt.delay {
+				t.Errorf("Start returned after %v, want at least %v", elapsed, tt.delay)
+			}
+			if got := s.source.subscribedTopics(); len(got) != 1 || got[0] != "client.traffic" {
+				t.Errorf("subscribed to %v, want [client.traffic]", got)
+			}
+			if state := s.state.Load(); state != stateRunning {
+				t.Errorf("state = %v after Start, want %s", state, stateRunning)
+			}
+		})
+	}
+}
+
+func TestSubscribeDelayCancelled(t *testing.T) {
+	s := newTestService(t, testConfig(t, map[string]string{"SUBSCRIBE_DELAY": "1h"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	if err := s.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Start = %v, want context.Canceled", err)
+	}
+	if got := s.source.subscribedTopics(); len(got) != 0 {
+		t.Errorf("subscribed to %v after cancelling the delay", got)
+	}
+}
+
+func TestSubscribeTopics(t *testing.T) {
+	tests := []struct {
+		name   string
+		topics string
+		want   []string
+	}{
+		{"single topic", "client.traffic", []string{"client.traffic"}},
+		{"topic list", "client.traffic, client.bulk,client.replay", []string{"client.traffic", "client.bulk", "client.replay"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"SOURCE_TOPIC": tt.topics}))
+			s.start(t)
+
+			if got := s.source.subscribedTopics(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("subscribed to %q, want %q", got, tt.want)
+			}
+
+			// Every subscribed topic drains into the destination
+			for i, topic := range tt.want {
+				msg := sourceMessage(sampleCapture, int64(i))
+				msg.TopicPartition.Topic = &topic
+				s.source.send(msg)
+			}
+			eventually(t, 2*time.Second, func() bool { return len(s.sink.messages("akto.api.logs")) == len(tt.want) },
+				"published %d messages, want %d", len(s.sink.messages("akto.api.logs")), len(tt.want))
+		})
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"capture", sampleCapture},
+		{"not JSON", "\x00\x01 raw bytes \xff"},
+		{"whitespace kept", "  {\"a\" : 1}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"PASSTHROUGH": "true"}))
+			s.handleMessage(context.Background(), sourceMessage(tt.value, 0))
+
+			produced := s.sink.messages("akto.api.logs")
+			if len(produced) != 1 {
+				t.Fatalf("produced %d messages, want 1", len(produced))
+			}
+			if !bytes.Equal(produced[0].Value, []byte(tt.value)) {
+				t.Errorf("value = %q, want the source bytes %q", produced[0].Value, tt.value)
+			}
+			if got := len(s.proto.produced); got != 0 {
+				t.Errorf("produced %d proto messages in passthrough mode", got)
+			}
+			if got := s.metrics.GetSnapshot()["transformed"].(int64); got != 0 {
+				t.Errorf("transformed = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestTransformModes(t *testing.T) {
+	// Extra whitespace shows whether the source bytes were re-encoded
+	const value = "  " + sampleCapture + "\n"
+	tests := []struct {
+		mode  string
+		check func(t *testing.T, published []byte)
+	}{
+		{"flat", func(t *testing.T, published []byte) {
+			var record map[string]interface{}
+			if err := json.Unmarshal(published, &record); err != nil || record["method"] != "GET" {
+				t.Errorf("published %q, want a flat record (%v)", published, err)
+			}
+		}},
+		{"proto", func(t *testing.T, published []byte) {
+			var payload trafficpb.HttpResponseParam
+			if err := proto.Unmarshal(published, &payload); err != nil || payload.Method != "GET" {
+				t.Errorf("published %q, want an HttpResponseParam (%v)", published, err)
+			}
+		}},
+		{"passthrough", func(t *testing.T, published []byte) {
+			if !bytes.Equal(published, []byte(value)) {
+				t.Errorf("published %q, want the source bytes %q", published, value)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"TRANSFORM_MODE": tt.mode}))
+			s.handleMessage(context.Background(), sourceMessage(value, 0))
+
+			published := s.sink.messages("akto.api.logs")
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(published))
+			}
+			tt.check(t, published[0].Value)
+		})
+	}
+}
+
+func TestMalformedMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"no request key", `{"info":{"ip":"203.0.113.7"}}`},
+		{"request is a string", `{"request":"GET /users","response":{"statusCode":200}}`},
+		{"response only without joining", `{"response":{"statusCode":200},"info":{"ip":"203.0.113.7"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"DLQ_TOPIC": "akto.api.dlq"}))
+			s.handleMessage(context.Background(), sourceMessage(tt.value, 0))
+
+			if got := len(s.sink.messages("akto.api.logs")); got != 0 {
+				t.Errorf("published %d malformed messages", got)
+			}
+			if got := s.metrics.GetSnapshot()["failed"].(int64); got != 1 {
+				t.Errorf("failed = %d, want 1", got)
+			}
+			dead := s.sink.messages("akto.api.dlq")
+			if len(dead) != 1 {
+				t.Fatalf("dead-lettered %d messages, want 1", len(dead))
+			}
+			if stage := headerValue(dead[0], "dlq_stage"); stage != dlqStageTransform {
+				t.Errorf("dlq_stage = %q, want %q", stage, dlqStageTransform)
+			}
+			if cause := headerValue(dead[0], "dlq_error"); !strings.Contains(cause, "invalid message shape") {
+				t.Errorf("dlq_error = %q, want the shape error", cause)
+			}
+		})
+	}
+}
+
+func TestEmitKafkaTimestamp(t *testing.T) {
+	timestamp := time.UnixMilli(1700000000123)
+	tests := []struct {
+		name          string
+		emit          string
+		timestampType kafkalib.TimestampType
+		want          interface{}
+	}{
+		{"create time", "true", kafkalib.TimestampCreateTime, float64(1700000000123)},
+		{"log append time", "true", kafkalib.TimestampLogAppendTime, float64(1700000000123)},
+		{"no broker timestamp", "true", kafkalib.TimestampNotAvailable, nil},
+		{"disabled", "false", kafkalib.TimestampCreateTime, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"EMIT_KAFKA_TIMESTAMP": tt.emit}))
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Timestamp, msg.TimestampType = timestamp, tt.timestampType
+			s.handleMessage(context.Background(), msg)
+
+			produced := s.sink.messages("akto.api.logs")
+			if len(produced) != 1 {
+				t.Fatalf("produced %d messages, want 1", len(produced))
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(produced[0].Value, &record); err != nil {
+				t.Fatalf("decode record: %v", err)
+			}
+			if got := record["kafkaTimestamp"]; got != tt.want {
+				t.Errorf("kafkaTimestamp = %v, want %v", got, tt.want)
+			}
+			if record["time"] == nil {
+				t.Error("the capture time is missing")
+			}
+		})
+	}
+}
+
+func TestFieldMissMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		capture string
+		want    map[string]int64
+	}{
+		{"complete capture", true, sampleCapture, map[string]int64{}},
+		{"missing method", true, strings.Replace(sampleCapture, `"method":"GET",`, "", 1), map[string]int64{"method": 1}},
+		{"missing url", true, strings.Replace(sampleCapture, `"url":"https://api.example.com/users?id=1",`, "", 1), map[string]int64{"path": 1}},
+		{"missing status and ip", true, strings.NewReplacer(`"statusCode":200,`, "", `"ip":"203.0.113.7",`, "").Replace(sampleCapture),
+			map[string]int64{"statusCode": 1, "ip": 1}},
+		{"disabled", false, strings.Replace(sampleCapture, `"method":"GET",`, "", 1), map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enabled := "false"
+			if tt.enabled {
+				enabled = "true"
+			}
+			s := newTestService(t, testConfig(t, map[string]string{"FIELD_MISS_METRICS": enabled}))
+			s.handleMessage(context.Background(), sourceMessage(tt.capture, 0))
+
+			if len(s.sink.messages("akto.api.logs")) != 1 {
+				t.Fatal("record with missing fields was not published")
+			}
+			misses := s.metrics.GetSnapshot()["field_misses"].(map[string]int64)
+			if len(misses) != len(tt.want) {
+				t.Errorf("field_misses = %v, want %v", misses, tt.want)
+			}
+			for field, count := range tt.want {
+				if misses[field] != count {
+					t.Errorf("field_misses[%s] = %d, want %d", field, misses[field], count)
+				}
+			}
+		})
+	}
+}
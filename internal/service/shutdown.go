@@ -0,0 +1,68 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// shutdownReport is the machine-readable summary written to SHUTDOWN_REPORT_FILE
+type shutdownReport struct {
+	Reason          string                 `json:"reason"`
+	StartedAt       string                 `json:"started_at"`
+	StoppedAt       string                 `json:"stopped_at"`
+	UptimeSeconds   float64                `json:"uptime_seconds"`
+	AvgProcessingMs float64                `json:"avg_processing_ms"`
+	Totals          map[string]interface{} `json:"totals"`
+}
+
+// writeShutdownReport writes the final metrics and stop reason as JSON,
+// replacing the file atomically so readers never see a partial report
+func (s *TransformerService) writeShutdownReport(reason string) error {
+	snapshot := s.metrics.GetSnapshot()
+	stoppedAt := time.Now()
+
+	// Durations are reported separately in readable units
+	totals := make(map[string]interface{}, len(snapshot))
+	for name, value := range snapshot {
+		if _, isDuration := value.(time.Duration); isDuration {
+			continue
+		}
+		totals[name] = value
+	}
+
+	report := shutdownReport{
+		Reason:          reason,
+		StartedAt:       s.startedAt.UTC().Format(time.RFC3339),
+		StoppedAt:       stoppedAt.UTC().Format(time.RFC3339),
+		UptimeSeconds:   stoppedAt.Sub(s.startedAt).Seconds(),
+		AvgProcessingMs: float64(snapshot["avg_time"].(time.Duration)) / float64(time.Millisecond),
+		Totals:          totals,
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal shutdown report: %w", err)
+	}
+
+	path := s.config.ShutdownReportFile
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".shutdown-report-*")
+	if err != nil {
+		return fmt.Errorf("failed to create shutdown report: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write shutdown report: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write shutdown report: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write shutdown report: %w", err)
+	}
+	return nil
+}
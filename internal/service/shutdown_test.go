@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdownReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		reason   string
+		want     map[string]float64
+	}{
+		{"one published", []string{sampleCapture}, "signal: terminated", map[string]float64{"received": 1, "published": 1, "failed": 0}},
+		{"one failed", []string{sampleCapture, "not json"}, "fatal consumer error", map[string]float64{"received": 2, "published": 1, "failed": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.json")
+			s := newTestService(t, testConfig(t, map[string]string{"SHUTDOWN_REPORT_FILE": path}))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := s.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			for i, value := range tt.messages {
+				s.source.send(sourceMessage(value, int64(i)))
+			}
+			eventually(t, 5*time.Second, func() bool {
+				return s.metrics.GetSnapshot()["received"].(int64) == int64(len(tt.messages))
+			}, "messages were not consumed")
+
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer stopCancel()
+			s.Stop(stopCtx, tt.reason)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read shutdown report: %v", err)
+			}
+			var report shutdownReport
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("decode shutdown report: %v", err)
+			}
+			if report.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", report.Reason, tt.reason)
+			}
+			if report.StartedAt == "" || report.StoppedAt == "" || report.UptimeSeconds <= 0 {
+				t.Errorf("report times = %q, %q, %v", report.StartedAt, report.StoppedAt, report.UptimeSeconds)
+			}
+			for name, want := range tt.want {
+				if got, _ := report.Totals[name].(float64); got != want {
+					t.Errorf("totals[%s] = %v, want %v", name, report.Totals[name], want)
+				}
+			}
+			if _, ok := report.Totals["avg_time"]; ok {
+				t.Error("totals carries the avg_time duration, want it only as avg_processing_ms")
+			}
+
+			// Only the report is left behind
+			entries, _ := os.ReadDir(filepath.Dir(path))
+			if len(entries) != 1 {
+				t.Errorf("report directory holds %d entries, want only the report", len(entries))
+			}
+		})
+	}
+}
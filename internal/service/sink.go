@@ -0,0 +1,24 @@
+package service
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// stdoutMu keeps concurrently written records on separate lines
+var stdoutMu sync.Mutex
+
+// stdout receives OUTPUT_SINK=stdout records; tests redirect it
+var stdout io.Writer = os.Stdout
+
+// writeStdout writes a record as one NDJSON line (OUTPUT_SINK=stdout)
+func writeStdout(data []byte) error {
+	line := make([]byte, 0, len(data)+1)
+	line = append(append(line, data...), '\n')
+
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	_, err := stdout.Write(line)
+	return err
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStdoutSink(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantLines int
+		wantField string // Top-level field every line carries
+	}{
+		{"flat records", nil, 3, "path"},
+		{"enveloped records", map[string]string{"ENVELOPE": "true"}, 3, "payload"},
+		{"extra outputs replaced by the sink", map[string]string{"OUTPUTS": "akto.api.logs.v1=v1"}, 3, "path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			previous := stdout
+			stdout = &out
+			t.Cleanup(func() { stdout = previous })
+
+			env := map[string]string{"OUTPUT_SINK": "stdout"}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			s := newTestService(t, testConfig(t, env))
+			for offset := int64(0); offset < 3; offset++ {
+				s.handleMessage(context.Background(), sourceMessage(sampleCapture, offset))
+			}
+
+			lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("wrote %d lines, want %d:\n%s", len(lines), tt.wantLines, out.String())
+			}
+			for _, line := range lines {
+				var record map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("line %q is not a JSON record: %v", line, err)
+				}
+				if _, ok := record[tt.wantField]; !ok {
+					t.Errorf("line %q has no %s field", line, tt.wantField)
+				}
+			}
+			if produced := len(s.sink.produced) + len(s.proto.produced); produced != 0 {
+				t.Errorf("produced %d Kafka messages with the stdout sink", produced)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+// skipRules drop transformed messages by path glob, status code or method
+type skipRules struct {
+	paths       []*regexp.Regexp
+	statusCodes map[string]bool // Exact codes and classes such as 3xx
+	methods     map[string]bool // Upper-cased
+}
+
+// newSkipRules compiles the configured rules, returning nil when there are none
+func newSkipRules(paths []string, statusCodes []string, methods []string) *skipRules {
+	if len(paths) == 0 && len(statusCodes) == 0 && len(methods) == 0 {
+		return nil
+	}
+
+	rules := &skipRules{
+		statusCodes: make(map[string]bool, len(statusCodes)),
+		methods:     make(map[string]bool, len(methods)),
+	}
+	for _, pattern := range paths {
+		rules.paths = append(rules.paths, compileGlob(pattern))
+	}
+	for _, code := range statusCodes {
+		rules.statusCodes[strings.ToLower(code)] = true
+	}
+	for _, method := range methods {
+		rules.methods[strings.ToUpper(method)] = true
+	}
+	return rules
+}
+
+// compileGlob turns a path glob into an anchored regexp: * matches within a
+// path segment, ** across segments and ? a single character
+func compileGlob(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// matches reports whether a transformed record should be skipped. Paths are
+// matched without their query string.
+func (r *skipRules) matches(record map[string]interface{}) bool {
+	if method, _ := record["method"].(string); r.methods[strings.ToUpper(method)] {
+		return true
+	}
+
+	statusCode, _ := record["statusCode"].(string)
+	if r.statusCodes[statusCode] || (len(statusCode) == 3 && r.statusCodes[statusCode[:1]+"xx"]) {
+		return true
+	}
+
+	path, _ := record["path"].(string)
+	path, _, _ = strings.Cut(path, "?")
+	for _, glob := range r.paths {
+		if glob.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
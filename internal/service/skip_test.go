@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSkipRulesMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		paths       []string
+		statusCodes []string
+		methods     []string
+		record      map[string]interface{}
+		want        bool
+	}{
+		{"exact path", []string{"/healthz"}, nil, nil, map[string]interface{}{"path": "/healthz"}, true},
+		{"query string ignored", []string{"/healthz"}, nil, nil, map[string]interface{}{"path": "/healthz?verbose=1"}, true},
+		{"other path", []string{"/healthz"}, nil, nil, map[string]interface{}{"path": "/users"}, false},
+		{"star within a segment", []string{"/static/*.js"}, nil, nil, map[string]interface{}{"path": "/static/app.js"}, true},
+		{"star stops at a slash", []string{"/static/*.js"}, nil, nil, map[string]interface{}{"path": "/static/v1/app.js"}, false},
+		{"double star across segments", []string{"/static/**"}, nil, nil, map[string]interface{}{"path": "/static/v1/app.js"}, true},
+		{"question mark", []string{"/v?/ping"}, nil, nil, map[string]interface{}{"path": "/v2/ping"}, true},
+		{"dot is literal", []string{"/favicon.ico"}, nil, nil, map[string]interface{}{"path": "/faviconxico"}, false},
+		{"exact status", nil, []string{"304"}, nil, map[string]interface{}{"statusCode": "304"}, true},
+		{"status class", nil, []string{"3XX"}, nil, map[string]interface{}{"statusCode": "301"}, true},
+		{"status outside the class", nil, []string{"3xx"}, nil, map[string]interface{}{"statusCode": "200"}, false},
+		{"method any case", nil, nil, []string{"options"}, map[string]interface{}{"method": "OPTIONS"}, true},
+		{"other method", nil, nil, []string{"OPTIONS"}, map[string]interface{}{"method": "GET"}, false},
+		{"any rule matches", []string{"/healthz"}, []string{"404"}, []string{"HEAD"},
+			map[string]interface{}{"path": "/users", "statusCode": "404", "method": "GET"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := newSkipRules(tt.paths, tt.statusCodes, tt.methods)
+			if got := rules.matches(tt.record); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.record, got, tt.want)
+			}
+		})
+	}
+
+	if rules := newSkipRules(nil, nil, nil); rules != nil {
+		t.Errorf("newSkipRules with no rules = %v, want nil", rules)
+	}
+}
+
+func TestSkipMessages(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantSkipped bool
+	}{
+		{"path glob", map[string]string{"SKIP_PATHS": "/health*,/users"}, true},
+		{"status code", map[string]string{"SKIP_STATUS_CODES": "2xx"}, true},
+		{"method", map[string]string{"SKIP_METHODS": "get"}, true},
+		{"no rule matches", map[string]string{"SKIP_PATHS": "/healthz", "SKIP_STATUS_CODES": "304", "SKIP_METHODS": "OPTIONS"}, false},
+		{"no rules", nil, false},
+	}
+	for _, tt := range tests {
+		for _, format := range []string{"json", "proto"} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				env := map[string]string{"OUTPUT_FORMAT": format}
+				for key, value := range tt.env {
+					env[key] = value
+				}
+				s := newTestService(t, testConfig(t, env))
+				s.handleMessage(context.Background(), sourceMessage(sampleCapture, 0))
+
+				wantPublished, wantSkipped := 1, int64(0)
+				if tt.wantSkipped {
+					wantPublished, wantSkipped = 0, 1
+				}
+				if got := len(s.sink.messages("akto.api.logs")); got != wantPublished {
+					t.Errorf("published %d messages, want %d", got, wantPublished)
+				}
+				if got := s.metrics.GetSnapshot()["skipped"].(int64); got != wantSkipped {
+					t.Errorf("skipped = %d, want %d", got, wantSkipped)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// statsdCounters lists the snapshot counters pushed to StatsD as deltas
+var statsdCounters = []string{"received", "transformed", "published", "failed", "output_failed", "deadline_exceeded", "skipped_private_ip", "skipped", "rate_limited", "likely_duplicate", "rejected_deep_json", "tombstones", "sampled_out", "quarantined", "captures_joined"}
+
+// pushStatsD sends counter deltas since the last push plus the average
+// processing time. It is only called from the reportMetrics goroutine.
+func (s *TransformerService) pushStatsD() {
+	snapshot := s.metrics.GetSnapshot()
+
+	for _, name := range statsdCounters {
+		total := snapshot[name].(int64)
+		if delta := total - s.statsdLast[name]; delta > 0 {
+			if err := s.statsd.Count("messages."+name, delta); err != nil {
+				s.logger.Warn(fmt.Sprintf("StatsD push failed: %v", err))
+				return
+			}
+		}
+		s.statsdLast[name] = total
+	}
+
+	if err := s.statsd.Timing("processing_time.avg", snapshot["avg_time"].(time.Duration)); err != nil {
+		s.logger.Warn(fmt.Sprintf("StatsD push failed: %v", err))
+	}
+}
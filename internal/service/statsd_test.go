@@ -0,0 +1,58 @@
+package service
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushStatsD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	s := newTestService(t, testConfig(t, map[string]string{
+		"STATSD_ADDR":   conn.LocalAddr().String(),
+		"STATSD_PREFIX": "cmt.",
+	}))
+	defer s.statsd.Close()
+
+	// readLines collects the datagrams of one push
+	readLines := func() []string {
+		var lines []string
+		buf := make([]byte, 512)
+		for {
+			conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				sort.Strings(lines)
+				return lines
+			}
+			lines = append(lines, string(buf[:n]))
+		}
+	}
+
+	s.metrics.IncrementReceivedFor("1000")
+	s.metrics.IncrementReceivedFor("1000")
+	s.metrics.IncrementTransformedFor("1000")
+	s.metrics.IncrementTransformedFor("1000")
+	s.metrics.IncrementPublishedFor("1000")
+	s.metrics.AddProcessingTime(8 * time.Millisecond)
+	s.pushStatsD()
+	want := []string{"cmt.messages.published:1|c", "cmt.messages.received:2|c", "cmt.messages.transformed:2|c", "cmt.processing_time.avg:4|ms"}
+	if got := readLines(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("first push sent %q, want %q", got, want)
+	}
+
+	// Counters are sent as deltas since the previous push
+	s.metrics.IncrementReceivedFor("1000")
+	s.pushStatsD()
+	want = []string{"cmt.messages.received:1|c", "cmt.processing_time.avg:4|ms"}
+	if got := readLines(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("second push sent %q, want %q", got, want)
+	}
+}
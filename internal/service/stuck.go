@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// stuckThreshold is how long a message may stay in flight before a warning:
+// STUCK_WARN_FRACTION of the consumer's max.poll.interval.ms
+func (s *TransformerService) stuckThreshold() time.Duration {
+	interval := time.Duration(s.config.MaxPollIntervalMs) * time.Millisecond
+	return time.Duration(float64(interval) * s.config.StuckWarnFraction)
+}
+
+// monitorStuck warns, once per message, about messages in flight past the
+// stuck threshold so slow processing is noticed before the consumer is
+// evicted from its group
+func (s *TransformerService) monitorStuck(ctx context.Context) {
+	defer s.wg.Done()
+
+	threshold := s.stuckThreshold()
+	interval := threshold / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, ticket := range s.offsets.stuck(threshold) {
+				s.logger.Warn(fmt.Sprintf("🐢 Message in flight for %v (topic: %s, partition: %d, offset: %v), max.poll.interval.ms is %d",
+					time.Since(ticket.started).Round(time.Second), ticket.key.topic, ticket.key.partition, ticket.offset, s.config.MaxPollIntervalMs))
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"client-message-transformer/internal/config"
+	"client-message-transformer/internal/logger"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestStuckTickets(t *testing.T) {
+	tests := []struct {
+		name     string
+		ages     map[int64]time.Duration // In-flight offsets and how long ago they started
+		released []int64
+		want     []int64
+	}{
+		{"nothing in flight", nil, nil, nil},
+		{"under the threshold", map[int64]time.Duration{0: 10 * time.Millisecond}, nil, nil},
+		{"past the threshold", map[int64]time.Duration{0: time.Minute}, nil, []int64{0}},
+		{"only the slow one", map[int64]time.Duration{0: 10 * time.Millisecond, 1: time.Minute}, nil, []int64{1}},
+		{"finished before the check", map[int64]time.Duration{0: time.Minute}, []int64{0}, nil},
+	}
+	topic := "client.traffic"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newOffsetTracker()
+			tickets := make(map[int64]*offsetTicket)
+			for offset, age := range tt.ages {
+				ticket := tracker.begin(kafkalib.TopicPartition{Topic: &topic, Partition: 0, Offset: kafkalib.Offset(offset)})
+				ticket.started = time.Now().Add(-age)
+				tickets[offset] = ticket
+			}
+			for _, offset := range tt.released {
+				tickets[offset].release()
+			}
+
+			var got []int64
+			for _, ticket := range tracker.stuck(time.Second) {
+				got = append(got, int64(ticket.offset))
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("stuck offsets = %v, want %v", got, tt.want)
+			}
+			if again := tracker.stuck(time.Second); len(again) != 0 {
+				t.Errorf("stuck reported %d tickets a second time, want each warned once", len(again))
+			}
+		})
+	}
+}
+
+func TestMonitorStuck(t *testing.T) {
+	var logs bytes.Buffer
+	s := &TransformerService{
+		config:   &config.Config{MaxPollIntervalMs: 1000, StuckWarnFraction: 0.5},
+		logger:   logger.NewLogger("WARN", &logs),
+		offsets:  newOffsetTracker(),
+		stopChan: make(chan bool),
+	}
+	topic := "client.traffic"
+	ticket := s.offsets.begin(kafkalib.TopicPartition{Topic: &topic, Partition: 0, Offset: 7})
+	ticket.started = time.Now().Add(-time.Minute)
+
+	s.wg.Add(1)
+	go s.monitorStuck(context.Background())
+	time.Sleep(2500 * time.Millisecond) // Two ticks of the one-second minimum interval
+	close(s.stopChan)
+	s.wg.Wait()
+
+	if got := strings.Count(logs.String(), "Message in flight"); got != 1 {
+		t.Fatalf("logged %d stuck warnings, want 1:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "offset: 7") || !strings.Contains(logs.String(), "max.poll.interval.ms is 1000") {
+		t.Errorf("warning = %q, want the offset and max.poll.interval.ms", logs.String())
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// logVerbose logs per-message progress at INFO, demoted to DEBUG when
+// COMPACT_MESSAGE_LOG replaces it with one summary line per message
+func (s *TransformerService) logVerbose(msg string) {
+	if s.config.CompactMessageLog {
+		s.logger.Debug(msg)
+		return
+	}
+	s.logger.Info(msg)
+}
+
+// logMessageSummary writes the single COMPACT_MESSAGE_LOG line for a message
+func (s *TransformerService) logMessageSummary(outcome string, clientID string, record map[string]interface{}, startTime time.Time) {
+	if !s.config.CompactMessageLog {
+		return
+	}
+	method, _ := record["method"].(string)
+	path, _ := record["path"].(string)
+	status, _ := record["statusCode"].(string)
+	s.logger.Info(fmt.Sprintf("message outcome=%s client=%s method=%s path=%q status=%s latency_ms=%.2f",
+		outcome, clientID, method, path, status, float64(time.Since(startTime).Microseconds())/1000))
+}
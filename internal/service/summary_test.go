@@ -0,0 +1,59 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"client-message-transformer/internal/logger"
+)
+
+func TestCompactMessageLog(t *testing.T) {
+	tests := []struct {
+		name        string
+		compact     string
+		capture     string
+		wantSummary string // Pattern of the single INFO line; "" when not compact
+	}{
+		{"published", "true", sampleCapture,
+			`^message outcome=published client=1000 method=GET path="/users\?id=1" status=200 latency_ms=\d+\.\d{2}$`},
+		{"failed", "true", `{"request":`,
+			`^message outcome=failed client=1000 method= path="" status= latency_ms=\d+\.\d{2}$`},
+		{"verbose", "false", sampleCapture, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"COMPACT_MESSAGE_LOG": tt.compact}))
+			var out bytes.Buffer
+			s.logger = logger.NewLogger("INFO", &out)
+			s.transformOpts.Logger = logger.NewLogger("INFO", &out)
+			s.handleMessage(context.Background(), sourceMessage(tt.capture, 0))
+
+			var lines []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				if _, msg, ok := strings.Cut(line, "INFO  | "); ok {
+					lines = append(lines, msg)
+				}
+			}
+			if tt.wantSummary == "" {
+				if len(lines) < 2 {
+					t.Errorf("verbose logging wrote %d INFO lines, want per-step progress: %q", len(lines), lines)
+				}
+				for _, line := range lines {
+					if strings.HasPrefix(line, "message outcome=") {
+						t.Errorf("summary line %q written without COMPACT_MESSAGE_LOG", line)
+					}
+				}
+				return
+			}
+			if len(lines) != 1 {
+				t.Fatalf("wrote %d INFO lines, want only the summary: %q", len(lines), lines)
+			}
+			if !regexp.MustCompile(tt.wantSummary).MatchString(lines[0]) {
+				t.Errorf("summary = %q, want it to match %s", lines[0], tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// tenantTopicPrefix is prepended to the sanitized tenant to name its topic
+const tenantTopicPrefix = "traffic."
+
+// maxTopicLength is the longest topic name Kafka accepts
+const maxTopicLength = 249
+
+// tenantTopic returns traffic.<tenant> for the message's TENANT_HEADER value,
+// or "" when tenant routing is off or the header is missing
+func (s *TransformerService) tenantTopic(kafkaMsg *kafkalib.Message) string {
+	if s.config.TenantHeader == "" {
+		return ""
+	}
+	value, ok := s.kafkaHeaderValue(kafkaMsg, s.config.TenantHeader)
+	if !ok {
+		return ""
+	}
+	tenant := sanitizeTopicName(value, maxTopicLength-len(tenantTopicPrefix))
+	if tenant == "" {
+		return ""
+	}
+	return tenantTopicPrefix + tenant
+}
+
+// sanitizeTopicName replaces characters Kafka does not allow in topic names
+// (anything but ASCII letters, digits, '.', '_' and '-') with '_' and cuts the
+// result to maxLength bytes
+func sanitizeTopicName(name string, maxLength int) string {
+	name = strings.TrimSpace(name)
+	sanitized := []byte(name)
+	for i := 0; i < len(sanitized); i++ {
+		c := sanitized[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-' {
+			continue
+		}
+		sanitized[i] = '_'
+	}
+	if len(sanitized) > maxLength {
+		sanitized = sanitized[:maxLength]
+	}
+	return string(sanitized)
+}
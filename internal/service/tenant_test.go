@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestSanitizeTopicName(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		want      string
+	}{
+		{"acme", 10, "acme"},
+		{"acme-corp_v2.eu", 20, "acme-corp_v2.eu"},
+		{" acme ", 10, "acme"},
+		{"acme/corp:eu", 20, "acme_corp_eu"},
+		{"tenant with spaces", 20, "tenant_with_spaces"},
+		{"café", 10, "caf__"},
+		{"abcdefghij", 4, "abcd"},
+		{"", 10, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeTopicName(tt.name, tt.maxLength); got != tt.want {
+				t.Errorf("sanitizeTopicName(%q, %d) = %q, want %q", tt.name, tt.maxLength, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantRouting(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   []kafkalib.Header
+		wantTopic string
+	}{
+		{"valid tenant", []kafkalib.Header{{Key: "x-tenant-id", Value: []byte("acme")}}, "traffic.acme"},
+		{"header matched case-insensitively", []kafkalib.Header{{Key: "X-Tenant-ID", Value: []byte("acme")}}, "traffic.acme"},
+		{"sanitized tenant", []kafkalib.Header{{Key: "x-tenant-id", Value: []byte("acme/eu west")}}, "traffic.acme_eu_west"},
+		{"over-long tenant", []kafkalib.Header{{Key: "x-tenant-id", Value: []byte(strings.Repeat("a", 300))}}, "traffic." + strings.Repeat("a", 241)},
+		{"missing header", nil, "akto.api.logs"},
+		{"blank tenant", []kafkalib.Header{{Key: "x-tenant-id", Value: []byte("  ")}}, "akto.api.logs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"TENANT_HEADER": "x-tenant-id"}))
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Headers = tt.headers
+			s.handleMessage(context.Background(), msg)
+
+			published := s.sink.messages(tt.wantTopic)
+			if len(published) != 1 {
+				t.Fatalf("published %d messages to %s, want 1", len(published), tt.wantTopic)
+			}
+			if len(*published[0].TopicPartition.Topic) > maxTopicLength {
+				t.Errorf("topic is %d bytes, over Kafka's %d limit", len(*published[0].TopicPartition.Topic), maxTopicLength)
+			}
+		})
+	}
+}
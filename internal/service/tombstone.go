@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"client-message-transformer/internal/tracing"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Tombstone handling policies for nil-value records on compacted topics
+const (
+	tombstonePolicySkip    = "skip"
+	tombstonePolicyForward = "forward-tombstone"
+)
+
+// handleTombstone applies TOMBSTONE_POLICY to a nil-value source record,
+// either skipping it or forwarding a tombstone with the same key
+func (s *TransformerService) handleTombstone(ctx context.Context, clientID string, kafkaMsg *kafkalib.Message) error {
+	s.metrics.IncrementTombstones()
+	if s.config.TombstonePolicy != tombstonePolicyForward {
+		s.logger.Debug(fmt.Sprintf("Skipping tombstone (partition: %d, offset: %v)", kafkaMsg.TopicPartition.Partition, kafkaMsg.TopicPartition.Offset))
+		return nil
+	}
+
+	topic := s.config.DestinationTopic
+	headers := []kafkalib.Header{
+		{Key: "client_id", Value: []byte(clientID)},
+		{Key: "transformed_at", Value: []byte(time.Now().Format(time.RFC3339))},
+	}
+	tracing.Inject(ctx, &headers)
+
+	err := s.produce(ctx, s.producer,
+		&kafkalib.Message{
+			TopicPartition: kafkalib.TopicPartition{
+				Topic:     &topic,
+				Partition: kafkalib.PartitionAny,
+			},
+			Key:     kafkaMsg.Key,
+			Value:   nil,
+			Headers: headers,
+		},
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to produce tombstone to %s: %w", topic, err)
+	}
+
+	s.logger.Info(fmt.Sprintf("🪦 Forwarded tombstone to %s (key: %s)", topic, string(kafkaMsg.Key)))
+	return nil
+}
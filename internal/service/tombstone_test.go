@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTombstonePolicy(t *testing.T) {
+	tests := []struct {
+		policy      string
+		wantForward bool
+	}{
+		{tombstonePolicySkip, false},
+		{tombstonePolicyForward, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			s := newTestService(t, testConfig(t, map[string]string{"TOMBSTONE_POLICY": tt.policy}))
+			tombstone := sourceMessage("", 3)
+			tombstone.Value = nil
+			tombstone.Key = []byte("user-42")
+			s.handleMessage(context.Background(), tombstone)
+
+			snapshot := s.metrics.GetSnapshot()
+			if snapshot["tombstones"].(int64) != 1 {
+				t.Errorf("tombstones = %d, want 1", snapshot["tombstones"])
+			}
+			if snapshot["failed"].(int64) != 0 || snapshot["transformed"].(int64) != 0 {
+				t.Errorf("tombstone counted as failed or transformed: %v", snapshot)
+			}
+
+			published := s.sink.messages("akto.api.logs")
+			if !tt.wantForward {
+				if len(published) != 0 {
+					t.Errorf("skipped tombstone was published: %v", published)
+				}
+				return
+			}
+			if len(published) != 1 {
+				t.Fatalf("published %d messages, want the forwarded tombstone", len(published))
+			}
+			if published[0].Value != nil || string(published[0].Key) != "user-42" {
+				t.Errorf("forwarded key %q value %q, want key user-42 and a nil value", published[0].Key, published[0].Value)
+			}
+			if headerValue(published[0], "client_id") == "" {
+				t.Error("forwarded tombstone has no client_id header")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// topicPool bounds concurrent processing for one weighted source topic. When
+// it is full the read loop pauses the message's partition instead of
+// blocking, so other topics keep flowing; paused partitions resume as soon
+// as a slot frees up.
+type topicPool struct {
+	slots  chan bool
+	mu     sync.Mutex
+	paused []kafkalib.TopicPartition
+}
+
+// newTopicPools splits maxConcurrent workers across topics in proportion to
+// their weights, giving every weighted topic at least one worker
+func newTopicPools(weights map[string]int, maxConcurrent int) map[string]*topicPool {
+	if len(weights) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	pools := make(map[string]*topicPool, len(weights))
+	for topic, weight := range weights {
+		size := maxConcurrent * weight / total
+		if size < 1 {
+			size = 1
+		}
+		pools[topic] = &topicPool{slots: make(chan bool, size)}
+	}
+	return pools
+}
+
+// tryAcquire takes a worker slot without blocking
+func (p *topicPool) tryAcquire() bool {
+	select {
+	case p.slots <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+// admit reserves a worker for a message on a weighted topic. When the topic
+// is saturated it pauses the partition and rewinds it to the message, which
+// is then re-read after resumption, and reports false.
+func (s *TransformerService) admit(pool *topicPool, msg *kafkalib.Message) bool {
+	if pool.tryAcquire() {
+		return true
+	}
+
+	tp := msg.TopicPartition
+	if err := s.consumer.Pause([]kafkalib.TopicPartition{tp}); err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to pause saturated partition %d: %v", tp.Partition, err))
+	}
+	if err := s.consumer.Seek(tp, 0); err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to rewind saturated partition %d: %v", tp.Partition, err))
+	}
+
+	pool.mu.Lock()
+	pool.paused = append(pool.paused, kafkalib.TopicPartition{Topic: tp.Topic, Partition: tp.Partition})
+	pool.mu.Unlock()
+
+	// A slot may have freed while pausing; resume so the partition is not stranded
+	if len(pool.slots) < cap(pool.slots) {
+		s.resumePool(pool)
+	}
+	return false
+}
+
+// release frees a worker slot and resumes partitions paused for saturation
+func (s *TransformerService) release(pool *topicPool) {
+	<-pool.slots
+	s.resumePool(pool)
+}
+
+// resumePool resumes every partition paused for the pool's saturation. While
+// the downstream is unhealthy the partitions stay paused; its recovery
+// resumes the whole assignment.
+func (s *TransformerService) resumePool(pool *topicPool) {
+	if !s.downstreamHealthy.Load() {
+		return
+	}
+
+	pool.mu.Lock()
+	paused := pool.paused
+	pool.paused = nil
+	pool.mu.Unlock()
+
+	if len(paused) == 0 {
+		return
+	}
+	if err := s.consumer.Resume(paused); err != nil {
+		s.logger.Warn(fmt.Sprintf("Failed to resume partitions: %v", err))
+	}
+}
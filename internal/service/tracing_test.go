@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	kafkalib "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// recordSpans installs an in-memory tracer provider until the test ends
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return exporter
+}
+
+func TestMessageSpans(t *testing.T) {
+	const upstreamTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const upstreamSpan = "00f067aa0ba902b7"
+
+	tests := []struct {
+		name       string
+		headers    []kafkalib.Header
+		wantParent string // Remote parent span ID, empty for a new trace
+	}{
+		{"continues upstream trace", []kafkalib.Header{{Key: "traceparent", Value: []byte("00-" + upstreamTrace + "-" + upstreamSpan + "-01")}}, upstreamSpan},
+		{"starts a new trace", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := recordSpans(t)
+			s := newTestService(t, testConfig(t, nil))
+
+			msg := sourceMessage(sampleCapture, 0)
+			msg.Headers = tt.headers
+			s.handleMessage(context.Background(), msg)
+
+			spans := make(map[string]tracetest.SpanStub)
+			for _, span := range exporter.GetSpans() {
+				if _, ok := spans[span.Name]; ok {
+					t.Fatalf("more than one %q span", span.Name)
+				}
+				spans[span.Name] = span
+			}
+			root, ok := spans["process message"]
+			if !ok {
+				t.Fatalf("no process message span among %v", spans)
+			}
+			if root.SpanKind != trace.SpanKindConsumer {
+				t.Errorf("process message kind = %v, want consumer", root.SpanKind)
+			}
+			if tt.wantParent != "" {
+				if root.Parent.SpanID().String() != tt.wantParent || root.SpanContext.TraceID().String() != upstreamTrace {
+					t.Errorf("process message parent = %v, want upstream span %s", root.Parent, tt.wantParent)
+				}
+			} else if root.Parent.IsValid() {
+				t.Errorf("process message parent = %v, want none", root.Parent)
+			}
+
+			for _, name := range []string{"transform", "produce akto.api.logs"} {
+				child, ok := spans[name]
+				if !ok {
+					t.Errorf("no %q span among %d spans", name, len(spans))
+					continue
+				}
+				if child.Parent.SpanID() != root.SpanContext.SpanID() {
+					t.Errorf("%q parent = %v, want the process message span", name, child.Parent.SpanID())
+				}
+			}
+
+			// The produced message carries the produce span's context downstream
+			produced := s.sink.messages("akto.api.logs")
+			if len(produced) != 1 {
+				t.Fatalf("produced %d messages, want 1", len(produced))
+			}
+			if got := headerValue(produced[0], "traceparent"); !strings.Contains(got, root.SpanContext.TraceID().String()) {
+				t.Errorf("traceparent = %q, want trace %s", got, root.SpanContext.TraceID())
+			}
+		})
+	}
+}
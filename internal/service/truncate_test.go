@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBodyTruncatedHeader(t *testing.T) {
+	longBody := `{"data":"` + strings.Repeat("x", 64) + `"}`
+	capture := strings.Replace(sampleCapture, `"body":"{\"id\":1}"`, `"body":`+strconv.Quote(longBody), 1)
+	tests := []struct {
+		name          string
+		maxBodyBytes  string
+		wantTruncated bool
+	}{
+		{"under the limit", "1024", false},
+		{"over the limit", "16", true},
+		{"disabled", "0", false},
+	}
+	for _, tt := range tests {
+		for _, format := range []string{"json", "proto"} {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				s := newTestService(t, testConfig(t, map[string]string{"MAX_BODY_BYTES": tt.maxBodyBytes, "OUTPUT_FORMAT": format}))
+				s.handleMessage(context.Background(), sourceMessage(capture, 0))
+
+				published := s.sink.messages("akto.api.logs")
+				if len(published) != 1 {
+					t.Fatalf("published %d messages, want 1", len(published))
+				}
+				want := ""
+				if tt.wantTruncated {
+					want = "true"
+				}
+				if got := headerValue(published[0], "body_truncated"); got != want {
+					t.Errorf("body_truncated header = %q, want %q", got, want)
+				}
+				if format != "json" {
+					return
+				}
+
+				var record map[string]interface{}
+				if err := json.Unmarshal(published[0].Value, &record); err != nil {
+					t.Fatalf("published value is not JSON: %v", err)
+				}
+				if got, _ := record["body_truncated"].(bool); got != tt.wantTruncated {
+					t.Errorf("body_truncated field = %v, want %v", got, tt.wantTruncated)
+				}
+				if tt.wantTruncated && record["response_body_original_length"] != float64(len(longBody)) {
+					t.Errorf("response_body_original_length = %v, want %d", record["response_body_original_length"], len(longBody))
+				}
+			})
+		}
+	}
+}
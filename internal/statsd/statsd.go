@@ -0,0 +1,48 @@
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Client sends metrics to a StatsD server over UDP
+type Client struct {
+	conn   net.Conn
+	prefix string
+}
+
+// New creates a StatsD client for the given host:port, prefixing every metric name
+func New(addr string, prefix string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %w", addr, err)
+	}
+	return &Client{conn: conn, prefix: prefix}, nil
+}
+
+// Count sends a counter increment
+func (c *Client) Count(name string, value int64) error {
+	return c.send(name, fmt.Sprintf("%d|c", value))
+}
+
+// Gauge sends an absolute gauge value
+func (c *Client) Gauge(name string, value int64) error {
+	return c.send(name, fmt.Sprintf("%d|g", value))
+}
+
+// Timing sends a timer value in milliseconds
+func (c *Client) Timing(name string, duration time.Duration) error {
+	return c.send(name, fmt.Sprintf("%d|ms", duration.Milliseconds()))
+}
+
+// Close releases the underlying connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// send writes a single metric line as one datagram
+func (c *Client) send(name string, value string) error {
+	_, err := fmt.Fprintf(c.conn, "%s%s:%s", c.prefix, name, value)
+	return err
+}
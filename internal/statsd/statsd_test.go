@@ -0,0 +1,59 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listen starts a fake StatsD server and returns its address and a reader of
+// the datagrams it receives
+func listen(t *testing.T) (string, func() string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	read := func() string {
+		t.Helper()
+		buf := make([]byte, 512)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read datagram: %v", err)
+		}
+		return string(buf[:n])
+	}
+	return conn.LocalAddr().String(), read
+}
+
+func TestClient(t *testing.T) {
+	addr, read := listen(t)
+	client, err := New(addr, "transformer.")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name string
+		send func() error
+		want string
+	}{
+		{"counter", func() error { return client.Count("messages.published", 3) }, "transformer.messages.published:3|c"},
+		{"gauge", func() error { return client.Gauge("consumer_lag", 42) }, "transformer.consumer_lag:42|g"},
+		{"timing", func() error { return client.Timing("processing_time.avg", 1500*time.Microsecond) }, "transformer.processing_time.avg:1|ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.send(); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if got := read(); got != tt.want {
+				t.Errorf("datagram = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
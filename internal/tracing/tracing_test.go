@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHeaderCarrier(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  []kafka.Header
+		set      [2]string
+		wantKeys []string
+	}{
+		{"adds a header", []kafka.Header{{Key: "client_id", Value: []byte("1000")}}, [2]string{"traceparent", "tp"}, []string{"client_id", "traceparent"}},
+		{"replaces a header", []kafka.Header{{Key: "traceparent", Value: []byte("old")}, {Key: "client_id", Value: []byte("1000")}}, [2]string{"traceparent", "tp"}, []string{"client_id", "traceparent"}},
+		{"replaces duplicates", []kafka.Header{{Key: "traceparent", Value: []byte("a")}, {Key: "traceparent", Value: []byte("b")}}, [2]string{"traceparent", "tp"}, []string{"traceparent"}},
+		{"no headers", nil, [2]string{"traceparent", "tp"}, []string{"traceparent"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := append([]kafka.Header(nil), tt.headers...)
+			carrier := &HeaderCarrier{Headers: &headers}
+			carrier.Set(tt.set[0], tt.set[1])
+
+			if got := carrier.Get(tt.set[0]); got != tt.set[1] {
+				t.Errorf("Get(%q) = %q, want %q", tt.set[0], got, tt.set[1])
+			}
+			if got := carrier.Keys(); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("Keys() = %v, want %v", got, tt.wantKeys)
+			}
+			if got := carrier.Get("missing"); got != "" {
+				t.Errorf("Get(missing) = %q, want empty", got)
+			}
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name      string
+		headers   []kafka.Header
+		wantValid bool
+	}{
+		{"traceparent", []kafka.Header{{Key: "traceparent", Value: []byte(traceparent)}}, true},
+		{"malformed traceparent", []kafka.Header{{Key: "traceparent", Value: []byte("garbage")}}, false},
+		{"no trace context", []kafka.Header{{Key: "client_id", Value: []byte("1000")}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := Extract(context.Background(), tt.headers)
+			spanContext := trace.SpanContextFromContext(ctx)
+			if spanContext.IsValid() != tt.wantValid {
+				t.Fatalf("extracted span context valid = %v, want %v", spanContext.IsValid(), tt.wantValid)
+			}
+			if !tt.wantValid {
+				return
+			}
+
+			var out []kafka.Header
+			Inject(ctx, &out)
+			if len(out) != 1 || out[0].Key != "traceparent" || string(out[0].Value) != traceparent {
+				t.Errorf("Inject wrote %v, want the extracted traceparent", out)
+			}
+		})
+	}
+}
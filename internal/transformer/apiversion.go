@@ -0,0 +1,61 @@
+package transformer
+
+import (
+	"mime"
+	"regexp"
+	"strings"
+)
+
+var (
+	// versionSegmentPattern matches path segments such as "v2" or "v1.1"
+	versionSegmentPattern = regexp.MustCompile(`^[vV]\d+(\.\d+)*$`)
+
+	// numericVersionPattern matches bare versions such as "2" or "1.1"
+	numericVersionPattern = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+	// vendorVersionPattern matches vendor media types such as "application/vnd.acme.v2+json"
+	vendorVersionPattern = regexp.MustCompile(`[.\-]([vV]\d+(?:\.\d+)*)(?:\+|$)`)
+)
+
+// extractAPIVersion derives the API version from a /vN/ path segment, the
+// configured version header, or the Accept header, in that order
+func extractAPIVersion(path string, requestHeaders string, versionHeader string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if versionSegmentPattern.MatchString(segment) {
+			return strings.ToLower(segment)
+		}
+	}
+
+	if versionHeader != "" {
+		if version := strings.TrimSpace(headerValue(requestHeaders, versionHeader)); version != "" {
+			return normalizeAPIVersion(version)
+		}
+	}
+
+	accept := headerValue(requestHeaders, "accept")
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if version := strings.TrimSpace(params["version"]); version != "" {
+			return normalizeAPIVersion(version)
+		}
+		if match := vendorVersionPattern.FindStringSubmatch(mediaType); match != nil {
+			return strings.ToLower(match[1])
+		}
+	}
+
+	return ""
+}
+
+// normalizeAPIVersion prefixes bare numeric versions with "v"
+func normalizeAPIVersion(version string) string {
+	if numericVersionPattern.MatchString(version) {
+		return "v" + version
+	}
+	return version
+}
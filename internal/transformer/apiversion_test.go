@@ -0,0 +1,43 @@
+package transformer
+
+import "testing"
+
+func TestExtractAPIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		headers string
+		want    string
+	}{
+		{"path segment", "/api/v2/users", `{}`, "v2"},
+		{"dotted path segment", "/V1.1/users?id=3", `{}`, "v1.1"},
+		{"version header", "/users", `{"Api-Version":"3"}`, "v3"},
+		{"named version header", "/users", `{"api-version":"2024-01-01"}`, "2024-01-01"},
+		{"accept parameter", "/users", `{"Accept":"application/json; version=2"}`, "v2"},
+		{"vendor media type", "/users", `{"Accept":"application/vnd.acme.v4+json"}`, "v4"},
+		{"path wins over header", "/v1/users", `{"Api-Version":"3"}`, "v1"},
+		{"absent", "/users/v/version", `{"Accept":"application/json"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractAPIVersion(tt.path, tt.headers, "api-version"); got != tt.want {
+				t.Errorf("extractAPIVersion(%q, %s) = %q, want %q", tt.path, tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformAPIVersion(t *testing.T) {
+	input := sampleInput()
+	section(input, "request")["url"] = "https://api.example.com/v2/users"
+
+	if output := transformFlat(t, input, &Options{ExtractAPIVersion: true}); output["apiVersion"] != "v2" {
+		t.Errorf("apiVersion = %v, want v2", output["apiVersion"])
+	}
+	if output := transformFlat(t, sampleInput(), &Options{ExtractAPIVersion: true}); output["apiVersion"] != nil {
+		t.Errorf("apiVersion = %v for an unversioned request, want it absent", output["apiVersion"])
+	}
+	if output := transformFlat(t, input, &Options{}); output["apiVersion"] != nil {
+		t.Errorf("apiVersion = %v with extraction disabled, want it absent", output["apiVersion"])
+	}
+}
@@ -0,0 +1,59 @@
+package transformer
+
+import "strings"
+
+const (
+	// redactedCredential replaces the secret part of an Authorization header
+	redactedCredential = "[REDACTED]"
+
+	// unknownAuthScheme is reported for Authorization values without a known
+	// scheme, such as bare tokens, whose first word may itself be the secret
+	unknownAuthScheme = "unknown"
+)
+
+// knownAuthSchemes maps lowercased scheme names to their canonical spelling
+var knownAuthSchemes = map[string]string{
+	"basic":            "Basic",
+	"bearer":           "Bearer",
+	"digest":           "Digest",
+	"negotiate":        "Negotiate",
+	"ntlm":             "NTLM",
+	"aws4-hmac-sha256": "AWS4-HMAC-SHA256",
+}
+
+// authScheme returns the canonical scheme of an Authorization header value,
+// unknownAuthScheme when it does not start with a known scheme, or an empty
+// string when it is blank
+func authScheme(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	if canonical, ok := knownAuthSchemes[strings.ToLower(fields[0])]; ok {
+		return canonical
+	}
+	return unknownAuthScheme
+}
+
+// redactAuthorization keeps only a known scheme of an Authorization header
+// value, replacing everything else with redactedCredential
+func redactAuthorization(value string) string {
+	switch scheme := authScheme(value); scheme {
+	case "":
+		return value
+	case unknownAuthScheme:
+		return redactedCredential
+	default:
+		return scheme + " " + redactedCredential
+	}
+}
+
+// isAuthenticated reports whether any of the auth-indicating headers carries a value
+func isAuthenticated(requestHeaders interface{}, authHeaders []string) bool {
+	for _, name := range authHeaders {
+		if strings.TrimSpace(headerValue(requestHeaders, name)) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package transformer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantScheme string
+		wantValue  string
+	}{
+		{"bearer", "Bearer abc.def.ghi", "Bearer", "Bearer [REDACTED]"},
+		{"basic lowercase", "basic dXNlcjpwYXNz", "Basic", "Basic [REDACTED]"},
+		{"aws signature", "AWS4-HMAC-SHA256 Credential=AKIA/2024, Signature=ff", "AWS4-HMAC-SHA256", "AWS4-HMAC-SHA256 [REDACTED]"},
+		{"bare token", "sk_live_51HxSecretToken", "unknown", "[REDACTED]"},
+		{"unknown scheme", "Token sk_live_51HxSecretToken", "unknown", "[REDACTED]"},
+		{"blank", "  ", "", "  "},
+		{"absent", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authScheme(tt.value); got != tt.wantScheme {
+				t.Errorf("authScheme(%q) = %q, want %q", tt.value, got, tt.wantScheme)
+			}
+			if got := redactAuthorization(tt.value); got != tt.wantValue {
+				t.Errorf("redactAuthorization(%q) = %q, want %q", tt.value, got, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestExtractAuthScheme(t *testing.T) {
+	const secret = "sk_live_51HxSecretToken"
+	tests := []struct {
+		name          string
+		authorization string // Omitted when empty
+		wantScheme    interface{}
+		wantHeader    string
+	}{
+		{"bearer", "Bearer " + secret, "Bearer", "Bearer [REDACTED]"},
+		{"basic", "Basic " + secret, "Basic", "Basic [REDACTED]"},
+		{"absent", "", nil, ""},
+		{"bare token", secret, "unknown", "[REDACTED]"},
+		{"unknown scheme", "Token " + secret, "unknown", "[REDACTED]"},
+	}
+	for _, tt := range tests {
+		for _, headerCase := range []string{"", HeaderCaseLower, HeaderCaseCanonical, HeaderCasePreserve} {
+			t.Run(tt.name+"/"+headerCase, func(t *testing.T) {
+				input := sampleInput()
+				if tt.authorization != "" {
+					headers, _ := json.Marshal(map[string]string{"Content-Type": "application/json", "authorization": tt.authorization})
+					section(input, "request")["headers"] = string(headers)
+				}
+				opts := &Options{ExtractAuthScheme: true, HeaderCase: headerCase}
+
+				output := transformFlat(t, input, opts)
+				if got := output["authScheme"]; got != tt.wantScheme {
+					t.Errorf("flat authScheme = %v, want %v", got, tt.wantScheme)
+				}
+				if got := headerValue(output["requestHeaders"], "authorization"); got != tt.wantHeader {
+					t.Errorf("flat Authorization = %q, want %q", got, tt.wantHeader)
+				}
+
+				payload := transformProto(t, input, opts)
+				var got string
+				for name, values := range payload.RequestHeaders {
+					if strings.EqualFold(name, "authorization") {
+						got = strings.Join(values.Values, ",")
+					}
+				}
+				if got != tt.wantHeader {
+					t.Errorf("proto Authorization = %q, want %q", got, tt.wantHeader)
+				}
+
+				data, _ := json.Marshal(output)
+				if strings.Contains(string(data), secret) || strings.Contains(payload.String(), secret) {
+					t.Errorf("credential leaked: flat %s, proto %v", data, payload)
+				}
+			})
+		}
+	}
+}
+
+func TestIsAuthenticated(t *testing.T) {
+	authHeaders := []string{"authorization", "cookie", "x-api-key"}
+	tests := []struct {
+		name    string
+		headers interface{}
+		want    bool
+	}{
+		{"bearer token", `{"Authorization":"Bearer abc"}`, true},
+		{"session cookie", `{"Cookie":"session=1"}`, true},
+		{"api key in object headers", map[string]interface{}{"X-API-Key": []interface{}{"k1"}}, true},
+		{"anonymous", `{"Accept":"*/*"}`, false},
+		{"blank credential", `{"Authorization":"  "}`, false},
+		{"unlisted header", `{"X-Auth-Token":"abc"}`, false},
+		{"no headers", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAuthenticated(tt.headers, authHeaders); got != tt.want {
+				t.Errorf("isAuthenticated(%v) = %v, want %v", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformIsAuthenticated(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		want    bool
+	}{
+		{"authenticated", `{"Authorization":"Bearer abc"}`, true},
+		{"anonymous", `{"Accept":"*/*"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["headers"] = tt.headers
+			output := transformFlat(t, input, &Options{AuthHeaders: []string{"authorization"}})
+			if output["isAuthenticated"] != tt.want {
+				t.Errorf("isAuthenticated = %v, want %v", output["isAuthenticated"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package transformer
+
+// captureSections returns a message's request and response sections and
+// whether both were captured. The response may be missing. The request may be
+// missing only with responseOnly set, for capture agents that send the request
+// and the response of one exchange separately, but never both.
+func captureSections(input map[string]interface{}, responseOnly bool) (request, response map[string]interface{}, complete bool, err error) {
+	request, hasRequest := input["request"].(map[string]interface{})
+	response, hasResponse := input["response"].(map[string]interface{})
+
+	if _, present := input["request"]; present && !hasRequest {
+		return nil, nil, false, &ShapeError{Message: "non-object \"request\" section"}
+	}
+	if !hasRequest && !hasResponse {
+		return nil, nil, false, &ShapeError{Message: "missing \"request\" and \"response\" sections"}
+	}
+	if !hasRequest && !responseOnly {
+		return nil, nil, false, &ShapeError{Message: "missing \"request\" section"}
+	}
+	if request == nil {
+		request = map[string]interface{}{}
+	}
+	return request, response, hasRequest && hasResponse, nil
+}
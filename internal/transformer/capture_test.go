@@ -0,0 +1,110 @@
+package transformer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShapeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(input map[string]interface{})
+	}{
+		{"no request or response key", func(input map[string]interface{}) {
+			delete(input, "request")
+			delete(input, "response")
+		}},
+		{"request is a string", func(input map[string]interface{}) { input["request"] = "GET /users" }},
+		{"request is null", func(input map[string]interface{}) { input["request"] = nil }},
+		{"request is an array", func(input map[string]interface{}) { input["request"] = []interface{}{} }},
+		{"response only", func(input map[string]interface{}) { delete(input, "request") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			tt.modify(input)
+			data := encodeInput(t, input)
+			opts := &Options{Logger: quietLogger}
+
+			var shapeErr *ShapeError
+			if _, err := TransformMessage(data, "1000", opts); !errors.As(err, &shapeErr) {
+				t.Errorf("TransformMessage error = %v, want a ShapeError", err)
+			}
+			if _, _, err := TransformToProto(data, "1000", opts); !errors.As(err, &shapeErr) {
+				t.Errorf("TransformToProto error = %v, want a ShapeError", err)
+			}
+		})
+	}
+}
+
+func TestCaptureComplete(t *testing.T) {
+	tests := []struct {
+		name       string
+		drop       string
+		want       bool
+		wantMethod string
+		wantStatus string
+	}{
+		{"complete", "", true, "GET", "200"},
+		{"request only", "response", false, "GET", "0"},
+		{"response only", "request", false, "", "200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			if tt.drop != "" {
+				delete(input, tt.drop)
+			}
+
+			opts := &Options{AllowResponseOnly: true}
+			output := transformFlat(t, input, opts)
+			if output["captureComplete"] != tt.want {
+				t.Errorf("captureComplete = %v, want %v", output["captureComplete"], tt.want)
+			}
+			if output["method"] != tt.wantMethod || output["statusCode"] != tt.wantStatus {
+				t.Errorf("method, statusCode = %q, %q, want %q, %q", output["method"], output["statusCode"], tt.wantMethod, tt.wantStatus)
+			}
+
+			payload := transformProto(t, input, opts)
+			if payload.Method != tt.wantMethod {
+				t.Errorf("proto Method = %q, want %q", payload.Method, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestAllowResponseOnly(t *testing.T) {
+	tests := []struct {
+		name      string
+		allow     bool
+		drop      string
+		wantShape bool
+	}{
+		{"response only rejected", false, "request", true},
+		{"response only accepted", true, "request", false},
+		{"request only accepted", false, "response", false},
+		{"neither rejected even when allowed", true, "both", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			switch tt.drop {
+			case "both":
+				delete(input, "request")
+				delete(input, "response")
+			default:
+				delete(input, tt.drop)
+			}
+			data := encodeInput(t, input)
+			opts := &Options{AllowResponseOnly: tt.allow, Logger: quietLogger}
+
+			var shapeErr *ShapeError
+			if _, err := TransformMessage(data, "1000", opts); errors.As(err, &shapeErr) != tt.wantShape {
+				t.Errorf("TransformMessage error = %v, want a ShapeError: %v", err, tt.wantShape)
+			}
+			if _, _, err := TransformToProto(data, "1000", opts); errors.As(err, &shapeErr) != tt.wantShape {
+				t.Errorf("TransformToProto error = %v, want a ShapeError: %v", err, tt.wantShape)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package transformer
+
+import (
+	"io"
+	"net/http/httputil"
+	"strings"
+)
+
+// isChunked reports whether a Transfer-Encoding value includes chunked
+func isChunked(transferEncoding string) bool {
+	for _, coding := range strings.Split(transferEncoding, ",") {
+		if strings.EqualFold(strings.TrimSpace(coding), "chunked") {
+			return true
+		}
+	}
+	return false
+}
+
+// dechunkBody strips chunked transfer-encoding framing left in a captured
+// body. It returns the body unchanged and false when it is not well-formed
+// chunked data, so already-decoded bodies pass through untouched.
+func dechunkBody(body string) (string, bool) {
+	if body == "" {
+		return body, false
+	}
+
+	decoded, err := io.ReadAll(httputil.NewChunkedReader(strings.NewReader(body)))
+	if err != nil {
+		return body, false
+	}
+	return string(decoded), true
+}
+
+// dechunkIfNeeded de-chunks a body whose headers declare chunked encoding
+func (o *Options) dechunkIfNeeded(body string, headers interface{}) string {
+	if !o.DechunkBodies || !isChunked(headerValue(headers, "transfer-encoding")) {
+		return body
+	}
+	dechunked, _ := dechunkBody(body)
+	return dechunked
+}
@@ -0,0 +1,59 @@
+package transformer
+
+import "testing"
+
+func TestDechunkBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		want   string
+		wantOK bool
+	}{
+		{"two chunks", "7\r\n{\"id\":1\r\n1\r\n}\r\n0\r\n\r\n", `{"id":1}`, true},
+		{"chunk extension", "8;ext=1\r\n{\"id\":1}\r\n0\r\n\r\n", `{"id":1}`, true},
+		{"already decoded", `{"id":1}`, `{"id":1}`, false},
+		{"truncated chunk", "ff\r\n{\"id\":1}", "ff\r\n{\"id\":1}", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := dechunkBody(tt.body)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("dechunkBody(%q) = %q, %v, want %q, %v", tt.body, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTransformChunkedBody(t *testing.T) {
+	const chunked = "7\r\n{\"id\":1\r\n1\r\n}\r\n0\r\n\r\n"
+	tests := []struct {
+		name             string
+		transferEncoding string
+		body             string
+		dechunk          bool
+		want             string
+	}{
+		{"chunked body", "chunked", chunked, true, `{"id":1}`},
+		{"chunked among codings", "gzip, Chunked", chunked, true, `{"id":1}`},
+		{"normal body", "identity", `{"id":1}`, true, `{"id":1}`},
+		{"declared chunked but decoded", "chunked", `{"id":1}`, true, `{"id":1}`},
+		{"disabled", "chunked", chunked, false, chunked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			response := section(input, "response")
+			response["headers"] = `{"Content-Type":"application/json","Transfer-Encoding":"` + tt.transferEncoding + `"}`
+			response["body"] = tt.body
+			opts := &Options{DechunkBodies: tt.dechunk}
+
+			if got := transformFlat(t, input, opts)["responsePayload"]; got != tt.want {
+				t.Errorf("flat responsePayload = %q, want %q", got, tt.want)
+			}
+			if got := transformProto(t, input, opts).ResponsePayload; got != tt.want {
+				t.Errorf("proto ResponsePayload = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
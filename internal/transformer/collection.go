@@ -0,0 +1,61 @@
+package transformer
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf16"
+)
+
+// requestHost returns the lowercased host of a request from its absolute URL,
+// falling back to the Host header for relative URLs
+func requestHost(fullURL string, requestHeaders interface{}) string {
+	if strings.Contains(fullURL, "://") {
+		if parsedURL, err := url.Parse(fullURL); err == nil && parsedURL.Host != "" {
+			return strings.ToLower(parsedURL.Host)
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(headerValue(requestHeaders, "host")))
+}
+
+// apiCollectionID maps a host to its Akto API collection, using the configured
+// mapping when present and otherwise the host's Java-style string hash
+func (o *Options) apiCollectionID(host string) int32 {
+	if id, ok := o.HostToCollection[host]; ok {
+		return id
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		if id, ok := o.HostToCollection[hostname]; ok {
+			return id
+		}
+	}
+	return javaStringHash(host)
+}
+
+// isInternalHost reports whether a host (port ignored) matches one of the
+// configured internal suffixes, either exactly or as a subdomain
+func (o *Options) isInternalHost(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if host == "" {
+		return false
+	}
+	for _, suffix := range o.InternalHostSuffixes {
+		suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// javaStringHash reproduces Java's String.hashCode, which Akto uses to derive
+// collection IDs from hostnames
+func javaStringHash(s string) int32 {
+	var hash int32
+	for _, unit := range utf16.Encode([]rune(s)) {
+		hash = 31*hash + int32(unit)
+	}
+	return hash
+}
@@ -0,0 +1,103 @@
+package transformer
+
+import "testing"
+
+func TestJavaStringHash(t *testing.T) {
+	// Values of String.hashCode in Java
+	tests := []struct {
+		s    string
+		want int32
+	}{
+		{"", 0},
+		{"abc", 96354},
+		{"Aa", 2112},
+		{"BB", 2112},
+		{"hello world", 1794106052},
+		{"api.example.com", -1628073687}, // Overflows int32
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := javaStringHash(tt.s); got != tt.want {
+				t.Errorf("javaStringHash(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPICollectionID(t *testing.T) {
+	opts := &Options{HostToCollection: map[string]int32{"api.example.com": 111, "admin.example.com:8443": 222}}
+
+	tests := []struct {
+		name string
+		url  string
+		host string // Host header
+		want int32
+	}{
+		{"mapped host", "https://api.example.com/users", "", 111},
+		{"mapped host with port", "https://api.example.com:8080/users", "", 111},
+		{"mapped host and port", "https://admin.example.com:8443/", "", 222},
+		{"host case ignored", "https://API.Example.com/users", "", 111},
+		{"unmapped host hashed", "https://shop.example.com/cart", "", javaStringHash("shop.example.com")},
+		{"relative URL uses Host header", "/users", "api.example.com", 111},
+		{"relative URL unmapped Host header", "/users", "Other.example.com", javaStringHash("other.example.com")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			request := section(input, "request")
+			request["url"] = tt.url
+			request["headers"] = `{"Host":"` + tt.host + `"}`
+
+			output := transformFlat(t, input, opts)
+			if got := output["apiCollectionId"]; got != tt.want {
+				t.Errorf("flat apiCollectionId = %v, want %d", got, tt.want)
+			}
+			if got := transformProto(t, input, opts).ApiCollectionId; got != tt.want {
+				t.Errorf("proto ApiCollectionId = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPICollectionIDWithoutHost(t *testing.T) {
+	input := sampleInput()
+	section(input, "request")["url"] = "/users"
+
+	if output := transformFlat(t, input, &Options{}); output["apiCollectionId"] != nil {
+		t.Errorf("apiCollectionId = %v without a host, want none", output["apiCollectionId"])
+	}
+}
+
+func TestIsInternal(t *testing.T) {
+	opts := &Options{InternalHostSuffixes: []string{"internal.example.com", ".svc.cluster.local"}}
+	tests := []struct {
+		name string
+		url  string
+		host string // Host header
+		want interface{}
+	}{
+		{"internal host", "https://internal.example.com/health", "", true},
+		{"internal subdomain with port", "http://billing.internal.example.com:8080/v1", "", true},
+		{"cluster service", "http://users.default.svc.cluster.local/users", "", true},
+		{"external host", "https://api.example.com/users", "", false},
+		{"suffix without a dot boundary", "https://notinternal.example.com/", "", false},
+		{"relative URL uses Host header", "/users", "orders.internal.example.com", true},
+		{"relative URL without host", "/users", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			request := section(input, "request")
+			request["url"] = tt.url
+			request["headers"] = `{"Host":"` + tt.host + `"}`
+
+			if got := transformFlat(t, input, opts)["isInternal"]; got != tt.want {
+				t.Errorf("isInternal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if output := transformFlat(t, sampleInput(), &Options{}); output["isInternal"] != nil {
+		t.Errorf("isInternal = %v without suffixes, want none", output["isInternal"])
+	}
+}
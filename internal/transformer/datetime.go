@@ -0,0 +1,48 @@
+package transformer
+
+// Units of the info.dateTime epoch timestamp
+const (
+	DateTimeUnitAuto         = "auto"
+	DateTimeUnitSeconds      = "s"
+	DateTimeUnitMilliseconds = "ms"
+	DateTimeUnitMicroseconds = "us"
+	DateTimeUnitNanoseconds  = "ns"
+)
+
+// Upper bounds used to infer a timestamp's unit from its magnitude. Each unit
+// covers epochs from 1973 to well past 5000 before the next one takes over.
+const (
+	maxEpochSeconds      = 1e11
+	maxEpochMilliseconds = 1e14
+	maxEpochMicroseconds = 1e17
+)
+
+// epochSeconds converts an info.dateTime timestamp to epoch seconds, in the
+// configured DateTimeUnit or, for auto, the unit its magnitude suggests
+func (o *Options) epochSeconds(dateTime int64) int64 {
+	switch o.DateTimeUnit {
+	case DateTimeUnitSeconds:
+		return dateTime
+	case DateTimeUnitMilliseconds:
+		return dateTime / 1e3
+	case DateTimeUnitMicroseconds:
+		return dateTime / 1e6
+	case DateTimeUnitNanoseconds:
+		return dateTime / 1e9
+	}
+
+	magnitude := dateTime
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	switch {
+	case magnitude < maxEpochSeconds:
+		return dateTime
+	case magnitude < maxEpochMilliseconds:
+		return dateTime / 1e3
+	case magnitude < maxEpochMicroseconds:
+		return dateTime / 1e6
+	default:
+		return dateTime / 1e9
+	}
+}
@@ -0,0 +1,41 @@
+package transformer
+
+import "testing"
+
+func TestEpochSeconds(t *testing.T) {
+	const want = 1700000000
+	tests := []struct {
+		name     string
+		unit     string
+		dateTime int64
+		want     int64
+	}{
+		{"auto seconds", DateTimeUnitAuto, want, want},
+		{"auto milliseconds", DateTimeUnitAuto, want * 1e3, want},
+		{"auto microseconds", DateTimeUnitAuto, want * 1e6, want},
+		{"auto nanoseconds", DateTimeUnitAuto, want * 1e9, want},
+		{"unset infers", "", want * 1e3, want},
+		{"auto zero", DateTimeUnitAuto, 0, 0},
+		{"auto before 1970", DateTimeUnitAuto, -86400, -86400},
+		{"auto milliseconds before 1970", DateTimeUnitAuto, -2e11, -2e8},
+		{"seconds", DateTimeUnitSeconds, want, want},
+		{"milliseconds", DateTimeUnitMilliseconds, want * 1e3, want},
+		{"microseconds", DateTimeUnitMicroseconds, want * 1e6, want},
+		{"nanoseconds", DateTimeUnitNanoseconds, want * 1e9, want},
+		{"forced unit overrides magnitude", DateTimeUnitMilliseconds, want, want / 1e3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{DateTimeUnit: tt.unit}
+			if got := opts.epochSeconds(tt.dateTime); got != tt.want {
+				t.Errorf("epochSeconds(%d) = %d, want %d", tt.dateTime, got, tt.want)
+			}
+
+			input := sampleInput()
+			section(input, "info")["dateTime"] = float64(tt.dateTime)
+			if got := transformProto(t, input, opts).Time; int64(got) != tt.want {
+				t.Errorf("proto Time = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
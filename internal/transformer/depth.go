@@ -0,0 +1,42 @@
+package transformer
+
+import "fmt"
+
+// checkJSONDepth scans the input's bytes and rejects it once objects and
+// arrays nest deeper than maxDepth, before a full decode. It tracks only
+// string boundaries and brackets, so it allocates nothing; malformed input
+// is left for the decode that follows to report.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("%w of %d", ErrJSONTooDeep, maxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
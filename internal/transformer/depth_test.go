@@ -0,0 +1,106 @@
+package transformer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// nested wraps a value in depth levels of alternating objects and arrays
+func nested(depth int) string {
+	value := `"leaf"`
+	for i := 0; i < depth; i++ {
+		if i%2 == 0 {
+			value = "[" + value + "]"
+		} else {
+			value = `{"k":` + value + "}"
+		}
+	}
+	return value
+}
+
+func TestCheckJSONDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		maxDepth int
+		wantErr  bool
+	}{
+		{"flat object", `{"a":1}`, 1, false},
+		{"at the limit", nested(5), 5, false},
+		{"over the limit", nested(6), 5, true},
+		{"disabled", nested(100), 0, false},
+		{"brackets inside strings", `{"a":"[[[[{{{{"}`, 2, false},
+		{"escaped quote inside string", `{"a":"\"[[[["}`, 2, false},
+		{"escaped backslash ends string", `{"a":"\\","b":[[[1]]]}`, 2, true},
+		{"siblings do not add up", `{"a":[1],"b":[2],"c":[3]}`, 2, false},
+		{"malformed left to the decoder", `{"a":[`, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONDepth([]byte(tt.data), tt.maxDepth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkJSONDepth(%s, %d) = %v, wantErr %v", tt.data, tt.maxDepth, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrJSONTooDeep) {
+				t.Errorf("error = %v, want ErrJSONTooDeep", err)
+			}
+		})
+	}
+}
+
+func TestTransformRejectsDeepJSON(t *testing.T) {
+	// An unused top-level field nested past the limit
+	normal := string(encodeInput(t, sampleInput()))
+	deepData := []byte(strings.TrimSuffix(normal, "}") + `,"extra":` + nested(80) + "}")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		maxDepth int
+		wantErr  bool
+	}{
+		{"normal payload", []byte(normal), 64, false},
+		{"deep payload", deepData, 64, true},
+		{"deep payload with the check off", deepData, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{Logger: quietLogger, MaxJSONDepth: tt.maxDepth}
+			_, flatErr := TransformMessage(tt.data, "1000", opts)
+			_, _, protoErr := TransformToProto(tt.data, "1000", opts)
+			for path, err := range map[string]error{"flat": flatErr, "proto": protoErr} {
+				if tt.wantErr && !errors.Is(err, ErrJSONTooDeep) {
+					t.Errorf("%s error = %v, want ErrJSONTooDeep", path, err)
+				}
+				if !tt.wantErr && err != nil {
+					t.Errorf("%s error = %v, want success", path, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTransformMessage(b *testing.B) {
+	data := encodeInput(b, sampleInput())
+	benchmarks := []struct {
+		name     string
+		maxDepth int
+	}{
+		{"depth check off", 0},
+		{"depth check 64", 64},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			opts := &Options{Logger: quietLogger, MaxJSONDepth: bm.maxDepth}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				output, err := TransformMessage(data, "1000", opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				ReleaseOutput(output)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package transformer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/golang/snappy"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Supported body payload encodings. Compressed bodies are expected to be
+// base64-encoded so they survive transport inside JSON strings.
+const (
+	PayloadEncodingNone   = "none"
+	PayloadEncodingGzip   = "gzip"
+	PayloadEncodingSnappy = "snappy"
+	PayloadEncodingLZ4    = "lz4"
+)
+
+// Policies for bodies that fail to decode
+const (
+	DecodeFailurePolicyFail           = "fail"            // Reject the message
+	DecodeFailurePolicyPassthroughRaw = "passthrough-raw" // Forward the undecoded body
+)
+
+// decodeBodyWithPolicy decodes a body, applying the configured failure policy.
+// The returned flag reports that decoding failed and the raw body was kept.
+func (o *Options) decodeBodyWithPolicy(body string) (string, bool, error) {
+	decoded, err := decodeBody(body, o.PayloadEncoding)
+	if err == nil {
+		if o.DecodeBase64Bodies && (o.PayloadEncoding == "" || o.PayloadEncoding == PayloadEncodingNone) {
+			decoded = decodeBase64Text(decoded)
+		}
+		return decoded, false, nil
+	}
+	if o.DecodeFailurePolicy == DecodeFailurePolicyPassthroughRaw {
+		return body, true, nil
+	}
+	return "", false, err
+}
+
+// decodeBody reverses the configured payload encoding of a body string
+func decodeBody(body string, encoding string) (string, error) {
+	if body == "" || encoding == "" || encoding == PayloadEncodingNone {
+		return body, nil
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("body is not valid base64: %w", err)
+	}
+
+	var decoded []byte
+	switch encoding {
+	case PayloadEncodingGzip:
+		decoded, err = decodeGzip(compressed)
+	case PayloadEncodingSnappy:
+		decoded, err = decodeSnappy(compressed)
+	case PayloadEncodingLZ4:
+		decoded, err = io.ReadAll(lz4.NewReader(bytes.NewReader(compressed)))
+	default:
+		return "", fmt.Errorf("unsupported payload encoding %q", encoding)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s body: %w", encoding, err)
+	}
+
+	return string(decoded), nil
+}
+
+// decodeBase64Text returns a base64 body decoded when the result is UTF-8
+// text, and the body unchanged when it is not base64 or decodes to binary
+func decodeBase64Text(body string) string {
+	if body == "" {
+		return body
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil || !utf8.Valid(decoded) {
+		return body
+	}
+	return string(decoded)
+}
+
+// decodeGzip decompresses a gzip stream
+func decodeGzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// decodeSnappy decompresses a snappy block, falling back to the framed format
+func decodeSnappy(data []byte) ([]byte, error) {
+	if decoded, err := snappy.Decode(nil, data); err == nil {
+		return decoded, nil
+	}
+	return io.ReadAll(snappy.NewReader(bytes.NewReader(data)))
+}
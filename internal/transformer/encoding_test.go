@@ -0,0 +1,180 @@
+package transformer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/pierrec/lz4/v4"
+)
+
+const plainBody = `{"user":"alice","items":[1,2,3]}`
+
+// compress encodes body with encoding and base64s it, as capture agents do
+func compress(t *testing.T, encoding string, body string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	switch encoding {
+	case PayloadEncodingGzip:
+		writer := gzip.NewWriter(&buf)
+		writer.Write([]byte(body))
+		writer.Close()
+	case PayloadEncodingSnappy:
+		buf.Write(snappy.Encode(nil, []byte(body)))
+	case "snappy-framed":
+		writer := snappy.NewBufferedWriter(&buf)
+		writer.Write([]byte(body))
+		writer.Close()
+	case PayloadEncodingLZ4:
+		writer := lz4.NewWriter(&buf)
+		writer.Write([]byte(body))
+		writer.Close()
+	default:
+		t.Fatalf("unknown encoding %q", encoding)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		encoding    string
+		compression string
+	}{
+		{"gzip", PayloadEncodingGzip, PayloadEncodingGzip},
+		{"snappy block", PayloadEncodingSnappy, PayloadEncodingSnappy},
+		{"snappy framed", PayloadEncodingSnappy, "snappy-framed"},
+		{"lz4", PayloadEncodingLZ4, PayloadEncodingLZ4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := decodeBody(compress(t, tt.compression, plainBody), tt.encoding)
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			if decoded != plainBody {
+				t.Errorf("decodeBody = %q, want %q", decoded, plainBody)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyUnencoded(t *testing.T) {
+	for _, encoding := range []string{"", PayloadEncodingNone} {
+		if decoded, err := decodeBody(plainBody, encoding); err != nil || decoded != plainBody {
+			t.Errorf("decodeBody with %q = (%q, %v), want the body unchanged", encoding, decoded, err)
+		}
+	}
+	if _, err := decodeBody("not base64!", PayloadEncodingSnappy); err == nil {
+		t.Error("decodeBody accepted a body that is not base64")
+	}
+}
+
+func TestTransformCompressedBodies(t *testing.T) {
+	for _, encoding := range []string{PayloadEncodingSnappy, PayloadEncodingLZ4} {
+		t.Run(encoding, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["body"] = compress(t, encoding, plainBody)
+			section(input, "response")["body"] = compress(t, encoding, `{"id":1}`)
+
+			output := transformFlat(t, input, &Options{PayloadEncoding: encoding})
+			if output["requestPayload"] != plainBody || output["responsePayload"] != `{"id":1}` {
+				t.Errorf("payloads = (%q, %q), want the decompressed bodies", output["requestPayload"], output["responsePayload"])
+			}
+
+			payload := transformProto(t, input, &Options{PayloadEncoding: encoding})
+			if payload.RequestPayload != plainBody || payload.ResponsePayload != `{"id":1}` {
+				t.Errorf("proto payloads = (%q, %q), want the decompressed bodies", payload.RequestPayload, payload.ResponsePayload)
+			}
+		})
+	}
+}
+
+func TestDecodeFailurePolicy(t *testing.T) {
+	// Valid base64 whose gzip stream is cut short
+	good := compress(t, PayloadEncodingGzip, plainBody)
+	raw, _ := base64.StdEncoding.DecodeString(good)
+	corrupt := base64.StdEncoding.EncodeToString(raw[:len(raw)/2])
+
+	tests := []struct {
+		name       string
+		policy     string
+		body       string
+		wantErr    bool
+		wantBody   string
+		wantFailed interface{}
+	}{
+		{"fail rejects", DecodeFailurePolicyFail, corrupt, true, "", nil},
+		{"default rejects", "", corrupt, true, "", nil},
+		{"passthrough-raw forwards", DecodeFailurePolicyPassthroughRaw, corrupt, false, corrupt, true},
+		{"passthrough-raw decodes good bodies", DecodeFailurePolicyPassthroughRaw, good, false, plainBody, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["body"] = ""
+			section(input, "response")["body"] = tt.body
+			opts := &Options{PayloadEncoding: PayloadEncodingGzip, DecodeFailurePolicy: tt.policy, Logger: quietLogger}
+
+			output, err := TransformMessage(encodeInput(t, input), "1000", opts)
+			_, _, protoErr := TransformToProto(encodeInput(t, input), "1000", opts)
+			if tt.wantErr {
+				if err == nil || protoErr == nil {
+					t.Fatalf("errors = %v, %v, want both transforms to fail", err, protoErr)
+				}
+				return
+			}
+			if err != nil || protoErr != nil {
+				t.Fatalf("errors = %v, %v", err, protoErr)
+			}
+			if output["responsePayload"] != tt.wantBody {
+				t.Errorf("responsePayload = %q, want %q", output["responsePayload"], tt.wantBody)
+			}
+			if output["bodyDecodeFailed"] != tt.wantFailed {
+				t.Errorf("bodyDecodeFailed = %v, want %v", output["bodyDecodeFailed"], tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestDecodeBase64Bodies(t *testing.T) {
+	binary := base64.StdEncoding.EncodeToString([]byte{0xff, 0xd8, 0xff, 0xe0, 0x00})
+	tests := []struct {
+		name     string
+		body     string
+		enabled  bool
+		encoding string
+		want     string
+	}{
+		{"valid base64 text", base64.StdEncoding.EncodeToString([]byte(plainBody)), true, "", plainBody},
+		{"valid base64 unicode", base64.StdEncoding.EncodeToString([]byte("héllo ✓")), true, PayloadEncodingNone, "héllo ✓"},
+		{"invalid base64 kept", plainBody, true, "", plainBody},
+		{"padding missing kept", "aGVsbG8", true, "", "aGVsbG8"},
+		{"binary kept encoded", binary, true, "", binary},
+		{"empty body", "", true, "", ""},
+		{"disabled", base64.StdEncoding.EncodeToString([]byte(plainBody)), false, "", base64.StdEncoding.EncodeToString([]byte(plainBody))},
+		{"compressed bodies decode once", compress(t, PayloadEncodingGzip, base64.StdEncoding.EncodeToString([]byte(plainBody))), true, PayloadEncodingGzip,
+			base64.StdEncoding.EncodeToString([]byte(plainBody))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{DecodeBase64Bodies: tt.enabled, PayloadEncoding: tt.encoding}
+			got, raw, err := opts.decodeBodyWithPolicy(tt.body)
+			if err != nil || raw {
+				t.Fatalf("decodeBodyWithPolicy = %q, %v, %v, want no failure", got, raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("decodeBodyWithPolicy(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+
+			input := sampleInput()
+			section(input, "request")["body"] = ""
+			section(input, "response")["body"] = tt.body
+			if output := transformFlat(t, input, opts); output["responsePayload"] != tt.want {
+				t.Errorf("flat responsePayload = %q, want %q", output["responsePayload"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ShapeError represents a message whose structure does not match the
+// expected nested client format
+type ShapeError struct {
+	Message string
+}
+
+// Error implements the error interface
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("invalid message shape: %s", e.Message)
+}
+
+// ErrJSONTooDeep is returned for inputs nested deeper than MaxJSONDepth
+var ErrJSONTooDeep = errors.New("JSON input exceeds maximum nesting depth")
@@ -0,0 +1,57 @@
+package transformer
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	// grpcWebFrameHeaderSize is the flag byte plus the 4-byte big-endian length
+	grpcWebFrameHeaderSize = 5
+
+	// grpcWebTrailerFlag marks a frame carrying trailers instead of a message
+	grpcWebTrailerFlag = 0x80
+)
+
+// isGRPCWeb reports whether a content type denotes gRPC-Web traffic
+func isGRPCWeb(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "application/grpc-web")
+}
+
+// unframeGRPCWeb strips gRPC-Web length-prefixed framing from a body, keeping
+// message frames and dropping trailer frames. It returns false and leaves the
+// body untouched when the framing is not valid.
+func unframeGRPCWeb(body string, contentType string) (string, bool) {
+	if body == "" {
+		return body, false
+	}
+
+	data := []byte(body)
+	if strings.HasPrefix(strings.ToLower(contentType), "application/grpc-web-text") {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return body, false
+		}
+		data = decoded
+	}
+
+	var messages []byte
+	for len(data) > 0 {
+		if len(data) < grpcWebFrameHeaderSize {
+			return body, false
+		}
+		flag := data[0]
+		length := binary.BigEndian.Uint32(data[1:grpcWebFrameHeaderSize])
+		data = data[grpcWebFrameHeaderSize:]
+		if uint64(length) > uint64(len(data)) {
+			return body, false
+		}
+		if flag&grpcWebTrailerFlag == 0 {
+			messages = append(messages, data[:length]...)
+		}
+		data = data[length:]
+	}
+
+	return string(messages), true
+}
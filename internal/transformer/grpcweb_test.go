@@ -0,0 +1,72 @@
+package transformer
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// grpcWebFrame builds one length-prefixed gRPC-Web frame
+func grpcWebFrame(flag byte, payload string) string {
+	frame := make([]byte, grpcWebFrameHeaderSize, grpcWebFrameHeaderSize+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return string(append(frame, payload...))
+}
+
+func TestUnframeGRPCWeb(t *testing.T) {
+	message := grpcWebFrame(0, "\x0a\x05hello")
+	trailer := grpcWebFrame(grpcWebTrailerFlag, "grpc-status: 0\r\n")
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+		wantOK      bool
+	}{
+		{"message frame", message, "application/grpc-web+proto", "\x0a\x05hello", true},
+		{"trailer frame dropped", message + trailer, "application/grpc-web", "\x0a\x05hello", true},
+		{"two message frames", message + grpcWebFrame(0, "!"), "application/grpc-web", "\x0a\x05hello!", true},
+		{"text variant", base64.StdEncoding.EncodeToString([]byte(message + trailer)), "application/grpc-web-text", "\x0a\x05hello", true},
+		{"truncated frame", message[:len(message)-1], "application/grpc-web", message[:len(message)-1], false},
+		{"invalid base64", "not base64!", "application/grpc-web-text", "not base64!", false},
+		{"empty body", "", "application/grpc-web", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := unframeGRPCWeb(tt.body, tt.contentType)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("unframeGRPCWeb = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTransformGRPCWeb(t *testing.T) {
+	input := sampleInput()
+	section(input, "request")["headers"] = `{"Content-Type":"application/grpc-web+proto"}`
+	section(input, "request")["body"] = grpcWebFrame(0, "request")
+	// The trailer flag is not valid UTF-8, so framed trailers travel as grpc-web-text
+	section(input, "response")["headers"] = `{"Content-Type":"application/grpc-web-text+proto"}`
+	section(input, "response")["body"] = base64.StdEncoding.EncodeToString(
+		[]byte(grpcWebFrame(0, "response") + grpcWebFrame(grpcWebTrailerFlag, "grpc-status: 0\r\n")))
+
+	output := transformFlat(t, input, &Options{})
+	if output["grpcWeb"] != true {
+		t.Errorf("grpcWeb = %v, want true", output["grpcWeb"])
+	}
+	if output["requestPayload"] != "request" || output["responsePayload"] != "response" {
+		t.Errorf("payloads = (%q, %q), want unframed (request, response)", output["requestPayload"], output["responsePayload"])
+	}
+
+	payload := transformProto(t, input, &Options{})
+	if payload.RequestPayload != "request" || payload.ResponsePayload != "response" {
+		t.Errorf("proto payloads = (%q, %q), want unframed (request, response)", payload.RequestPayload, payload.ResponsePayload)
+	}
+
+	plain := transformFlat(t, sampleInput(), &Options{})
+	if _, ok := plain["grpcWeb"]; ok {
+		t.Error("plain JSON traffic flagged as grpcWeb")
+	}
+}
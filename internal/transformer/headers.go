@@ -0,0 +1,273 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+)
+
+// decodeHeaders accepts headers either as a JSON-encoded string or as an
+// already-parsed object and returns them as a map. Strings that are not a
+// JSON object return an error; callers treat them as no headers.
+func decodeHeaders(raw interface{}) (map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		var headersMap map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &headersMap); err != nil {
+			return nil, fmt.Errorf("failed to parse headers: %w", err)
+		}
+		return headersMap, nil
+	}
+	return nil, nil
+}
+
+// headersString returns headers as the JSON string the flat format carries,
+// encoding already-parsed objects and mapping any other type to ""
+func headersString(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	}
+	return ""
+}
+
+// parseHeaders converts headers (JSON string or object) into protobuf header lists
+func parseHeaders(raw interface{}, opts *Options) map[string]*trafficpb.StringList {
+	headers := make(map[string]*trafficpb.StringList)
+
+	headersMap, err := decodeHeaders(raw)
+	if err != nil {
+		opts.log().Warnf("⚠️  [TRANSFORMER] %v", err)
+	}
+	for name, value := range headersMap {
+		if opts.dropsHeader(name) || !opts.keepsHeader(name) {
+			continue
+		}
+
+		var values []string
+		switch v := value.(type) {
+		case string:
+			values = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				if str, ok := item.(string); ok {
+					values = append(values, str)
+				}
+			}
+		}
+		for i, v := range values {
+			values[i] = opts.truncateHeaderValue(v)
+		}
+		key := opts.headerName(name)
+		if opts.CoalesceDuplicateHeaders {
+			// Names differing only in case collapse onto one key, so merge them
+			if existing, ok := headers[key]; ok {
+				values = append(existing.Values, values...)
+			}
+			values = dedupeValues(values)
+		}
+		headers[key] = &trafficpb.StringList{
+			Values: values,
+		}
+	}
+	return headers
+}
+
+// Header name casing modes
+const (
+	HeaderCaseLower     = "lower"     // content-type
+	HeaderCaseCanonical = "canonical" // Content-Type
+	HeaderCasePreserve  = "preserve"  // as captured
+)
+
+// headerName applies the configured casing to a header name. Parsed header
+// maps are lowercased unless another mode is configured.
+func (o *Options) headerName(name string) string {
+	switch o.HeaderCase {
+	case HeaderCaseCanonical:
+		return http.CanonicalHeaderKey(name)
+	case HeaderCasePreserve:
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// renameHeaders applies the configured casing to the names in a JSON headers
+// string, merging the values of names that collide. Headers are returned
+// unchanged when no casing is configured or they cannot be parsed.
+func (o *Options) renameHeaders(headersStr string) string {
+	if o.HeaderCase == "" || o.HeaderCase == HeaderCasePreserve {
+		return headersStr
+	}
+	headersMap, _ := decodeHeaders(headersStr)
+	if headersMap == nil {
+		return headersStr
+	}
+
+	renamed := make(map[string]interface{}, len(headersMap))
+	for name, value := range headersMap {
+		key := o.headerName(name)
+		existing, ok := renamed[key]
+		if !ok {
+			renamed[key] = value
+			continue
+		}
+		merged, _ := existing.([]interface{})
+		if merged == nil {
+			merged = []interface{}{existing}
+		}
+		if values, isList := value.([]interface{}); isList {
+			merged = append(merged, values...)
+		} else {
+			merged = append(merged, value)
+		}
+		renamed[key] = merged
+	}
+
+	encoded, err := json.Marshal(renamed)
+	if err != nil {
+		return headersStr
+	}
+	return string(encoded)
+}
+
+// truncatedMarker is appended to header values cut at MaxHeaderValueSize
+const truncatedMarker = "…[truncated]"
+
+// truncateHeaderValue cuts a value longer than MaxHeaderValueSize bytes at a
+// character boundary and marks it as truncated
+func (o *Options) truncateHeaderValue(value string) string {
+	if o.MaxHeaderValueSize <= 0 || len(value) <= o.MaxHeaderValueSize {
+		return value
+	}
+	cut := o.MaxHeaderValueSize
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + truncatedMarker
+}
+
+// dedupeValues removes repeated header values, keeping first occurrences in order
+func dedupeValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := values[:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// headerValue returns the first value of a header (JSON string or object),
+// matching the name case-insensitively
+func headerValue(raw interface{}, name string) string {
+	headersMap, _ := decodeHeaders(raw)
+	for key, value := range headersMap {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case []interface{}:
+			for _, item := range v {
+				if str, ok := item.(string); ok {
+					return str
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// rewriteHeaders applies fn to every value of a JSON headers string and
+// re-encodes it, returning the input unchanged when it cannot be parsed
+func rewriteHeaders(headersStr string, fn func(name string, value string) string) string {
+	headersMap, _ := decodeHeaders(headersStr)
+	if headersMap == nil {
+		return headersStr
+	}
+
+	for name, value := range headersMap {
+		switch v := value.(type) {
+		case string:
+			headersMap[name] = fn(name, v)
+		case []interface{}:
+			for i, item := range v {
+				if str, ok := item.(string); ok {
+					v[i] = fn(name, str)
+				}
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(headersMap)
+	if err != nil {
+		return headersStr
+	}
+	return string(encoded)
+}
+
+// filterHeaders removes headers the keep function rejects from a JSON headers
+// string and returns the re-encoded headers with the number removed. The input
+// is returned unchanged when it cannot be parsed or nothing is removed.
+func filterHeaders(headersStr string, keep func(name string) bool) (string, int) {
+	headersMap, _ := decodeHeaders(headersStr)
+	if headersMap == nil {
+		return headersStr, 0
+	}
+
+	dropped := 0
+	for name := range headersMap {
+		if !keep(name) {
+			delete(headersMap, name)
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return headersStr, 0
+	}
+
+	encoded, err := json.Marshal(headersMap)
+	if err != nil {
+		return headersStr, 0
+	}
+	return string(encoded), dropped
+}
+
+// rawHeaders renders headers (JSON string or object) for the protobuf raw
+// header fields. The raw copy gets the same credential redaction, value
+// truncation and drop/keep filtering as the parsed header maps, so it never
+// carries a header the parsed maps leave out.
+func (o *Options) rawHeaders(raw interface{}) string {
+	headers := headersString(raw)
+	if o.ExtractAuthScheme || o.MaxHeaderValueSize > 0 {
+		headers = rewriteHeaders(headers, func(name string, value string) string {
+			if o.ExtractAuthScheme && strings.EqualFold(name, "authorization") {
+				value = redactAuthorization(value)
+			}
+			return o.truncateHeaderValue(value)
+		})
+	}
+	headers, _ = filterHeaders(headers, func(name string) bool {
+		return !o.dropsHeader(name) && o.keepsHeader(name)
+	})
+	return headers
+}
@@ -0,0 +1,391 @@
+package transformer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"client-message-transformer/internal/logger"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDropHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		drop        []string
+		wantDropped []string
+		wantKept    []string
+	}{
+		{
+			name:        "hop-by-hop header",
+			drop:        []string{"connection"},
+			wantDropped: []string{"Connection"},
+			wantKept:    []string{"Content-Type", "X-Request-Id"},
+		},
+		{
+			name:        "matches names case-insensitively",
+			drop:        []string{"X-REQUEST-ID", "CONNECTION"},
+			wantDropped: []string{"Connection", "X-Request-Id"},
+			wantKept:    []string{"Content-Type"},
+		},
+		{
+			name:     "nothing listed",
+			wantKept: []string{"Connection", "Content-Type", "X-Request-Id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{DropHeaders: tt.drop}
+
+			flat := flatHeaders(t, transformFlat(t, sampleInput(), opts), "requestHeaders")
+			for _, name := range tt.wantDropped {
+				if _, ok := flat[name]; ok {
+					t.Errorf("flat requestHeaders still carry %s", name)
+				}
+			}
+			for _, name := range tt.wantKept {
+				if _, ok := flat[name]; !ok {
+					t.Errorf("flat requestHeaders lost %s", name)
+				}
+			}
+
+			payload := transformProto(t, sampleInput(), opts)
+			for _, name := range tt.wantDropped {
+				if _, ok := payload.RequestHeaders[opts.headerName(name)]; ok {
+					t.Errorf("proto request headers still carry %s", name)
+				}
+			}
+			for _, name := range tt.wantKept {
+				if _, ok := payload.RequestHeaders[opts.headerName(name)]; !ok {
+					t.Errorf("proto request headers lost %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDropHeadersResponse(t *testing.T) {
+	opts := &Options{DropHeaders: []string{"transfer-encoding"}}
+
+	flat := flatHeaders(t, transformFlat(t, sampleInput(), opts), "responseHeaders")
+	if _, ok := flat["Transfer-Encoding"]; ok {
+		t.Error("flat responseHeaders still carry Transfer-Encoding")
+	}
+	if flat["Content-Type"] != "application/json" {
+		t.Errorf("flat Content-Type = %v, want application/json", flat["Content-Type"])
+	}
+
+	payload := transformProto(t, sampleInput(), opts)
+	if _, ok := payload.ResponseHeaders["transfer-encoding"]; ok {
+		t.Error("proto response headers still carry transfer-encoding")
+	}
+	if _, ok := payload.ResponseHeaders["content-type"]; !ok {
+		t.Error("proto response headers lost content-type")
+	}
+}
+
+func TestObjectAndStringHeaders(t *testing.T) {
+	stringForm := sampleInput()
+	objectForm := sampleInput()
+	section(objectForm, "request")["headers"] = map[string]interface{}{
+		"Content-Type": "application/json",
+		"Connection":   "keep-alive",
+		"X-Request-Id": "abc",
+	}
+	section(objectForm, "response")["headers"] = map[string]interface{}{
+		"Content-Type":      "application/json",
+		"Transfer-Encoding": "identity",
+	}
+
+	opts := &Options{IncludeRawHeaders: true}
+	fromString := transformProto(t, stringForm, opts)
+	fromObject := transformProto(t, objectForm, opts)
+
+	// Raw strings differ only in key order, so compare them decoded
+	for _, raw := range [][2]string{
+		{fromString.RawRequestHeaders, fromObject.RawRequestHeaders},
+		{fromString.RawResponseHeaders, fromObject.RawResponseHeaders},
+	} {
+		stringHeaders, _ := decodeHeaders(raw[0])
+		objectHeaders, _ := decodeHeaders(raw[1])
+		if !reflect.DeepEqual(stringHeaders, objectHeaders) {
+			t.Errorf("raw headers differ: %s vs %s", raw[0], raw[1])
+		}
+	}
+	fromString.RawRequestHeaders, fromObject.RawRequestHeaders = "", ""
+	fromString.RawResponseHeaders, fromObject.RawResponseHeaders = "", ""
+	if !proto.Equal(fromString, fromObject) {
+		t.Errorf("object-form headers produced\n%v\nwant the string-form output\n%v", fromObject, fromString)
+	}
+
+	flatString := transformFlat(t, stringForm, &Options{})
+	flatObject := transformFlat(t, objectForm, &Options{})
+	for _, field := range []string{"requestHeaders", "responseHeaders"} {
+		if !reflect.DeepEqual(flatHeaders(t, flatString, field), flatHeaders(t, flatObject, field)) {
+			t.Errorf("flat %s differ: %s vs %s", field, flatString[field], flatObject[field])
+		}
+	}
+}
+
+func TestMalformedHeadersLogged(t *testing.T) {
+	var logs bytes.Buffer
+	input := sampleInput()
+	section(input, "request")["headers"] = "{not json"
+
+	payload := transformProto(t, input, &Options{Logger: logger.NewLogger("WARN", &logs)})
+	if len(payload.RequestHeaders) != 1 || payload.RequestHeaders["host"] == nil {
+		t.Errorf("request headers = %v, want only the derived host", payload.RequestHeaders)
+	}
+	if !strings.Contains(logs.String(), "failed to parse headers") {
+		t.Errorf("logs = %q, want the header parse failure through the injected logger", logs.String())
+	}
+}
+
+func TestCoalesceDuplicateHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      interface{}
+		coalesce bool
+		want     map[string][]string
+	}{
+		{
+			"exact duplicates collapse",
+			map[string]interface{}{"Accept": []interface{}{"text/html", "text/html", "text/html"}},
+			true,
+			map[string][]string{"accept": {"text/html"}},
+		},
+		{
+			"distinct values kept in order",
+			map[string]interface{}{"Set-Cookie": []interface{}{"a=1", "b=2", "a=1", "c=3"}},
+			true,
+			map[string][]string{"set-cookie": {"a=1", "b=2", "c=3"}},
+		},
+		{
+			"names differing in case merge",
+			`{"X-Tag":"blue","x-tag":"blue"}`,
+			true,
+			map[string][]string{"x-tag": {"blue"}},
+		},
+		{
+			"duplicates kept when disabled",
+			map[string]interface{}{"Accept": []interface{}{"text/html", "text/html"}},
+			false,
+			map[string][]string{"accept": {"text/html", "text/html"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := parseHeaders(tt.raw, &Options{CoalesceDuplicateHeaders: tt.coalesce, Logger: quietLogger})
+			got := make(map[string][]string, len(headers))
+			for name, list := range headers {
+				got[name] = list.Values
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHeaders = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		keep         []string
+		wantRequest  []string
+		wantResponse []string
+		wantDropped  interface{}
+	}{
+		{"subset", []string{"content-type", "x-request-id"}, []string{"Content-Type", "X-Request-Id"}, []string{"Content-Type"}, 2},
+		{"case-insensitive", []string{"CONTENT-TYPE"}, []string{"Content-Type"}, []string{"Content-Type"}, 3},
+		{"nothing matches", []string{"x-missing"}, []string{}, []string{}, 5},
+		{"no allowlist", nil, []string{"Connection", "Content-Type", "X-Request-Id"}, []string{"Content-Type", "Transfer-Encoding"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{KeepHeaders: tt.keep}
+			output := transformFlat(t, sampleInput(), opts)
+			if got := sortedKeys(flatHeaders(t, output, "requestHeaders")); !reflect.DeepEqual(got, tt.wantRequest) {
+				t.Errorf("flat request headers = %v, want %v", got, tt.wantRequest)
+			}
+			if got := sortedKeys(flatHeaders(t, output, "responseHeaders")); !reflect.DeepEqual(got, tt.wantResponse) {
+				t.Errorf("flat response headers = %v, want %v", got, tt.wantResponse)
+			}
+			if got := output["headersDropped"]; got != tt.wantDropped {
+				t.Errorf("headersDropped = %v, want %v", got, tt.wantDropped)
+			}
+
+			// Protobuf header names are lowercased
+			payload := transformProto(t, sampleInput(), opts)
+			for name := range payload.ResponseHeaders {
+				if !opts.keepsHeader(name) {
+					t.Errorf("proto response header %q is not on the allowlist", name)
+				}
+			}
+			if len(payload.ResponseHeaders) != len(tt.wantResponse) {
+				t.Errorf("proto response headers = %v, want %d", payload.ResponseHeaders, len(tt.wantResponse))
+			}
+		})
+	}
+}
+
+// sortedKeys returns the names of a headers map in order
+func sortedKeys(headers map[string]interface{}) []string {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestMaxHeaderValueSize(t *testing.T) {
+	long := strings.Repeat("a", 20)
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"normal value", "short", "short"},
+		{"exactly the limit", long[:10], long[:10]},
+		{"oversized value", long, long[:10] + truncatedMarker},
+		{"cut at a character boundary", "aaaaaaaaa€€", "aaaaaaaaa" + truncatedMarker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			headers, _ := json.Marshal(map[string]string{"X-Trace": tt.value})
+			section(input, "request")["headers"] = string(headers)
+			opts := &Options{MaxHeaderValueSize: 10}
+
+			if got := flatHeaders(t, transformFlat(t, input, opts), "requestHeaders")["X-Trace"]; got != tt.want {
+				t.Errorf("flat X-Trace = %q, want %q", got, tt.want)
+			}
+			if got := transformProto(t, input, opts).RequestHeaders["x-trace"].GetValues(); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("proto x-trace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderCase(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantFlat  string
+		wantProto string
+	}{
+		{"", "content-type", "content-type"}, // Flat headers keep the captured name
+		{HeaderCaseLower, "content-type", "content-type"},
+		{HeaderCaseCanonical, "Content-Type", "Content-Type"},
+		{HeaderCasePreserve, "content-type", "content-type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "response")["headers"] = `{"content-type":"application/json"}`
+			opts := &Options{HeaderCase: tt.mode}
+
+			if headers := flatHeaders(t, transformFlat(t, input, opts), "responseHeaders"); headers[tt.wantFlat] != "application/json" || len(headers) != 1 {
+				t.Errorf("flat response headers = %v, want only %q", headers, tt.wantFlat)
+			}
+			if headers := transformProto(t, input, opts).ResponseHeaders; headers[tt.wantProto] == nil || len(headers) != 1 {
+				t.Errorf("proto response headers = %v, want only %q", headers, tt.wantProto)
+			}
+		})
+	}
+}
+
+func TestHeaderCaseMergesCollisions(t *testing.T) {
+	opts := &Options{HeaderCase: HeaderCaseLower}
+	got := opts.renameHeaders(`{"Accept":"a","ACCEPT":["b","c"]}`)
+
+	var headers map[string][]string
+	if err := json.Unmarshal([]byte(got), &headers); err != nil {
+		t.Fatalf("renameHeaders = %s: %v", got, err)
+	}
+	values := headers["accept"]
+	sort.Strings(values)
+	if len(headers) != 1 || !reflect.DeepEqual(values, []string{"a", "b", "c"}) {
+		t.Errorf("renameHeaders = %s, want accept with a, b and c", got)
+	}
+}
+
+func TestRawHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         *Options
+		wantRequest  map[string]interface{}
+		wantResponse map[string]interface{}
+	}{
+		{
+			"carried",
+			&Options{},
+			map[string]interface{}{"Content-Type": "application/json", "Authorization": "Bearer secret-token", "Cookie": "session=abc"},
+			map[string]interface{}{"Content-Type": "application/json", "Set-Cookie": "session=abc"},
+		},
+		{
+			"auth redacted",
+			&Options{ExtractAuthScheme: true},
+			map[string]interface{}{"Content-Type": "application/json", "Authorization": "Bearer [REDACTED]", "Cookie": "session=abc"},
+			map[string]interface{}{"Content-Type": "application/json", "Set-Cookie": "session=abc"},
+		},
+		{
+			"dropped",
+			&Options{DropHeaders: []string{"authorization", "cookie", "set-cookie"}},
+			map[string]interface{}{"Content-Type": "application/json"},
+			map[string]interface{}{"Content-Type": "application/json"},
+		},
+		{
+			"kept",
+			&Options{KeepHeaders: []string{"content-type"}},
+			map[string]interface{}{"Content-Type": "application/json"},
+			map[string]interface{}{"Content-Type": "application/json"},
+		},
+		{
+			"values truncated",
+			&Options{MaxHeaderValueSize: 6},
+			map[string]interface{}{"Content-Type": "applic" + truncatedMarker, "Authorization": "Bearer" + truncatedMarker, "Cookie": "sessio" + truncatedMarker},
+			map[string]interface{}{"Content-Type": "applic" + truncatedMarker, "Set-Cookie": "sessio" + truncatedMarker},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["headers"] = `{"Content-Type":"application/json","Authorization":"Bearer secret-token","Cookie":"session=abc"}`
+			section(input, "response")["headers"] = `{"Content-Type":"application/json","Set-Cookie":"session=abc"}`
+
+			tt.opts.IncludeRawHeaders = true
+			payload := transformProto(t, input, tt.opts)
+			for _, raw := range []struct {
+				field string
+				got   string
+				want  map[string]interface{}
+			}{
+				{"raw_request_headers", payload.RawRequestHeaders, tt.wantRequest},
+				{"raw_response_headers", payload.RawResponseHeaders, tt.wantResponse},
+			} {
+				got, err := decodeHeaders(raw.got)
+				if err != nil {
+					t.Fatalf("decode %s: %v", raw.field, err)
+				}
+				if !reflect.DeepEqual(got, raw.want) {
+					t.Errorf("%s = %v, want %v", raw.field, got, raw.want)
+				}
+			}
+			if tt.opts.ExtractAuthScheme || len(tt.opts.DropHeaders) > 0 {
+				if strings.Contains(payload.RawRequestHeaders, "secret-token") {
+					t.Errorf("raw_request_headers = %s, want no credential", payload.RawRequestHeaders)
+				}
+			}
+
+			tt.opts.IncludeRawHeaders = false
+			if payload := transformProto(t, input, tt.opts); payload.RawRequestHeaders != "" || payload.RawResponseHeaders != "" {
+				t.Errorf("raw headers = %q / %q with IncludeRawHeaders off, want none", payload.RawRequestHeaders, payload.RawResponseHeaders)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldMapping declares where one output field comes from: a dot-separated
+// source path, with a default used when the path is missing, or a constant
+// value. A bare string is shorthand for a path.
+type FieldMapping struct {
+	Path    string      `yaml:"path"`
+	Default interface{} `yaml:"default"`
+	Value   interface{} `yaml:"value"`
+}
+
+// UnmarshalYAML accepts a bare path string or a full mapping object
+func (f *FieldMapping) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&f.Path)
+	}
+	type plain FieldMapping
+	return node.Decode((*plain)(f))
+}
+
+// Mapping maps output field names to their sources, replacing the built-in
+// mapping for upstreams that use a different message layout
+type Mapping map[string]FieldMapping
+
+// LoadMapping reads a mapping file. JSON files are read as YAML, which they
+// are a subset of.
+func LoadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var mapping Mapping
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+	if len(mapping) == 0 {
+		return nil, fmt.Errorf("mapping file %s declares no fields", path)
+	}
+	for key, field := range mapping {
+		if (field.Path == "") == (field.Value == nil) {
+			return nil, fmt.Errorf("mapping for %q needs exactly one of path or value", key)
+		}
+	}
+	return mapping, nil
+}
+
+// nestedFields are the output fields the built-in transformation derives,
+// with the section and field of the nested client format each comes from
+var nestedFields = map[string][2]string{
+	"path":            {"request", "url"},
+	"method":          {"request", "method"},
+	"requestHeaders":  {"request", "headers"},
+	"requestPayload":  {"request", "body"},
+	"responseHeaders": {"response", "headers"},
+	"responsePayload": {"response", "body"},
+	"statusCode":      {"response", "statusCode"},
+	"ip":              {"info", "ip"},
+	"time":            {"info", "dateTime"},
+	"responseTime":    {"info", "responseTime"},
+}
+
+// value resolves a field against input, reporting false when neither the path
+// nor a default yields one
+func (f FieldMapping) value(input map[string]interface{}) (interface{}, bool) {
+	if f.Value != nil {
+		return f.Value, true
+	}
+	if value, ok := lookupPath(input, f.Path); ok && value != nil {
+		return value, true
+	}
+	return f.Default, f.Default != nil
+}
+
+// nested rewrites input into the nested client format from the mapped fields
+// the built-in transformation derives, so every transformation step applies
+// to them. Non-string bodies are carried as JSON.
+func (m Mapping) nested(input map[string]interface{}) map[string]interface{} {
+	nested := map[string]interface{}{"request": map[string]interface{}{}}
+	for key, field := range m {
+		target, ok := nestedFields[key]
+		if !ok {
+			continue
+		}
+		value, ok := field.value(input)
+		if !ok {
+			continue
+		}
+		value = mappedNumber(value)
+		if target[1] == "body" {
+			if _, isString := value.(string); !isString {
+				encoded, _ := json.Marshal(value)
+				value = string(encoded)
+			}
+		}
+		section, ok := nested[target[0]].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			nested[target[0]] = section
+		}
+		section[target[1]] = value
+	}
+	return nested
+}
+
+// project sets the remaining mapped fields on a fully transformed record,
+// replacing what the built-in transformation derived for them
+func (m Mapping) project(input map[string]interface{}, output map[string]interface{}) {
+	for key, field := range m {
+		if _, ok := nestedFields[key]; ok {
+			continue
+		}
+		if value, ok := field.value(input); ok {
+			output[key] = value
+		}
+	}
+}
+
+// mappedNumber converts the integers YAML constants decode to into the
+// float64 JSON inputs use
+func mappedNumber(value interface{}) interface{} {
+	if v, ok := value.(int); ok {
+		return float64(v)
+	}
+	return value
+}
+
+// lookupPath follows a dot-separated path through nested objects
+func lookupPath(input map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = input
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,199 @@
+package transformer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// reqResMapping maps an upstream that nests captures under req/res/meta
+const reqResMapping = `
+path: req.uri
+method: req.verb
+requestHeaders: req.headers
+requestPayload: req.body
+responseHeaders: res.headers
+responsePayload: res.body
+statusCode: {path: res.status, default: 200}
+ip: meta.client
+time: meta.ts
+type: {value: HTTP/2}
+region: {path: meta.region, default: unknown}
+`
+
+// reqResInput is a capture in the req/res/meta layout
+func reqResInput() map[string]interface{} {
+	return map[string]interface{}{
+		"req": map[string]interface{}{
+			"uri":     "/users?id=1",
+			"verb":    "POST",
+			"headers": map[string]interface{}{"Content-Type": "application/json", "Authorization": "Bearer secret-token", "Host": "api.example.com"},
+			"body":    map[string]interface{}{"name": "alice"},
+		},
+		"res": map[string]interface{}{
+			"status":  float64(201),
+			"headers": `{"Content-Type":"application/json"}`,
+			"body":    `{"id":1}`,
+		},
+		"meta": map[string]interface{}{"client": "203.0.113.7", "ts": float64(1700000000)},
+	}
+}
+
+// writeMapping loads a mapping from a file in the test's temp directory
+func writeMapping(t *testing.T, name string, content string) Mapping {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write mapping: %v", err)
+	}
+	mapping, err := LoadMapping(path)
+	if err != nil {
+		t.Fatalf("LoadMapping: %v", err)
+	}
+	return mapping
+}
+
+func TestLoadMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"yaml", "mapping.yaml", reqResMapping, ""},
+		{"json", "mapping.json", `{"path": "req.uri", "type": {"value": "HTTP/1.1"}}`, ""},
+		{"empty", "mapping.yaml", "", "declares no fields"},
+		{"path and value", "mapping.yaml", "type: {path: req.proto, value: HTTP/1.1}", `mapping for "type" needs exactly one of path or value`},
+		{"neither path nor value", "mapping.yaml", "type: {default: HTTP/1.1}", `mapping for "type" needs exactly one of path or value`},
+		{"not a mapping", "mapping.yaml", "- path", "failed to parse mapping file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("write mapping: %v", err)
+			}
+			_, err := LoadMapping(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadMapping: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadMapping error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadMapping(filepath.Join(t.TempDir(), "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read mapping file") {
+		t.Errorf("LoadMapping of a missing file = %v, want a read error", err)
+	}
+}
+
+func TestMapping(t *testing.T) {
+	mapping := writeMapping(t, "mapping.yaml", reqResMapping)
+	tests := []struct {
+		name   string
+		modify func(input map[string]interface{})
+		want   map[string]interface{}
+	}{
+		{"mapped fields", func(input map[string]interface{}) {}, map[string]interface{}{
+			"path":            "/users?id=1",
+			"method":          "POST",
+			"requestPayload":  `{"name":"alice"}`,
+			"responsePayload": `{"id":1}`,
+			"statusCode":      "201",
+			"status":          "Created",
+			"contentType":     "application/json",
+			"ip":              "203.0.113.7",
+			"time":            "1700000000",
+			"type":            "HTTP/2",
+			"region":          "unknown",
+			"queryParamCount": 1,
+			"akto_account_id": "1000",
+			"source":          "MIRRORING",
+		}},
+		{"defaults for missing paths", func(input map[string]interface{}) {
+			delete(section(input, "res"), "status")
+			section(input, "meta")["region"] = "eu-west-1"
+		}, map[string]interface{}{"statusCode": "200", "status": "OK", "region": "eu-west-1"}},
+		{"missing path without a default", func(input map[string]interface{}) {
+			delete(section(input, "req"), "verb")
+		}, map[string]interface{}{"method": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := reqResInput()
+			tt.modify(input)
+
+			output := transformFlat(t, input, &Options{Mapping: mapping})
+			for key, want := range tt.want {
+				// Compared as text since numeric fields are int or int64
+				if got := output[key]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMappingWithOptions(t *testing.T) {
+	mapping := writeMapping(t, "mapping.yaml", reqResMapping)
+	tests := []struct {
+		name          string
+		opts          *Options
+		wantResponse  string
+		wantAuth      string // Authorization value in the forwarded request headers
+		wantScheme    interface{}
+		wantTruncated bool
+	}{
+		{"mapping alone", &Options{}, `{"id":1}`, "Bearer secret-token", nil, false},
+		{"drop response body", &Options{DropResponseBody: true}, "", "Bearer secret-token", nil, false},
+		{"extract auth scheme", &Options{ExtractAuthScheme: true}, `{"id":1}`, "Bearer [REDACTED]", "Bearer", false},
+		{"both", &Options{DropResponseBody: true, ExtractAuthScheme: true}, "", "Bearer [REDACTED]", "Bearer", false},
+		{"drop authorization", &Options{DropHeaders: []string{"authorization"}}, `{"id":1}`, "", nil, false},
+		{"truncated bodies", &Options{MaxBodyBytes: 4}, `{"id`, "Bearer secret-token", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Mapping = mapping
+			output := transformFlat(t, reqResInput(), tt.opts)
+			if output["responsePayload"] != tt.wantResponse {
+				t.Errorf("flat responsePayload = %q, want %q", output["responsePayload"], tt.wantResponse)
+			}
+			if got := headerValue(output["requestHeaders"], "authorization"); got != tt.wantAuth {
+				t.Errorf("flat Authorization = %q, want %q", got, tt.wantAuth)
+			}
+			if output["authScheme"] != tt.wantScheme {
+				t.Errorf("flat authScheme = %v, want %v", output["authScheme"], tt.wantScheme)
+			}
+			if truncated, _ := output[FieldBodyTruncated].(bool); truncated != tt.wantTruncated {
+				t.Errorf("flat %s = %v, want %v", FieldBodyTruncated, truncated, tt.wantTruncated)
+			}
+			if output["type"] != "HTTP/2" || output["method"] != "POST" {
+				t.Errorf("flat record lost mapped fields: %v", output)
+			}
+
+			tt.opts.IncludeRawHeaders = true
+			payload, truncation, err := TransformToProto(encodeInput(t, reqResInput()), "1000", tt.opts)
+			if err != nil {
+				t.Fatalf("TransformToProto: %v", err)
+			}
+			if payload.ResponsePayload != tt.wantResponse {
+				t.Errorf("proto ResponsePayload = %q, want %q", payload.ResponsePayload, tt.wantResponse)
+			}
+			if strings.Contains(payload.RawRequestHeaders, "secret-token") != (tt.wantAuth == "Bearer secret-token") {
+				t.Errorf("proto raw_request_headers = %s, want Authorization %q", payload.RawRequestHeaders, tt.wantAuth)
+			}
+			if truncation.Truncated() != tt.wantTruncated {
+				t.Errorf("proto truncation = %+v, want truncated %v", truncation, tt.wantTruncated)
+			}
+			if payload.Type != "HTTP/2" || payload.StatusCode != 201 || payload.Method != "POST" {
+				t.Errorf("proto payload lost mapped fields: %v", payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package transformer
+
+import (
+	"client-message-transformer/internal/logger"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options controls optional transformation behavior
+type Options struct {
+	// Logger receives transformer logs; nil logs at INFO
+	Logger *logger.Logger
+
+	// CompactLog demotes per-message progress logs from INFO to DEBUG
+	CompactLog bool
+
+	// MaxJSONDepth rejects inputs nested deeper than this with ErrJSONTooDeep (0 disables)
+	MaxJSONDepth int
+
+	// DropHeaders lists header names removed from the flat and protobuf headers
+	DropHeaders []string
+
+	// KeepHeaders, when set, is an allowlist: only these headers are forwarded
+	KeepHeaders []string
+
+	// HeaderCase sets header-name casing: HeaderCaseLower, HeaderCaseCanonical
+	// or HeaderCasePreserve. Unset lowercases parsed header maps and leaves
+	// flat header strings as captured.
+	HeaderCase string
+
+	// MaxHeaderValueSize truncates longer header values (0 disables)
+	MaxHeaderValueSize int
+
+	// CoalesceDuplicateHeaders collapses repeated identical header values
+	CoalesceDuplicateHeaders bool
+
+	// ExtractAPIVersion emits an apiVersion field parsed from the path or headers
+	ExtractAPIVersion bool
+
+	// APIVersionHeader names the request header carrying an explicit API version
+	APIVersionHeader string
+
+	// TimeFormat selects the flat "time" field format: TimeFormatEpoch or TimeFormatRFC3339
+	TimeFormat string
+
+	// DateTimeUnit is the unit of info.dateTime (DateTimeUnitSeconds and so
+	// on); unset or DateTimeUnitAuto infers it from the value's magnitude
+	DateTimeUnit string
+
+	// PayloadEncoding names the encoding applied to request/response bodies
+	PayloadEncoding string
+
+	// DecodeFailurePolicy decides what happens to bodies that fail to decode:
+	// DecodeFailurePolicyFail (the default) or DecodeFailurePolicyPassthroughRaw
+	DecodeFailurePolicy string
+
+	// DecodeBase64Bodies replaces base64 bodies with their decoded text, leaving
+	// bodies that are not base64 or decode to binary as they are. Applies
+	// only without a PayloadEncoding, whose bodies are always base64.
+	DecodeBase64Bodies bool
+
+	// ParseFormBody emits url-encoded form request bodies as a formParams map
+	ParseFormBody bool
+
+	// DechunkBodies strips chunk framing from bodies sent with
+	// Transfer-Encoding: chunked
+	DechunkBodies bool
+
+	// SniffContentType emits best-effort MIME types detected from the response
+	// (sniffedContentType) and request (requestSniffedContentType) bodies
+	SniffContentType bool
+
+	// IncludeRawHeaders carries the header strings in the protobuf
+	// raw_request_headers / raw_response_headers fields, after redaction and
+	// the drop/keep filters
+	IncludeRawHeaders bool
+
+	// StructuredBody additionally emits JSON bodies as nested objects
+	// (requestBodyJson / responseBodyJson)
+	StructuredBody bool
+
+	// MaxBodyBytes cuts longer request and response bodies, marking the output
+	// with body_truncated and the original lengths (0 disables)
+	MaxBodyBytes int
+
+	// AllowResponseOnly accepts captures without a request section, as sent
+	// when partial captures are joined; otherwise they fail with a ShapeError
+	AllowResponseOnly bool
+
+	// DropResponseBody blanks response payloads while keeping headers and status
+	DropResponseBody bool
+
+	// ExtractAuthScheme emits the Authorization scheme as authScheme and
+	// redacts the credential from the forwarded headers. Values without a
+	// known scheme report "unknown" and are redacted whole.
+	ExtractAuthScheme bool
+
+	// AuthHeaders lists headers whose presence marks a request as authenticated
+	AuthHeaders []string
+
+	// InternalHostSuffixes marks requests to matching hosts with isInternal=true
+	InternalHostSuffixes []string
+
+	// HostToCollection overrides the API collection ID derived for a host
+	HostToCollection map[string]int32
+
+	// Mapping, when set, replaces the built-in sources of output fields. The
+	// mapped record goes through every other transformation step.
+	Mapping Mapping
+}
+
+// Supported flat "time" field formats
+const (
+	TimeFormatEpoch   = "epoch"
+	TimeFormatRFC3339 = "rfc3339"
+)
+
+// defaultOptions is used when callers pass nil options
+var defaultOptions = &Options{}
+
+// orDefault returns the options or the package defaults when nil
+func (o *Options) orDefault() *Options {
+	if o == nil {
+		return defaultOptions
+	}
+	return o
+}
+
+// defaultLogger is used when no Logger is configured
+var defaultLogger = logger.NewLogger("INFO", nil)
+
+// log returns the configured logger or the package default
+func (o *Options) log() *logger.Logger {
+	if o.Logger == nil {
+		return defaultLogger
+	}
+	return o.Logger
+}
+
+// progressf logs per-message progress at INFO, or DEBUG in compact mode
+func (o *Options) progressf(format string, args ...interface{}) {
+	if o.CompactLog {
+		o.log().Debugf(format, args...)
+		return
+	}
+	o.log().Infof(format, args...)
+}
+
+// dropsHeader reports whether a header should be removed
+func (o *Options) dropsHeader(name string) bool {
+	for _, dropped := range o.DropHeaders {
+		if strings.EqualFold(dropped, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepsHeader reports whether a header passes the KeepHeaders allowlist
+func (o *Options) keepsHeader(name string) bool {
+	if len(o.KeepHeaders) == 0 {
+		return true
+	}
+	for _, kept := range o.KeepHeaders {
+		if strings.EqualFold(kept, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatTime renders epoch seconds in the configured output format
+func (o *Options) formatTime(seconds int64) string {
+	if o.TimeFormat == TimeFormatRFC3339 {
+		return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	}
+	return strconv.FormatInt(seconds, 10)
+}
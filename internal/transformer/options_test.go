@@ -0,0 +1,63 @@
+package transformer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"client-message-transformer/internal/logger"
+)
+
+func TestTimeFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "1700000000"},
+		{TimeFormatEpoch, "1700000000"},
+		{TimeFormatRFC3339, "2023-11-14T22:13:20Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			output := transformFlat(t, sampleInput(), &Options{TimeFormat: tt.format})
+			if output["time"] != tt.want {
+				t.Errorf("time = %v, want %s", output["time"], tt.want)
+			}
+
+			// Either format converts back to the same protobuf time
+			payload, err := TransformToProtoFromFlat(output, &Options{})
+			if err != nil {
+				t.Fatalf("TransformToProtoFromFlat: %v", err)
+			}
+			if payload.Time != 1700000000 {
+				t.Errorf("proto time = %d, want 1700000000", payload.Time)
+			}
+		})
+	}
+}
+
+func TestComponentLogger(t *testing.T) {
+	tests := []struct {
+		level     string
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{"DEBUG", true, true},
+		{"INFO", false, true},
+		{"ERROR", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			transformFlat(t, sampleInput(), &Options{Logger: logger.NewLogger(tt.level, &buf)})
+
+			logs := buf.String()
+			if got := strings.Contains(logs, "Input size"); got != tt.wantDebug {
+				t.Errorf("debug lines logged = %v, want %v:\n%s", got, tt.wantDebug, logs)
+			}
+			if got := strings.Contains(logs, "Transformation completed"); got != tt.wantInfo {
+				t.Errorf("progress lines logged = %v, want %v:\n%s", got, tt.wantInfo, logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package transformer
+
+import "sync"
+
+// outputPool recycles flat output maps between messages to ease GC pressure
+// at high throughput
+var outputPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[string]interface{}, 32)
+	},
+}
+
+// newOutput returns an empty output map, reusing a released one when available
+func newOutput() map[string]interface{} {
+	return outputPool.Get().(map[string]interface{})
+}
+
+// ReleaseOutput returns a map produced by TransformMessage to the pool. The
+// caller must not touch the map, or anything still referencing it, afterwards.
+func ReleaseOutput(output map[string]interface{}) {
+	if output == nil {
+		return
+	}
+	clear(output)
+	outputPool.Put(output)
+}
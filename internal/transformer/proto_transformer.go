@@ -2,22 +2,45 @@ package transformer
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	trafficpb "client-message-transformer/protobuf/traffic_payload"
 )
 
-// TransformToProto converts the transformed message to protobuf format
-func TransformToProto(data []byte, clientID string) (*trafficpb.HttpResponseParam, error) {
-	log.Printf("🔄 [PROTO TRANSFORMER] Starting protobuf transformation for client: %s", clientID)
+// TransformToProto converts the transformed message to protobuf format. The
+// returned Truncation reports bodies cut at MaxBodyBytes, which the protobuf
+// message has no field for.
+func TransformToProto(data []byte, clientID string, opts *Options) (*trafficpb.HttpResponseParam, Truncation, error) {
+	opts = opts.orDefault()
+	log := opts.log()
+
+	// Mapped fields outside the protobuf message's nested sources only exist
+	// on the flat record, so mapped messages go through it
+	if opts.Mapping != nil {
+		flat, err := TransformMessage(data, clientID, opts)
+		if err != nil {
+			return nil, Truncation{}, err
+		}
+		defer ReleaseOutput(flat)
+		payload, err := TransformToProtoFromFlat(flat, opts)
+		return payload, truncationOf(flat), err
+	}
+
+	opts.progressf("🔄 [PROTO TRANSFORMER] Starting protobuf transformation for client: %s", clientID)
+
+	if err := checkJSONDepth(data, opts.MaxJSONDepth); err != nil {
+		log.Errorf("❌ [PROTO TRANSFORMER] %v", err)
+		return nil, Truncation{}, err
+	}
 
 	var input map[string]interface{}
 	err := json.Unmarshal(data, &input)
 	if err != nil {
-		log.Printf("❌ [PROTO TRANSFORMER] JSON parse error: %v", err)
-		return nil, err
+		log.Errorf("❌ [PROTO TRANSFORMER] JSON parse error: %v", err)
+		return nil, Truncation{}, err
 	}
 
 	// Helper to safely get nested value
@@ -61,60 +84,70 @@ func TransformToProto(data []byte, clientID string) (*trafficpb.HttpResponsePara
 		return 0
 	}
 
-	// Parse headers helper
-	parseHeaders := func(headersStr string) map[string]*trafficpb.StringList {
-		headers := make(map[string]*trafficpb.StringList)
-		if headersStr == "" {
-			return headers
-		}
-
-		var headersMap map[string]interface{}
-		err := json.Unmarshal([]byte(headersStr), &headersMap)
-		if err != nil {
-			log.Printf("⚠️  [PROTO TRANSFORMER] Failed to parse headers: %v", err)
-			return headers
-		}
-
-		for name, value := range headersMap {
-			var values []string
-			switch v := value.(type) {
-			case string:
-				values = []string{v}
-			case []interface{}:
-				for _, item := range v {
-					if str, ok := item.(string); ok {
-						values = append(values, str)
-					}
-				}
-			}
-			headers[strings.ToLower(name)] = &trafficpb.StringList{
-				Values: values,
-			}
-		}
-		return headers
-	}
-
 	// Extract from nested payload structure
-	request, _ := input["request"].(map[string]interface{})
+	request, response, _, err := captureSections(input, opts.AllowResponseOnly)
+	if err != nil {
+		log.Errorf("❌ [PROTO TRANSFORMER] %v", err)
+		return nil, Truncation{}, err
+	}
 	fullURL := getNestedString(request, "url")
+	if fullURL == "" {
+		fullURL = urlFromParts(getNestedString(request, "scheme"), getNestedString(request, "host"), getNestedString(request, "path"))
+	}
 	path := extractURI(fullURL)
 	method := getNestedString(request, "method")
-	requestHeaders := getNestedString(request, "headers")
-	requestPayload := getNestedString(request, "body")
+	httpVersion := defaultHTTPVersion
+
+	// Some clients only send the raw request line
+	if method == "" && fullURL == "" {
+		if m, target, version, ok := parseRequestLine(getNestedString(request, "requestLine")); ok {
+			method, fullURL, httpVersion = m, target, version
+			path = extractURI(fullURL)
+		}
+	}
+	requestHeaders := request["headers"] // JSON string or already-parsed object
+	requestPayload, _, err := opts.decodeBodyWithPolicy(getNestedString(request, "body"))
+	if err != nil {
+		log.Errorf("❌ [PROTO TRANSFORMER] Request body decode error: %v", err)
+		return nil, Truncation{}, fmt.Errorf("request body: %w", err)
+	}
+	requestPayload = opts.dechunkIfNeeded(requestPayload, requestHeaders)
 
 	// Response fields
-	response, _ := input["response"].(map[string]interface{})
-	responseHeaders := getNestedString(response, "headers")
-	responsePayload := getNestedString(response, "body")
-	statusCode := int32(getNestedFloat(response, "statusCode"))
+	responseHeaders := response["headers"] // JSON string or already-parsed object
+	responsePayload, _, err := opts.decodeBodyWithPolicy(getNestedString(response, "body"))
+	if err != nil {
+		log.Errorf("❌ [PROTO TRANSFORMER] Response body decode error: %v", err)
+		return nil, Truncation{}, fmt.Errorf("response body: %w", err)
+	}
+	responsePayload = opts.dechunkIfNeeded(responsePayload, responseHeaders)
+	if opts.DropResponseBody {
+		responsePayload = ""
+	}
+	statusCode := int32(parseStatusCode(response["statusCode"]))
 
 	// Info fields
 	info, _ := input["info"].(map[string]interface{})
 	clientIP := getNestedString(info, "ip")
 	dateTime := int64(getNestedFloat(info, "dateTime"))
 
+	// Strip gRPC-Web framing so bodies carry the bare messages
+	if contentType := headerValue(requestHeaders, "content-type"); isGRPCWeb(contentType) {
+		requestPayload, _ = unframeGRPCWeb(requestPayload, contentType)
+	}
+	if contentType := headerValue(responseHeaders, "content-type"); isGRPCWeb(contentType) {
+		responsePayload, _ = unframeGRPCWeb(responsePayload, contentType)
+	}
+
+	var truncation Truncation
+	requestPayload, truncation.RequestLength = opts.truncateBody(requestPayload)
+	responsePayload, truncation.ResponseLength = opts.truncateBody(responsePayload)
+	if truncation.Truncated() {
+		log.Warnf("⚠️  [PROTO TRANSFORMER] Body exceeded %d bytes, truncated", opts.MaxBodyBytes)
+	}
+
 	// Parse headers into protobuf format
-	reqHeaderMap := parseHeaders(requestHeaders)
+	reqHeaderMap := parseHeaders(requestHeaders, opts)
 
 	// Add host header
 	if host := extractHostFromURL(fullURL); host != "" {
@@ -123,36 +156,60 @@ func TransformToProto(data []byte, clientID string) (*trafficpb.HttpResponsePara
 		}
 	}
 
-	respHeaderMap := parseHeaders(responseHeaders)
+	if opts.ExtractAuthScheme {
+		// Header names follow HEADER_CASE, so match the name in any case
+		for name, values := range reqHeaderMap {
+			if !strings.EqualFold(name, "authorization") {
+				continue
+			}
+			for i, value := range values.Values {
+				values.Values[i] = redactAuthorization(value)
+			}
+		}
+	}
+
+	respHeaderMap := parseHeaders(responseHeaders, opts)
+
+	var apiCollectionID int32
+	if host := requestHost(fullURL, requestHeaders); host != "" {
+		apiCollectionID = opts.apiCollectionID(host)
+	}
 
 	// Build protobuf message
 	payload := &trafficpb.HttpResponseParam{
 		Method:          method,
 		Path:            path,
-		Type:            "HTTP/1.1",
+		Type:            httpVersion,
+		ApiCollectionId: apiCollectionID,
 		RequestHeaders:  reqHeaderMap,
 		RequestPayload:  requestPayload,
 		ResponseHeaders: respHeaderMap,
 		ResponsePayload: responsePayload,
 		Ip:              clientIP,
-		Time:            int32(dateTime / 1000), // Convert to seconds
+		Time:            int32(opts.epochSeconds(dateTime)),
 		StatusCode:      statusCode,
 		Status:          getStatus(int(statusCode)),
 		AktoAccountId:   clientID,
-		AktoVxlanId:     "0",      // Default value
-		IsPending:       false,     // Default value
+		AktoVxlanId:     "0",   // Default value
+		IsPending:       false, // Default value
 		Source:          "MIRRORING",
-		Direction:       "",        // Not available in client message
-		DestIp:          "",        // Not available in client message
+		Direction:       "", // Not available in client message
+		DestIp:          "", // Not available in client message
+	}
+	if opts.IncludeRawHeaders {
+		payload.RawRequestHeaders = opts.rawHeaders(requestHeaders)
+		payload.RawResponseHeaders = opts.rawHeaders(responseHeaders)
 	}
 
-	log.Printf("✅ [PROTO TRANSFORMER] Protobuf transformation completed - Method: %s, Path: %s, Status: %d", method, path, statusCode)
+	opts.progressf("✅ [PROTO TRANSFORMER] Protobuf transformation completed - Method: %s, Path: %s, Status: %d", method, path, statusCode)
 
-	return payload, nil
+	return payload, truncation, nil
 }
 
 // TransformToProtoFromFlat converts the flat JSON format to protobuf format
-func TransformToProtoFromFlat(flatData map[string]interface{}) (*trafficpb.HttpResponseParam, error) {
+func TransformToProtoFromFlat(flatData map[string]interface{}, opts *Options) (*trafficpb.HttpResponseParam, error) {
+	opts = opts.orDefault()
+
 	// Helper to safely get string from map
 	getString := func(key string) string {
 		if val, ok := flatData[key]; ok {
@@ -179,42 +236,13 @@ func TransformToProtoFromFlat(flatData map[string]interface{}) (*trafficpb.HttpR
 				if i, err := strconv.ParseInt(v, 10, 32); err == nil {
 					return int32(i)
 				}
-			}
-		}
-		return 0
-	}
-
-	// Parse headers helper
-	parseHeaders := func(headersStr string) map[string]*trafficpb.StringList {
-		headers := make(map[string]*trafficpb.StringList)
-		if headersStr == "" {
-			return headers
-		}
-
-		var headersMap map[string]interface{}
-		err := json.Unmarshal([]byte(headersStr), &headersMap)
-		if err != nil {
-			log.Printf("⚠️  [PROTO TRANSFORMER] Failed to parse headers: %v", err)
-			return headers
-		}
-
-		for name, value := range headersMap {
-			var values []string
-			switch v := value.(type) {
-			case string:
-				values = []string{v}
-			case []interface{}:
-				for _, item := range v {
-					if str, ok := item.(string); ok {
-						values = append(values, str)
-					}
+				// The flat "time" field may be rendered as RFC3339
+				if t, err := time.Parse(time.RFC3339, v); err == nil {
+					return int32(t.Unix())
 				}
 			}
-			headers[strings.ToLower(name)] = &trafficpb.StringList{
-				Values: values,
-			}
 		}
-		return headers
+		return 0
 	}
 
 	requestHeaders := getString("requestHeaders")
@@ -225,9 +253,10 @@ func TransformToProtoFromFlat(flatData map[string]interface{}) (*trafficpb.HttpR
 		Method:          getString("method"),
 		Path:            getString("path"),
 		Type:            getString("type"),
-		RequestHeaders:  parseHeaders(requestHeaders),
+		ApiCollectionId: getInt32("apiCollectionId"),
+		RequestHeaders:  parseHeaders(requestHeaders, opts),
 		RequestPayload:  getString("requestPayload"),
-		ResponseHeaders: parseHeaders(responseHeaders),
+		ResponseHeaders: parseHeaders(responseHeaders, opts),
 		ResponsePayload: getString("responsePayload"),
 		Ip:              getString("ip"),
 		Time:            getInt32("time"),
@@ -240,6 +269,10 @@ func TransformToProtoFromFlat(flatData map[string]interface{}) (*trafficpb.HttpR
 		Direction:       "",
 		DestIp:          "",
 	}
+	if opts.IncludeRawHeaders {
+		payload.RawRequestHeaders = requestHeaders
+		payload.RawResponseHeaders = responseHeaders
+	}
 
 	return payload, nil
 }
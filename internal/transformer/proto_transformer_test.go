@@ -0,0 +1,43 @@
+package transformer
+
+import (
+	"testing"
+
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProtoRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+	}{
+		{"defaults", &Options{}},
+		{"auth scheme extracted", &Options{ExtractAuthScheme: true}},
+		{"response body dropped", &Options{DropResponseBody: true}},
+		{"raw headers", &Options{IncludeRawHeaders: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["headers"] = `{"Content-Type":"application/json","Authorization":"Bearer secret-token"}`
+			payload := transformProto(t, input, tt.opts)
+
+			data, err := proto.Marshal(payload)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var decoded trafficpb.HttpResponseParam
+			if err := proto.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !proto.Equal(payload, &decoded) {
+				t.Errorf("round trip changed the payload:\n got  %v\n want %v", &decoded, payload)
+			}
+			if decoded.Method != "GET" || decoded.Path != "/users?id=1" || decoded.StatusCode != 200 || decoded.AktoAccountId != "1000" {
+				t.Errorf("decoded payload = %v, want GET /users?id=1 200 for account 1000", &decoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package transformer
+
+import "strings"
+
+// defaultHTTPVersion is used when the message does not carry a protocol version
+const defaultHTTPVersion = "HTTP/1.1"
+
+// parseRequestLine splits an HTTP request line such as "GET /x HTTP/1.1"
+// into its method, request target and protocol version
+func parseRequestLine(line string) (method, target, version string, ok bool) {
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 2:
+		// HTTP/0.9 style lines omit the version
+		return fields[0], fields[1], defaultHTTPVersion, true
+	case 3:
+		if !strings.HasPrefix(strings.ToUpper(fields[2]), "HTTP/") {
+			return "", "", "", false
+		}
+		return fields[0], fields[1], strings.ToUpper(fields[2]), true
+	}
+	return "", "", "", false
+}
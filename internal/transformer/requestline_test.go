@@ -0,0 +1,63 @@
+package transformer
+
+import "testing"
+
+func TestParseRequestLine(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantMethod  string
+		wantTarget  string
+		wantVersion string
+		wantOK      bool
+	}{
+		{"GET /users?id=1 HTTP/1.1", "GET", "/users?id=1", "HTTP/1.1", true},
+		{"POST /login http/2", "POST", "/login", "HTTP/2", true},
+		{"GET /legacy", "GET", "/legacy", defaultHTTPVersion, true},
+		{"GET /x SPDY/3", "", "", "", false},
+		{"GET", "", "", "", false},
+		{"", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			method, target, version, ok := parseRequestLine(tt.line)
+			if method != tt.wantMethod || target != tt.wantTarget || version != tt.wantVersion || ok != tt.wantOK {
+				t.Errorf("parseRequestLine(%q) = (%q, %q, %q, %v), want (%q, %q, %q, %v)", tt.line,
+					method, target, version, ok, tt.wantMethod, tt.wantTarget, tt.wantVersion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTransformRequestLine(t *testing.T) {
+	requestLineOnly := sampleInput()
+	request := section(requestLineOnly, "request")
+	delete(request, "url")
+	delete(request, "method")
+	request["requestLine"] = "DELETE /users/7?force=true HTTP/2"
+
+	tests := []struct {
+		name        string
+		input       map[string]interface{}
+		wantMethod  string
+		wantPath    string
+		wantVersion string
+	}{
+		{"requestLine only", requestLineOnly, "DELETE", "/users/7?force=true", "HTTP/2"},
+		{"discrete fields", sampleInput(), "GET", "/users?id=1", defaultHTTPVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := transformFlat(t, tt.input, &Options{})
+			if output["method"] != tt.wantMethod || output["path"] != tt.wantPath || output["type"] != tt.wantVersion {
+				t.Errorf("method, path, type = (%v, %v, %v), want (%s, %s, %s)",
+					output["method"], output["path"], output["type"], tt.wantMethod, tt.wantPath, tt.wantVersion)
+			}
+
+			payload := transformProto(t, tt.input, &Options{})
+			if payload.Method != tt.wantMethod || payload.Path != tt.wantPath || payload.Type != tt.wantVersion {
+				t.Errorf("proto method, path, type = (%s, %s, %s), want (%s, %s, %s)",
+					payload.Method, payload.Path, payload.Type, tt.wantMethod, tt.wantPath, tt.wantVersion)
+			}
+		})
+	}
+}
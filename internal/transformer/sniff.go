@@ -0,0 +1,21 @@
+package transformer
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// sniffContentType makes a best-effort guess at a body's MIME type. It extends
+// http.DetectContentType, which reports JSON as plain text, with JSON detection.
+func sniffContentType(body string) string {
+	if body == "" {
+		return ""
+	}
+
+	trimmed := strings.TrimSpace(body)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "application/json"
+	}
+	return http.DetectContentType([]byte(body))
+}
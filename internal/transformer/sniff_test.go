@@ -0,0 +1,63 @@
+package transformer
+
+import "testing"
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"JSON object", `{"id":1}`, "application/json"},
+		{"JSON array with whitespace", "  [1, 2]\n", "application/json"},
+		{"invalid JSON", `{"id":`, "text/plain; charset=utf-8"},
+		{"HTML", "<!DOCTYPE html><html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"PNG", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"plain text", "hello", "text/plain; charset=utf-8"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffContentType(tt.body); got != tt.want {
+				t.Errorf("sniffContentType(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformSniffContentType(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestBody  string
+		responseBody string
+		wantRequest  interface{}
+		wantResponse interface{}
+	}{
+		{"JSON without content type", `{"name":"alice"}`, `{"id":1}`, "application/json", "application/json"},
+		{"HTML response", "", "<html><body>hi</body></html>", nil, "text/html; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["headers"] = `{}`
+			section(input, "request")["body"] = tt.requestBody
+			section(input, "response")["headers"] = `{}`
+			section(input, "response")["body"] = tt.responseBody
+
+			output := transformFlat(t, input, &Options{SniffContentType: true})
+			if got := output["requestSniffedContentType"]; got != tt.wantRequest {
+				t.Errorf("requestSniffedContentType = %v, want %v", got, tt.wantRequest)
+			}
+			if got := output["sniffedContentType"]; got != tt.wantResponse {
+				t.Errorf("sniffedContentType = %v, want %v", got, tt.wantResponse)
+			}
+			if output["contentType"] != "" {
+				t.Errorf("contentType = %q, want the declared (absent) type kept", output["contentType"])
+			}
+		})
+	}
+
+	if output := transformFlat(t, sampleInput(), &Options{}); output["sniffedContentType"] != nil {
+		t.Errorf("sniffedContentType = %v with sniffing disabled", output["sniffedContentType"])
+	}
+}
@@ -0,0 +1,28 @@
+package transformer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseStatusCode reads a status code sent either as a number or as a string,
+// which may be a bare code ("200") or a full status line ("200 OK"). It
+// returns 0 when no leading integer is present.
+func parseStatusCode(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		v = strings.TrimSpace(v)
+		end := 0
+		for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+			end++
+		}
+		code, err := strconv.Atoi(v[:end])
+		if err != nil {
+			return 0
+		}
+		return code
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package transformer
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestParseStatusCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  int
+	}{
+		{"string code", "200", 200},
+		{"status line", "200 OK", 200},
+		{"padded status line", " 404 Not Found ", 404},
+		{"number", float64(200), 200},
+		{"garbage", "OK", 0},
+		{"empty", "", 0},
+		{"missing", nil, 0},
+		{"boolean", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStatusCode(tt.value); got != tt.want {
+				t.Errorf("parseStatusCode(%#v) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformStatusCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode interface{}
+		wantCode   string
+		wantStatus string
+	}{
+		{"string", "200", "200", "OK"},
+		{"status line", "201 Created", "201", "Created"},
+		{"number", float64(200), "200", "OK"},
+		{"garbage", "unknown", "0", "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "response")["statusCode"] = tt.statusCode
+
+			output := transformFlat(t, input, &Options{})
+			if output["statusCode"] != tt.wantCode || output["status"] != tt.wantStatus {
+				t.Errorf("flat status = %v %v, want %s %s", output["statusCode"], output["status"], tt.wantCode, tt.wantStatus)
+			}
+			payload := transformProto(t, input, &Options{})
+			if got := payload.StatusCode; got != int32(parseStatusCode(tt.statusCode)) || payload.Status != tt.wantStatus {
+				t.Errorf("proto status = %d %s, want %s %s", got, payload.Status, tt.wantCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{200, "OK"},
+		{301, "Moved Permanently"},
+		{418, "I'm a teapot"},
+		{429, "Too Many Requests"},
+		{599, "Unknown"},
+		{0, "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			if got := getStatus(tt.code); got != tt.want {
+				t.Errorf("getStatus(%d) = %q, want %q", tt.code, got, tt.want)
+			}
+
+			input := sampleInput()
+			section(input, "response")["statusCode"] = float64(tt.code)
+			if got := transformFlat(t, input, &Options{})["status"]; got != tt.want {
+				t.Errorf("flat status = %q, want %q", got, tt.want)
+			}
+			if got := transformProto(t, input, &Options{}).Status; got != tt.want {
+				t.Errorf("proto Status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package transformer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// structuredBody decodes a body holding a JSON object or array, keeping
+// numbers exact, and reports false for empty and non-JSON bodies
+func structuredBody(body string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(trimmed)))
+	decoder.UseNumber()
+	var value interface{}
+	// Anything after the value, even a stray closing bracket, makes the body not JSON
+	if err := decoder.Decode(&value); err != nil || decoder.InputOffset() != int64(len(trimmed)) {
+		return nil, false
+	}
+	return value, true
+}
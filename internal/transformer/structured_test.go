@@ -0,0 +1,66 @@
+package transformer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStructuredBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"object", `{"id":1,"name":"alice"}`, map[string]interface{}{"id": json.Number("1"), "name": "alice"}, true},
+		{"array", `[1,2]`, []interface{}{json.Number("1"), json.Number("2")}, true},
+		{"surrounding whitespace", "  {\"a\":true}\n", map[string]interface{}{"a": true}, true},
+		{"large number kept exact", `{"id":12345678901234567890}`, map[string]interface{}{"id": json.Number("12345678901234567890")}, true},
+		{"empty", "", nil, false},
+		{"plain text", "hello", nil, false},
+		{"JSON scalar", `"hello"`, nil, false},
+		{"truncated", `{"id":1`, nil, false},
+		{"two documents", `{"a":1}{"b":2}`, nil, false},
+		{"stray closing bracket", `{"a":1}}`, nil, false},
+		{"form body", "a=1&b=2", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := structuredBody(tt.body)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("structuredBody(%q) = %v, %v, want %v, %v", tt.body, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTransformStructuredBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		enabled      bool
+		responseBody string
+		wantResponse bool
+	}{
+		{"JSON body", true, `{"id":1}`, true},
+		{"non-JSON body", true, "<html></html>", false},
+		{"disabled", false, `{"id":1}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "response")["body"] = tt.responseBody
+			output := transformFlat(t, input, &Options{StructuredBody: tt.enabled})
+
+			if _, ok := output["responseBodyJson"]; ok != tt.wantResponse {
+				t.Errorf("responseBodyJson present = %v, want %v", ok, tt.wantResponse)
+			}
+			if _, ok := output["requestBodyJson"]; ok != tt.enabled {
+				t.Errorf("requestBodyJson present = %v, want %v", ok, tt.enabled)
+			}
+			if output["responsePayload"] != tt.responseBody {
+				t.Errorf("responsePayload = %q, want the raw body kept", output["responsePayload"])
+			}
+		})
+	}
+}
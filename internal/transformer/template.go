@@ -0,0 +1,46 @@
+package transformer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var (
+	integerSegmentPattern = regexp.MustCompile(`^\d+$`)
+	uuidSegmentPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegmentPattern     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	tokenSegmentPattern   = regexp.MustCompile(`^[0-9A-Za-z_\-]{20,}$`)
+	hasDigitPattern       = regexp.MustCompile(`\d`)
+)
+
+// TemplatizePath replaces dynamic path segments (numeric IDs, UUIDs, long
+// hex or token-like values) with INTEGER/STRING placeholders so that
+// requests to the same endpoint share one path template
+func TemplatizePath(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+		case integerSegmentPattern.MatchString(segment):
+			segments[i] = "INTEGER"
+		case uuidSegmentPattern.MatchString(segment),
+			hexSegmentPattern.MatchString(segment),
+			tokenSegmentPattern.MatchString(segment) && hasDigitPattern.MatchString(segment):
+			segments[i] = "STRING"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// endpointHash fingerprints an endpoint as the hex SHA-256 of method, host and
+// path template, so requests differing only in IDs share a hash
+func endpointHash(method string, host string, path string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(method) + " " + host + TemplatizePath(path)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,72 @@
+package transformer
+
+import "testing"
+
+func TestTemplatizePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users", "/users"},
+		{"/users/42", "/users/INTEGER"},
+		{"/users/42/orders/7?expand=true", "/users/INTEGER/orders/INTEGER"},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", "/items/STRING"},
+		{"/blobs/deadbeefdeadbeef", "/blobs/STRING"},
+		{"/sessions/abc123def456ghi789jkl0", "/sessions/STRING"},
+		{"/sessions/only-letters-but-quite-long", "/sessions/only-letters-but-quite-long"},
+		{"/v2/users", "/v2/users"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := TemplatizePath(tt.path); got != tt.want {
+				t.Errorf("TemplatizePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointHash(t *testing.T) {
+	base := endpointHash("GET", "api.example.com", "/users/42")
+	tests := []struct {
+		name   string
+		method string
+		host   string
+		path   string
+		same   bool
+	}{
+		{"identical endpoint", "GET", "api.example.com", "/users/42", true},
+		{"different ID", "GET", "api.example.com", "/users/7", true},
+		{"query string", "GET", "api.example.com", "/users/42?expand=true", true},
+		{"lowercase method", "get", "api.example.com", "/users/42", true},
+		{"different method", "POST", "api.example.com", "/users/42", false},
+		{"different host", "GET", "admin.example.com", "/users/42", false},
+		{"different path", "GET", "api.example.com", "/orders/42", false},
+		{"non-numeric segment", "GET", "api.example.com", "/users/me", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := endpointHash(tt.method, tt.host, tt.path)
+			if len(got) != 64 {
+				t.Errorf("endpointHash = %q, want a hex SHA-256", got)
+			}
+			if (got == base) != tt.same {
+				t.Errorf("endpointHash(%q, %q, %q) == base is %v, want %v", tt.method, tt.host, tt.path, got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestTransformEndpointHash(t *testing.T) {
+	hash := func(url string) interface{} {
+		input := sampleInput()
+		section(input, "request")["url"] = url
+		return transformFlat(t, input, &Options{})["endpointHash"]
+	}
+	if hash("https://api.example.com/users/1") != hash("https://api.example.com/users/2?id=2") {
+		t.Error("requests differing only in IDs got different endpointHash values")
+	}
+	if hash("https://api.example.com/users/1") == hash("https://other.example.com/users/1") {
+		t.Error("requests to different hosts share an endpointHash")
+	}
+}
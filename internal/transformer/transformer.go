@@ -3,8 +3,10 @@ package transformer
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"mime"
+	"net/http"
 	"net/url"
+	"strings"
 )
 
 // extractURI extracts only the path/URI from a full URL
@@ -27,28 +29,78 @@ func extractURI(fullURL string) string {
 	return parsedURL.Path
 }
 
+// queryParamCount counts the query parameters of a URL, counting every value
+// of repeated keys
+func queryParamCount(fullURL string) int {
+	_, rawQuery, ok := strings.Cut(fullURL, "?")
+	if !ok {
+		return 0
+	}
+	rawQuery, _, _ = strings.Cut(rawQuery, "#")
+
+	// ParseQuery keeps the pairs it could decode even when some are malformed
+	values, _ := url.ParseQuery(rawQuery)
+	count := 0
+	for _, v := range values {
+		count += len(v)
+	}
+	return count
+}
+
+// parseFormBody decodes an application/x-www-form-urlencoded body into a map of
+// parameter values, keeping every value of repeated keys. It returns nil for
+// other content types or bodies without parameters.
+func parseFormBody(body string, contentType string) map[string][]string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/x-www-form-urlencoded" || body == "" {
+		return nil
+	}
+
+	// ParseQuery keeps the pairs it could decode even when some are malformed
+	values, _ := url.ParseQuery(body)
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
 // TransformMessage transforms from client nested format to standard flat format
-func TransformMessage(data []byte, clientID string) (map[string]interface{}, error) {
-	log.Printf("🔄 [TRANSFORMER] Starting transformation for client: %s", clientID)
-	log.Printf("🔄 [TRANSFORMER] Input size: %d bytes", len(data))
+func TransformMessage(data []byte, clientID string, opts *Options) (map[string]interface{}, error) {
+	opts = opts.orDefault()
+	log := opts.log()
+
+	opts.progressf("🔄 [TRANSFORMER] Starting transformation for client: %s", clientID)
+	log.Debugf("🔄 [TRANSFORMER] Input size: %d bytes", len(data))
 
 	previewSize := len(data)
 	if previewSize > 100 {
 		previewSize = 100
 	}
-	log.Printf("🔄 [TRANSFORMER] Input preview: %s...", string(data[:previewSize]))
+	log.Debugf("🔄 [TRANSFORMER] Input preview: %s...", string(data[:previewSize]))
+
+	if err := checkJSONDepth(data, opts.MaxJSONDepth); err != nil {
+		log.Errorf("❌ [TRANSFORMER] %v", err)
+		return nil, err
+	}
 
 	var input map[string]interface{}
 	err := json.Unmarshal(data, &input)
 	if err != nil {
-		log.Printf("❌ [TRANSFORMER] JSON parse error: %v", err)
+		log.Errorf("❌ [TRANSFORMER] JSON parse error: %v", err)
 		return nil, err
 	}
 
-	log.Printf("✅ [TRANSFORMER] JSON parsed successfully")
+	log.Debugf("✅ [TRANSFORMER] JSON parsed successfully")
+
+	// A mapping rewrites the source into the nested format so every step below
+	// applies to the mapped fields; the others are projected onto the result
+	source := input
+	if opts.Mapping != nil {
+		input = opts.Mapping.nested(source)
+	}
 
 	// Extract nested payload structure
-	output := make(map[string]interface{})
+	output := newOutput()
 
 	// Helper to safely get nested value
 	getNestedString := func(parent map[string]interface{}, keys ...string) string {
@@ -93,39 +145,159 @@ func TransformMessage(data []byte, clientID string) (map[string]interface{}, err
 
 	// Extract from nested payload structure
 
-	log.Printf("✅ [TRANSFORMER] Payload structure found")
+	log.Debugf("✅ [TRANSFORMER] Payload structure found")
 
 	// Request fields
-	request, _ := input["request"].(map[string]interface{})
+	request, response, complete, err := captureSections(input, opts.AllowResponseOnly)
+	if err != nil {
+		log.Errorf("❌ [TRANSFORMER] %v", err)
+		return nil, err
+	}
+	output["captureComplete"] = complete
 	fullURL := getNestedString(request, "url")
-	fmt.Println("[DEBUG] Full URL value:", fullURL)
+	if fullURL == "" {
+		fullURL = urlFromParts(getNestedString(request, "scheme"), getNestedString(request, "host"), getNestedString(request, "path"))
+	}
+	log.Debugf("[TRANSFORMER] Full URL value: %s", fullURL)
 	path := extractURI(fullURL)
-	fmt.Println("[DEBUG] Extracted URI value:", path)
+	log.Debugf("[TRANSFORMER] Extracted URI value: %s", path)
 	method := getNestedString(request, "method")
-	requestHeaders := request["headers"].(string)
-	requestPayload := request["body"].(string)
+	httpVersion := defaultHTTPVersion
+
+	// Some clients only send the raw request line
+	if method == "" && fullURL == "" {
+		if m, target, version, ok := parseRequestLine(getNestedString(request, "requestLine")); ok {
+			method, fullURL, httpVersion = m, target, version
+			path = extractURI(fullURL)
+		}
+	}
+	requestHeaders := headersString(request["headers"])
+	requestPayload, requestRaw, err := opts.decodeBodyWithPolicy(getNestedString(request, "body"))
+	if err != nil {
+		log.Errorf("❌ [TRANSFORMER] Request body decode error: %v", err)
+		return nil, fmt.Errorf("request body: %w", err)
+	}
+	requestPayload = opts.dechunkIfNeeded(requestPayload, requestHeaders)
+
+	output["isAuthenticated"] = isAuthenticated(requestHeaders, opts.AuthHeaders)
+	if opts.ExtractAuthScheme {
+		if scheme := authScheme(headerValue(requestHeaders, "authorization")); scheme != "" {
+			output["authScheme"] = scheme
+			requestHeaders = rewriteHeaders(requestHeaders, func(name string, value string) string {
+				if strings.EqualFold(name, "authorization") {
+					return redactAuthorization(value)
+				}
+				return value
+			})
+		}
+	}
 
 	output["path"] = path
 	output["method"] = method
+	output["queryParamCount"] = queryParamCount(fullURL)
+	output["endpointHash"] = endpointHash(method, requestHost(fullURL, requestHeaders), path)
 	output["requestHeaders"] = requestHeaders
 	output["requestPayload"] = requestPayload
-	output["type"] = "HTTP/1.1"
+	output["type"] = httpVersion
+	if host := requestHost(fullURL, requestHeaders); host != "" {
+		output["apiCollectionId"] = opts.apiCollectionID(host)
+	}
+	if len(opts.InternalHostSuffixes) > 0 {
+		output["isInternal"] = opts.isInternalHost(requestHost(fullURL, requestHeaders))
+	}
+
+	if opts.ParseFormBody {
+		if formParams := parseFormBody(requestPayload, headerValue(requestHeaders, "content-type")); formParams != nil {
+			output["formParams"] = formParams
+		}
+	}
+
+	if opts.ExtractAPIVersion {
+		if version := extractAPIVersion(path, requestHeaders, opts.APIVersionHeader); version != "" {
+			output["apiVersion"] = version
+		}
+	}
 
-	log.Printf("📥 [TRANSFORMER] Request extracted - Method: %s, Path: %s", method, path)
+	opts.progressf("📥 [TRANSFORMER] Request extracted - Method: %s, Path: %s", method, path)
 
 	// Response fields
-	response, _ := input["response"].(map[string]interface{})
-	responseHeaders := getNestedString(response, "headers")
-	responsePayload := getNestedString(response, "body")
-	statusCode := int(getNestedFloat(response, "statusCode"))
+	responseHeaders := headersString(response["headers"])
+	responsePayload, responseRaw, err := opts.decodeBodyWithPolicy(getNestedString(response, "body"))
+	if err != nil {
+		log.Errorf("❌ [TRANSFORMER] Response body decode error: %v", err)
+		return nil, fmt.Errorf("response body: %w", err)
+	}
+	responsePayload = opts.dechunkIfNeeded(responsePayload, responseHeaders)
+	if requestRaw || responseRaw {
+		log.Warnf("⚠️  [TRANSFORMER] Body failed to decode, forwarding it undecoded")
+		output["bodyDecodeFailed"] = true
+	}
+	if opts.DropResponseBody {
+		responsePayload = ""
+	}
+	statusCode := parseStatusCode(response["statusCode"])
 
-	output["responseHeaders"] = responseHeaders
+	output["requestHeaders"] = opts.renameHeaders(output["requestHeaders"].(string))
+	output["responseHeaders"] = opts.renameHeaders(responseHeaders)
+	if opts.MaxHeaderValueSize > 0 {
+		truncate := func(name string, value string) string { return opts.truncateHeaderValue(value) }
+		output["requestHeaders"] = rewriteHeaders(output["requestHeaders"].(string), truncate)
+		output["responseHeaders"] = rewriteHeaders(output["responseHeaders"].(string), truncate)
+	}
+	if len(opts.DropHeaders) > 0 {
+		keep := func(name string) bool { return !opts.dropsHeader(name) }
+		output["requestHeaders"], _ = filterHeaders(output["requestHeaders"].(string), keep)
+		output["responseHeaders"], _ = filterHeaders(output["responseHeaders"].(string), keep)
+	}
+	if len(opts.KeepHeaders) > 0 {
+		keptRequest, requestDropped := filterHeaders(output["requestHeaders"].(string), opts.keepsHeader)
+		keptResponse, responseDropped := filterHeaders(output["responseHeaders"].(string), opts.keepsHeader)
+		output["requestHeaders"] = keptRequest
+		output["responseHeaders"] = keptResponse
+		output["headersDropped"] = requestDropped + responseDropped
+	}
 	output["responsePayload"] = responsePayload
 	output["statusCode"] = fmt.Sprintf("%d", statusCode)
 	output["status"] = getStatus(statusCode)
-	output["contentType"] = responseHeaders // Would need to parse from headers
+	output["contentType"] = headerValue(responseHeaders, "content-type")
 
-	log.Printf("📤 [TRANSFORMER] Response extracted - Status: %d, Response size: %d bytes", statusCode, len(responsePayload))
+	// Strip gRPC-Web framing so bodies carry the bare messages
+	if contentType := headerValue(requestHeaders, "content-type"); isGRPCWeb(contentType) {
+		if unframed, ok := unframeGRPCWeb(requestPayload, contentType); ok {
+			output["requestPayload"] = unframed
+		}
+		output["grpcWeb"] = true
+	}
+	if contentType := headerValue(responseHeaders, "content-type"); isGRPCWeb(contentType) {
+		if unframed, ok := unframeGRPCWeb(responsePayload, contentType); ok {
+			output["responsePayload"] = unframed
+		}
+		output["grpcWeb"] = true
+	}
+
+	if truncation := opts.truncateBodies(output); truncation.Truncated() {
+		log.Warnf("⚠️  [TRANSFORMER] Body exceeded %d bytes, truncated", opts.MaxBodyBytes)
+	}
+
+	if opts.SniffContentType {
+		if sniffed := sniffContentType(output["requestPayload"].(string)); sniffed != "" {
+			output["requestSniffedContentType"] = sniffed
+		}
+		if sniffed := sniffContentType(output["responsePayload"].(string)); sniffed != "" {
+			output["sniffedContentType"] = sniffed
+		}
+	}
+
+	if opts.StructuredBody {
+		if body, ok := structuredBody(output["requestPayload"].(string)); ok {
+			output["requestBodyJson"] = body
+		}
+		if body, ok := structuredBody(output["responsePayload"].(string)); ok {
+			output["responseBodyJson"] = body
+		}
+	}
+
+	opts.progressf("📤 [TRANSFORMER] Response extracted - Status: %d, Response size: %d bytes", statusCode, len(responsePayload))
 
 	// Info fields
 	info, _ := input["info"].(map[string]interface{})
@@ -134,33 +306,24 @@ func TransformMessage(data []byte, clientID string) (map[string]interface{}, err
 	responseTime := int(getNestedFloat(info, "responseTime"))
 
 	output["ip"] = clientIP
-	output["time"] = fmt.Sprintf("%d", dateTime/1000) // Convert to seconds
+	output["time"] = opts.formatTime(opts.epochSeconds(dateTime))
 	output["akto_account_id"] = clientID
 	output["responseTime"] = responseTime
 	output["source"] = "MIRRORING"
+	if opts.Mapping != nil {
+		opts.Mapping.project(source, output)
+	}
 
-	log.Printf("ℹ️  [TRANSFORMER] Info extracted - IP: %s, Client ID: %s, Response Time: %dms", clientIP, clientID, responseTime)
-	log.Printf("✅ [TRANSFORMER] Transformation completed successfully - Output has %d fields", len(output))
+	opts.progressf("ℹ️  [TRANSFORMER] Info extracted - IP: %s, Client ID: %s, Response Time: %dms", clientIP, clientID, responseTime)
+	opts.progressf("✅ [TRANSFORMER] Transformation completed successfully - Output has %d fields", len(output))
 
 	return output, nil
 }
 
 // getStatus converts HTTP status code to status message
 func getStatus(code int) string {
-	statusMap := map[int]string{
-		200: "OK",
-		201: "Created",
-		204: "No Content",
-		400: "Bad Request",
-		401: "Unauthorized",
-		403: "Forbidden",
-		404: "Not Found",
-		500: "Internal Server Error",
-		502: "Bad Gateway",
-		503: "Service Unavailable",
-	}
-	if msg, ok := statusMap[code]; ok {
-		return msg
+	if text := http.StatusText(code); text != "" {
+		return text
 	}
 	return "Unknown"
 }
@@ -0,0 +1,314 @@
+package transformer
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"client-message-transformer/internal/logger"
+	trafficpb "client-message-transformer/protobuf/traffic_payload"
+)
+
+// quietLogger keeps transformer progress logs out of test output
+var quietLogger = logger.NewLogger("ERROR", io.Discard)
+
+// sampleInput returns a complete nested client message; tests adjust it
+// before encoding
+func sampleInput() map[string]interface{} {
+	return map[string]interface{}{
+		"request": map[string]interface{}{
+			"url":     "https://api.example.com/users?id=1",
+			"method":  "GET",
+			"headers": `{"Content-Type":"application/json","Connection":"keep-alive","X-Request-Id":"abc"}`,
+			"body":    `{"name":"alice"}`,
+		},
+		"response": map[string]interface{}{
+			"statusCode": float64(200),
+			"headers":    `{"Content-Type":"application/json","Transfer-Encoding":"identity"}`,
+			"body":       `{"id":1}`,
+		},
+		"info": map[string]interface{}{
+			"ip":           "203.0.113.7",
+			"dateTime":     float64(1700000000),
+			"responseTime": float64(12),
+		},
+	}
+}
+
+// encodeInput marshals a nested client message
+func encodeInput(t testing.TB, input map[string]interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	return data
+}
+
+// section returns a nested section of a client message for modification
+func section(input map[string]interface{}, name string) map[string]interface{} {
+	return input[name].(map[string]interface{})
+}
+
+// transformFlat runs TransformMessage and fails the test on error
+func transformFlat(t testing.TB, input map[string]interface{}, opts *Options) map[string]interface{} {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = quietLogger
+	}
+	output, err := TransformMessage(encodeInput(t, input), "1000", opts)
+	if err != nil {
+		t.Fatalf("TransformMessage: %v", err)
+	}
+	return output
+}
+
+// transformProto runs TransformToProto and fails the test on error
+func transformProto(t testing.TB, input map[string]interface{}, opts *Options) *trafficpb.HttpResponseParam {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = quietLogger
+	}
+	payload, _, err := TransformToProto(encodeInput(t, input), "1000", opts)
+	if err != nil {
+		t.Fatalf("TransformToProto: %v", err)
+	}
+	return payload
+}
+
+// flatHeaders decodes a JSON headers field of a flat record
+func flatHeaders(t testing.TB, output map[string]interface{}, field string) map[string]interface{} {
+	t.Helper()
+	var headers map[string]interface{}
+	if err := json.Unmarshal([]byte(output[field].(string)), &headers); err != nil {
+		t.Fatalf("decode %s: %v", field, err)
+	}
+	return headers
+}
+
+func TestParseFormBody(t *testing.T) {
+	const form = "application/x-www-form-urlencoded"
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        map[string][]string
+	}{
+		{"simple form", "name=alice&age=30", form, map[string][]string{"name": {"alice"}, "age": {"30"}}},
+		{"repeated keys", "tag=a&tag=b&tag=c", form, map[string][]string{"tag": {"a", "b", "c"}}},
+		{"escaped values", "q=hello+world&path=%2Fusers", form, map[string][]string{"q": {"hello world"}, "path": {"/users"}}},
+		{"charset parameter", "a=1", form + "; charset=utf-8", map[string][]string{"a": {"1"}}},
+		{"malformed pair kept around", "a=1&b=%zz&c=3", form, map[string][]string{"a": {"1"}, "c": {"3"}}},
+		{"empty body", "", form, nil},
+		{"JSON content type", "a=1", "application/json", nil},
+		{"missing content type", "a=1", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFormBody(tt.body, tt.contentType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFormBody(%q, %q) = %v, want %v", tt.body, tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformFormBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		want    interface{}
+	}{
+		{"enabled", true, map[string][]string{"tag": {"a", "b"}, "name": {"alice"}}},
+		{"disabled", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			request := section(input, "request")
+			request["headers"] = `{"Content-Type":"application/x-www-form-urlencoded"}`
+			request["body"] = "tag=a&name=alice&tag=b"
+
+			output := transformFlat(t, input, &Options{ParseFormBody: tt.enabled})
+			got, ok := output["formParams"]
+			if tt.want == nil {
+				if ok {
+					t.Errorf("formParams = %v, want none", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formParams = %v, want %v", got, tt.want)
+			}
+			if output["requestPayload"] != "tag=a&name=alice&tag=b" {
+				t.Errorf("requestPayload = %q, want the raw form body", output["requestPayload"])
+			}
+		})
+	}
+}
+
+func TestDropResponseBody(t *testing.T) {
+	tests := []struct {
+		name string
+		drop bool
+		want string
+	}{
+		{"dropped", true, ""},
+		{"kept", false, `{"id":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := transformFlat(t, sampleInput(), &Options{DropResponseBody: tt.drop})
+			if output["responsePayload"] != tt.want {
+				t.Errorf("flat responsePayload = %q, want %q", output["responsePayload"], tt.want)
+			}
+			if output["requestPayload"] != `{"name":"alice"}` || output["statusCode"] != "200" || output["contentType"] != "application/json" {
+				t.Errorf("flat record lost other fields: %v", output)
+			}
+			if headers := flatHeaders(t, output, "responseHeaders"); headers["Content-Type"] != "application/json" {
+				t.Errorf("flat responseHeaders = %v, want Content-Type kept", headers)
+			}
+
+			payload := transformProto(t, sampleInput(), &Options{DropResponseBody: tt.drop})
+			if payload.ResponsePayload != tt.want {
+				t.Errorf("proto ResponsePayload = %q, want %q", payload.ResponsePayload, tt.want)
+			}
+			if payload.RequestPayload != `{"name":"alice"}` || payload.StatusCode != 200 || len(payload.ResponseHeaders) == 0 {
+				t.Errorf("proto payload lost other fields: %v", payload)
+			}
+		})
+	}
+}
+
+func TestQueryParamCount(t *testing.T) {
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://api.example.com/users", 0},
+		{"https://api.example.com/users?", 0},
+		{"https://api.example.com/users?id=1", 1},
+		{"https://api.example.com/users?id=1&sort=asc&page=2", 3},
+		{"https://api.example.com/users?tag=a&tag=b&tag=c", 3},
+		{"https://api.example.com/users?id=1#section?x=2", 1},
+		{"/users?flag", 1},
+		{"/users?a=1&b=%zz&c=3", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := queryParamCount(tt.url); got != tt.want {
+				t.Errorf("queryParamCount(%q) = %d, want %d", tt.url, got, tt.want)
+			}
+
+			input := sampleInput()
+			section(input, "request")["url"] = tt.url
+			if got := transformFlat(t, input, &Options{})["queryParamCount"]; got != tt.want {
+				t.Errorf("flat queryParamCount = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers interface{}
+		want    string
+	}{
+		{"present", `{"Content-Type":"text/html; charset=utf-8","X-Id":"1"}`, "text/html; charset=utf-8"},
+		{"lowercase name", `{"content-type":"application/xml"}`, "application/xml"},
+		{"mixed case name", `{"CONTENT-type":"text/plain"}`, "text/plain"},
+		{"absent", `{"X-Id":"1"}`, ""},
+		{"no headers", "", ""},
+		{"array value", `{"Content-Type":["application/json","text/plain"]}`, "application/json"},
+		{"array with a non-string first", `{"Content-Type":[1,"text/plain"]}`, "text/plain"},
+		{"object headers", map[string]interface{}{"Content-Type": "image/png"}, "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "response")["headers"] = tt.headers
+			if got := transformFlat(t, input, &Options{})["contentType"]; got != tt.want {
+				t.Errorf("contentType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMalformedFieldsDoNotPanic(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(input map[string]interface{})
+		wantHeaders string // Flat requestHeaders; "-" skips the check
+	}{
+		{"missing request", func(input map[string]interface{}) { delete(input, "request") }, ""},
+		{"headers as an object", func(input map[string]interface{}) {
+			section(input, "request")["headers"] = map[string]interface{}{"Content-Type": "application/json"}
+		}, `{"Content-Type":"application/json"}`},
+		{"headers as a number", func(input map[string]interface{}) { section(input, "request")["headers"] = 42 }, ""},
+		{"headers null", func(input map[string]interface{}) { section(input, "request")["headers"] = nil }, ""},
+		{"body null", func(input map[string]interface{}) { section(input, "request")["body"] = nil }, "-"},
+		{"body an object", func(input map[string]interface{}) {
+			section(input, "request")["body"] = map[string]interface{}{"name": "alice"}
+		}, "-"},
+		{"method a number", func(input map[string]interface{}) { section(input, "request")["method"] = 7 }, "-"},
+		{"response headers an array", func(input map[string]interface{}) {
+			section(input, "response")["headers"] = []interface{}{"Content-Type"}
+		}, "-"},
+		{"info a string", func(input map[string]interface{}) { input["info"] = "none" }, "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			tt.modify(input)
+
+			// Response-only captures are accepted so a missing request is transformed too
+			opts := &Options{AllowResponseOnly: true}
+			output := transformFlat(t, input, opts)
+			if tt.wantHeaders != "-" && output["requestHeaders"] != tt.wantHeaders {
+				t.Errorf("flat requestHeaders = %q, want %q", output["requestHeaders"], tt.wantHeaders)
+			}
+			transformProto(t, input, opts)
+		})
+	}
+}
+
+func TestReleasedOutputStartsEmpty(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  *Options
+		field string // Present only for the first message
+	}{
+		{"form params", &Options{ParseFormBody: true}, "formParams"},
+		{"auth scheme", &Options{ExtractAuthScheme: true}, "authScheme"},
+		{"structured body", &Options{StructuredBody: true}, "requestBodyJson"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := sampleInput()
+			request := section(first, "request")
+			request["headers"] = `{"Content-Type":"application/x-www-form-urlencoded","Authorization":"Bearer secret"}`
+			request["body"] = "a=1"
+			if tt.field == "requestBodyJson" {
+				request["headers"] = `{"Content-Type":"application/json"}`
+				request["body"] = `{"a":1}`
+			}
+			output := transformFlat(t, first, tt.opts)
+			if _, ok := output[tt.field]; !ok {
+				t.Fatalf("first output has no %s: %v", tt.field, output)
+			}
+			ReleaseOutput(output)
+
+			second := sampleInput()
+			section(second, "request")["headers"] = `{}`
+			section(second, "request")["body"] = ""
+			for i := 0; i < 10; i++ {
+				output := transformFlat(t, second, tt.opts)
+				if value, ok := output[tt.field]; ok {
+					t.Fatalf("output reused from the pool kept %s = %v", tt.field, value)
+				}
+				ReleaseOutput(output)
+			}
+		})
+	}
+}
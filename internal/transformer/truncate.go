@@ -0,0 +1,71 @@
+package transformer
+
+import "unicode/utf8"
+
+// Flat fields marking bodies cut at MaxBodyBytes
+const (
+	FieldBodyTruncated      = "body_truncated"
+	fieldRequestBodyLength  = "request_body_original_length"
+	fieldResponseBodyLength = "response_body_original_length"
+)
+
+// Truncation holds the original lengths of bodies cut at MaxBodyBytes, 0 for
+// a body left whole
+type Truncation struct {
+	RequestLength  int
+	ResponseLength int
+}
+
+// Truncated reports whether either body was cut
+func (t Truncation) Truncated() bool {
+	return t.RequestLength > 0 || t.ResponseLength > 0
+}
+
+// Mark sets body_truncated and the original lengths on a flat record when a
+// body was cut
+func (t Truncation) Mark(record map[string]interface{}) {
+	if !t.Truncated() {
+		return
+	}
+	record[FieldBodyTruncated] = true
+	if t.RequestLength > 0 {
+		record[fieldRequestBodyLength] = t.RequestLength
+	}
+	if t.ResponseLength > 0 {
+		record[fieldResponseBodyLength] = t.ResponseLength
+	}
+}
+
+// truncationOf reads back the truncation marked on a flat record
+func truncationOf(record map[string]interface{}) Truncation {
+	requestLength, _ := record[fieldRequestBodyLength].(int)
+	responseLength, _ := record[fieldResponseBodyLength].(int)
+	return Truncation{RequestLength: requestLength, ResponseLength: responseLength}
+}
+
+// truncateBody cuts a body longer than MaxBodyBytes, backing up to a character
+// boundary for text, and returns its original length when it was cut
+func (o *Options) truncateBody(body string) (string, int) {
+	if o.MaxBodyBytes <= 0 || len(body) <= o.MaxBodyBytes {
+		return body, 0
+	}
+	cut := o.MaxBodyBytes
+	for back := 0; back < utf8.UTFMax-1 && cut > 0 && !utf8.RuneStart(body[cut]); back++ {
+		cut--
+	}
+	return body[:cut], len(body)
+}
+
+// truncateBodies cuts the payloads of a flat record at MaxBodyBytes and marks
+// the record when either was cut
+func (o *Options) truncateBodies(output map[string]interface{}) Truncation {
+	var truncation Truncation
+	if body, ok := output["requestPayload"].(string); ok {
+		output["requestPayload"], truncation.RequestLength = o.truncateBody(body)
+	}
+	if body, ok := output["responsePayload"].(string); ok {
+		output["responsePayload"], truncation.ResponseLength = o.truncateBody(body)
+	}
+	truncation.Mark(output)
+	return truncation
+}
@@ -0,0 +1,82 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		max        int
+		body       string
+		want       string
+		wantLength int
+	}{
+		{"disabled", 0, "abcdef", "abcdef", 0},
+		{"under the limit", 10, "abcdef", "abcdef", 0},
+		{"at the limit", 6, "abcdef", "abcdef", 0},
+		{"over the limit", 4, "abcdef", "abcd", 6},
+		{"backs up to a character boundary", 4, "abcé", "abc", 5},
+		{"multibyte character kept whole", 5, "abcé!", "abcé", 6},
+		{"four-byte character", 3, "a😀b", "a", 6},
+		{"empty body", 4, "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &Options{MaxBodyBytes: tt.max}
+			got, length := opts.truncateBody(tt.body)
+			if got != tt.want || length != tt.wantLength {
+				t.Errorf("truncateBody(%q) = %q, %d, want %q, %d", tt.body, got, length, tt.want, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestTruncateBodies(t *testing.T) {
+	request := strings.Repeat("q", 40)
+	response := strings.Repeat("r", 10)
+	tests := []struct {
+		name               string
+		max                int
+		wantRequest        string
+		wantResponse       string
+		wantRequestLength  int
+		wantResponseLength int
+	}{
+		{"disabled", 0, request, response, 0, 0},
+		{"both under the limit", 64, request, response, 0, 0},
+		{"request cut", 16, request[:16], response, 40, 0},
+		{"both cut", 8, request[:8], response[:8], 40, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			section(input, "request")["body"] = request
+			section(input, "response")["body"] = response
+			truncated := tt.wantRequestLength > 0 || tt.wantResponseLength > 0
+
+			output := transformFlat(t, input, &Options{MaxBodyBytes: tt.max})
+			if output["requestPayload"] != tt.wantRequest || output["responsePayload"] != tt.wantResponse {
+				t.Errorf("flat payloads = %q, %q, want %q, %q", output["requestPayload"], output["responsePayload"], tt.wantRequest, tt.wantResponse)
+			}
+			if got, _ := output[FieldBodyTruncated].(bool); got != truncated {
+				t.Errorf("flat %s = %v, want %v", FieldBodyTruncated, got, truncated)
+			}
+			if got := truncationOf(output); got != (Truncation{tt.wantRequestLength, tt.wantResponseLength}) {
+				t.Errorf("flat original lengths = %+v, want %d, %d", got, tt.wantRequestLength, tt.wantResponseLength)
+			}
+
+			payload, truncation, err := TransformToProto(encodeInput(t, input), "1000", &Options{MaxBodyBytes: tt.max, Logger: quietLogger})
+			if err != nil {
+				t.Fatalf("TransformToProto: %v", err)
+			}
+			if payload.RequestPayload != tt.wantRequest || payload.ResponsePayload != tt.wantResponse {
+				t.Errorf("proto payloads = %q, %q, want %q, %q", payload.RequestPayload, payload.ResponsePayload, tt.wantRequest, tt.wantResponse)
+			}
+			if truncation != (Truncation{tt.wantRequestLength, tt.wantResponseLength}) || truncation.Truncated() != truncated {
+				t.Errorf("proto truncation = %+v, want %d, %d", truncation, tt.wantRequestLength, tt.wantResponseLength)
+			}
+		})
+	}
+}
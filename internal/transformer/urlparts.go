@@ -0,0 +1,19 @@
+package transformer
+
+import "strings"
+
+// urlFromParts rebuilds an absolute URL from discrete request.scheme,
+// request.host and request.path fields, for clients that send no full URL.
+// The scheme defaults to http; without a host only the path is returned.
+func urlFromParts(scheme string, host string, path string) string {
+	if host == "" {
+		return path
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.ToLower(scheme) + "://" + host + path
+}
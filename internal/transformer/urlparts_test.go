@@ -0,0 +1,73 @@
+package transformer
+
+import "testing"
+
+func TestURLFromParts(t *testing.T) {
+	tests := []struct {
+		scheme, host, path string
+		want               string
+	}{
+		{"https", "api.example.com", "/users?id=1", "https://api.example.com/users?id=1"},
+		{"HTTPS", "api.example.com", "/users", "https://api.example.com/users"},
+		{"", "api.example.com", "/users", "http://api.example.com/users"},
+		{"https", "api.example.com", "users", "https://api.example.com/users"},
+		{"https", "api.example.com", "", "https://api.example.com"},
+		{"https", "", "/users", "/users"},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := urlFromParts(tt.scheme, tt.host, tt.path); got != tt.want {
+				t.Errorf("urlFromParts(%q, %q, %q) = %q, want %q", tt.scheme, tt.host, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformURLParts(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   map[string]interface{}
+		wantPath  string
+		wantCount int
+	}{
+		{
+			"full URL",
+			map[string]interface{}{"url": "https://api.example.com/users?id=1"},
+			"/users?id=1", 1,
+		},
+		{
+			"parts",
+			map[string]interface{}{"scheme": "https", "host": "api.example.com", "path": "/users?id=1"},
+			"/users?id=1", 1,
+		},
+		{
+			"full URL wins over parts",
+			map[string]interface{}{"url": "https://api.example.com/users", "host": "other.example.com", "path": "/orders?a=1&b=2"},
+			"/users", 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleInput()
+			request := section(input, "request")
+			delete(request, "url")
+			for key, value := range tt.request {
+				request[key] = value
+			}
+
+			output := transformFlat(t, input, &Options{})
+			if output["path"] != tt.wantPath || output["queryParamCount"] != tt.wantCount {
+				t.Errorf("flat path %q with %v params, want %q with %d", output["path"], output["queryParamCount"], tt.wantPath, tt.wantCount)
+			}
+			if _, ok := output["apiCollectionId"]; !ok {
+				t.Error("flat record has no apiCollectionId for the request host")
+			}
+
+			payload := transformProto(t, input, &Options{})
+			if payload.Path != tt.wantPath {
+				t.Errorf("proto Path = %q, want %q", payload.Path, tt.wantPath)
+			}
+		})
+	}
+}
@@ -66,27 +66,29 @@ func (x *StringList) GetValues() []string {
 }
 
 type HttpResponseParam struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Method          string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
-	Path            string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
-	Type            string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
-	RequestHeaders  map[string]*StringList `protobuf:"bytes,4,rep,name=request_headers,json=requestHeaders,proto3" json:"request_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	RequestPayload  string                 `protobuf:"bytes,5,opt,name=request_payload,json=requestPayload,proto3" json:"request_payload,omitempty"`
-	ApiCollectionId int32                  `protobuf:"varint,6,opt,name=api_collection_id,json=apiCollectionId,proto3" json:"api_collection_id,omitempty"`
-	StatusCode      int32                  `protobuf:"varint,7,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
-	Status          string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
-	ResponseHeaders map[string]*StringList `protobuf:"bytes,9,rep,name=response_headers,json=responseHeaders,proto3" json:"response_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	ResponsePayload string                 `protobuf:"bytes,10,opt,name=response_payload,json=responsePayload,proto3" json:"response_payload,omitempty"`
-	Time            int32                  `protobuf:"varint,11,opt,name=time,proto3" json:"time,omitempty"`
-	AktoAccountId   string                 `protobuf:"bytes,12,opt,name=akto_account_id,json=aktoAccountId,proto3" json:"akto_account_id,omitempty"`
-	Ip              string                 `protobuf:"bytes,13,opt,name=ip,proto3" json:"ip,omitempty"`
-	DestIp          string                 `protobuf:"bytes,14,opt,name=dest_ip,json=destIp,proto3" json:"dest_ip,omitempty"`
-	Direction       string                 `protobuf:"bytes,15,opt,name=direction,proto3" json:"direction,omitempty"`
-	IsPending       bool                   `protobuf:"varint,16,opt,name=is_pending,json=isPending,proto3" json:"is_pending,omitempty"`
-	Source          string                 `protobuf:"bytes,17,opt,name=source,proto3" json:"source,omitempty"`
-	AktoVxlanId     string                 `protobuf:"bytes,18,opt,name=akto_vxlan_id,json=aktoVxlanId,proto3" json:"akto_vxlan_id,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Method             string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Path               string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	Type               string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	RequestHeaders     map[string]*StringList `protobuf:"bytes,4,rep,name=request_headers,json=requestHeaders,proto3" json:"request_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	RequestPayload     string                 `protobuf:"bytes,5,opt,name=request_payload,json=requestPayload,proto3" json:"request_payload,omitempty"`
+	ApiCollectionId    int32                  `protobuf:"varint,6,opt,name=api_collection_id,json=apiCollectionId,proto3" json:"api_collection_id,omitempty"`
+	StatusCode         int32                  `protobuf:"varint,7,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Status             string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	ResponseHeaders    map[string]*StringList `protobuf:"bytes,9,rep,name=response_headers,json=responseHeaders,proto3" json:"response_headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ResponsePayload    string                 `protobuf:"bytes,10,opt,name=response_payload,json=responsePayload,proto3" json:"response_payload,omitempty"`
+	Time               int32                  `protobuf:"varint,11,opt,name=time,proto3" json:"time,omitempty"`
+	AktoAccountId      string                 `protobuf:"bytes,12,opt,name=akto_account_id,json=aktoAccountId,proto3" json:"akto_account_id,omitempty"`
+	Ip                 string                 `protobuf:"bytes,13,opt,name=ip,proto3" json:"ip,omitempty"`
+	DestIp             string                 `protobuf:"bytes,14,opt,name=dest_ip,json=destIp,proto3" json:"dest_ip,omitempty"`
+	Direction          string                 `protobuf:"bytes,15,opt,name=direction,proto3" json:"direction,omitempty"`
+	IsPending          bool                   `protobuf:"varint,16,opt,name=is_pending,json=isPending,proto3" json:"is_pending,omitempty"`
+	Source             string                 `protobuf:"bytes,17,opt,name=source,proto3" json:"source,omitempty"`
+	AktoVxlanId        string                 `protobuf:"bytes,18,opt,name=akto_vxlan_id,json=aktoVxlanId,proto3" json:"akto_vxlan_id,omitempty"`
+	RawRequestHeaders  string                 `protobuf:"bytes,19,opt,name=raw_request_headers,json=rawRequestHeaders,proto3" json:"raw_request_headers,omitempty"`
+	RawResponseHeaders string                 `protobuf:"bytes,20,opt,name=raw_response_headers,json=rawResponseHeaders,proto3" json:"raw_response_headers,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *HttpResponseParam) Reset() {
@@ -245,6 +247,20 @@ func (x *HttpResponseParam) GetAktoVxlanId() string {
 	return ""
 }
 
+func (x *HttpResponseParam) GetRawRequestHeaders() string {
+	if x != nil {
+		return x.RawRequestHeaders
+	}
+	return ""
+}
+
+func (x *HttpResponseParam) GetRawResponseHeaders() string {
+	if x != nil {
+		return x.RawResponseHeaders
+	}
+	return ""
+}
+
 var File_protobuf_traffic_payload_message_proto protoreflect.FileDescriptor
 
 const file_protobuf_traffic_payload_message_proto_rawDesc = "" +
@@ -252,7 +268,7 @@ const file_protobuf_traffic_payload_message_proto_rawDesc = "" +
 	"&protobuf/traffic_payload/message.proto\x12/threat_detection.message.http_response_param.v1\"$\n" +
 	"\n" +
 	"StringList\x12\x16\n" +
-	"\x06values\x18\x01 \x03(\tR\x06values\"\xf1\a\n" +
+	"\x06values\x18\x01 \x03(\tR\x06values\"\xd3\b\n" +
 	"\x11HttpResponseParam\x12\x16\n" +
 	"\x06method\x18\x01 \x01(\tR\x06method\x12\x12\n" +
 	"\x04path\x18\x02 \x01(\tR\x04path\x12\x12\n" +
@@ -274,7 +290,9 @@ const file_protobuf_traffic_payload_message_proto_rawDesc = "" +
 	"\n" +
 	"is_pending\x18\x10 \x01(\bR\tisPending\x12\x16\n" +
 	"\x06source\x18\x11 \x01(\tR\x06source\x12\"\n" +
-	"\rakto_vxlan_id\x18\x12 \x01(\tR\vaktoVxlanId\x1a~\n" +
+	"\rakto_vxlan_id\x18\x12 \x01(\tR\vaktoVxlanId\x12.\n" +
+	"\x13raw_request_headers\x18\x13 \x01(\tR\x11rawRequestHeaders\x120\n" +
+	"\x14raw_response_headers\x18\x14 \x01(\tR\x12rawResponseHeaders\x1a~\n" +
 	"\x13RequestHeadersEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12Q\n" +
 	"\x05value\x18\x02 \x01(\v2;.threat_detection.message.http_response_param.v1.StringListR\x05value:\x028\x01\x1a\x7f\n" +